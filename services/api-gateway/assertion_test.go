@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertionSigner_Mint(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Unix(1_700_000_000, 0)
+	s := &AssertionSigner{Key: priv, now: func() time.Time { return now }}
+
+	var claims identityClaims
+	claims.PreferredUsername = "tester"
+	claims.Email = "t@example.com"
+	claims.RealmAccess.Roles = []string{"user", "SuperAdmin"}
+
+	token, err := s.Mint("user-123", claims)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), sig))
+
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.JSONEq(t, `{"alg":"EdDSA","typ":"JWT"}`, string(header))
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var got AssertionClaims
+	require.NoError(t, json.Unmarshal(payload, &got))
+	assert.Equal(t, "api-gateway", got.Issuer)
+	assert.Equal(t, "t-hub-services", got.Audience)
+	assert.Equal(t, "user-123", got.Subject)
+	assert.Equal(t, "tester", got.Name)
+	assert.Equal(t, "t@example.com", got.Email)
+	assert.Equal(t, "SuperAdmin,user", got.Roles)
+	assert.Equal(t, now.Unix(), got.IssuedAt)
+	assert.Equal(t, now.Add(assertionTTL).Unix(), got.ExpiresAt)
+	assert.Len(t, got.ID, 32)
+
+	// Every request gets its own assertion
+	other, err := s.Mint("user-123", claims)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestNewAssertionSignerFromEnv(t *testing.T) {
+	t.Setenv("GATEWAY_ASSERTION_KEY", "")
+	t.Setenv("GATEWAY_ASSERTION_KEY_FILE", "")
+	s, err := NewAssertionSignerFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, s, "no key means assertions are off")
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	t.Setenv("GATEWAY_ASSERTION_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+
+	s, err = NewAssertionSignerFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, priv, s.Key)
+
+	t.Setenv("GATEWAY_ASSERTION_KEY", "not a key")
+	_, err = NewAssertionSignerFromEnv()
+	assert.Error(t, err)
+}
@@ -1,24 +1,390 @@
 package main
 // ci trigger
 import (
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Services []Service `yaml:"services"`
+	Services  []Service       `yaml:"services"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Security  SecurityConfig  `yaml:"security"`
+	BodyLimit BodyLimitConfig `yaml:"bodyLimit"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+}
+
+// CORSConfig sets which browser origins may call the API. An origin is "scheme://host[:port]";
+// "https://*.example.com" matches any subdomain of example.com (not example.com itself).
+type CORSConfig struct {
+	AllowOrigins     []string            `yaml:"allowOrigins"`     // Defaults to the production frontend
+	Environments     map[string][]string `yaml:"environments"`     // Further origins, by the GATEWAY_ENV the gateway runs in
+	AllowMethods     []string            `yaml:"allowMethods"`     // Defaults to all methods the API uses
+	AllowHeaders     []string            `yaml:"allowHeaders"`     // Defaults to Origin, Content-Type, Accept and Authorization
+	ExposeHeaders    []string            `yaml:"exposeHeaders"`    // Response headers scripts may read
+	AllowCredentials bool                `yaml:"allowCredentials"` // Cookies; the API itself uses bearer tokens
+	MaxAge           time.Duration       `yaml:"maxAge"`           // How long browsers may cache a preflight
+}
+
+var defaultAllowOrigins = []string{"https://t-hub.ltu-m7011e-4.se"}
+
+// origins returns the allowed origins in the given environment.
+func (c CORSConfig) origins(env string) []string {
+	origins := c.AllowOrigins
+	if len(origins) == 0 {
+		origins = defaultAllowOrigins
+	}
+	return append(append([]string{}, origins...), c.Environments[env]...)
+}
+
+// SecurityConfig sets the security headers added to every response. Empty values are not sent.
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration `yaml:"hstsMaxAge"` // Sent on HTTPS requests (X-Forwarded-Proto from the ingress) only
+	HSTSPreload           bool          `yaml:"hstsPreload"`
+	ContentSecurityPolicy string        `yaml:"contentSecurityPolicy"`
+	FrameOptions          string        `yaml:"frameOptions"` // X-Frame-Options: DENY or SAMEORIGIN
+	ReferrerPolicy        string        `yaml:"referrerPolicy"`
+}
+
+// BodyLimitConfig caps request body sizes, e.g. "1M". The first matching route overrides Default.
+type BodyLimitConfig struct {
+	Default string           `yaml:"default"` // Empty is unlimited
+	Routes  []BodyLimitRoute `yaml:"routes"`
+}
+
+type BodyLimitRoute struct {
+	Methods []string `yaml:"methods"` // Empty matches every method
+	Path    string   `yaml:"path"`    // Same patterns as access routes
+	Limit   string   `yaml:"limit"`
+}
+
+func (c BodyLimitConfig) limitFor(method, path string) string {
+	for _, r := range c.Routes {
+		if (Route{Methods: r.Methods, Path: r.Path}).matches(method, path) {
+			return r.Limit
+		}
+	}
+	return c.Default
+}
+
+// UserServiceURL is the (first) URL of the "user-service" entry, which registration and account deletion call directly.
+func (c *Config) UserServiceURL() string {
+	for _, s := range c.Services {
+		if s.Name == "user-service" {
+			return s.Targets()[0]
+		}
+	}
+	return ""
 }
 
 type Service struct {
-	Name  string `yaml:"name"`
-	URL   string `yaml:"url"`
-	Proxy Proxy  `yaml:"proxy"`
+	Name     string         `yaml:"name"`
+	URL      string         `yaml:"url"`
+	URLs     []string       `yaml:"urls"` // Several targets to balance between, instead of url
+	Proxy    Proxy          `yaml:"proxy"`
+	Upstream UpstreamConfig `yaml:"upstream"`
+}
+
+// Targets returns the service's upstream URLs.
+func (s Service) Targets() []string {
+	if len(s.URLs) > 0 {
+		return s.URLs
+	}
+	return []string{s.URL}
+}
+
+// UpstreamConfig tunes how the service's targets are called and when they are taken out of rotation.
+// Zero values mean the defaults below.
+type UpstreamConfig struct {
+	Timeout     time.Duration     `yaml:"timeout"`     // Until the response headers arrive
+	Retries     int               `yaml:"retries"`     // On another target, for idempotent methods when the target is unreachable; -1 disables
+	MaxFailures int               `yaml:"maxFailures"` // Consecutive 5xx or connection errors that eject a target
+	EjectFor    time.Duration     `yaml:"ejectFor"`    // First ejection, doubled on every repeat
+	HealthCheck HealthCheckConfig `yaml:"healthCheck"`
+}
+
+type HealthCheckConfig struct {
+	Path     string        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"` // Negative disables active health checks
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+func (u UpstreamConfig) withDefaults() UpstreamConfig {
+	if u.Timeout == 0 {
+		u.Timeout = 10 * time.Second
+	}
+	if u.Retries == 0 {
+		u.Retries = 1
+	} else if u.Retries < 0 {
+		u.Retries = 0
+	}
+	if u.MaxFailures == 0 {
+		u.MaxFailures = 5
+	}
+	if u.EjectFor == 0 {
+		u.EjectFor = 30 * time.Second
+	}
+	if u.HealthCheck.Path == "" {
+		u.HealthCheck.Path = "/health"
+	}
+	if u.HealthCheck.Interval == 0 {
+		u.HealthCheck.Interval = 10 * time.Second
+	}
+	if u.HealthCheck.Timeout == 0 {
+		u.HealthCheck.Timeout = 2 * time.Second
+	}
+	return u
 }
 
 type Proxy struct {
 	Path    string `yaml:"path"`
 	Rewrite string `yaml:"rewrite"`
+	// Access of requests no route matches; defaults to authenticated
+	Access string  `yaml:"access"`
+	Routes []Route `yaml:"routes"`
+}
+
+// Access levels of a route
+const (
+	AccessPublic        = "public"        // Anyone; a valid token still identifies the caller
+	AccessAuthenticated = "authenticated" // A valid token is required
+)
+
+// Route sets who may call matching requests. The first matching route of a service wins.
+type Route struct {
+	Methods []string `yaml:"methods"` // Empty matches every method
+	Path    string   `yaml:"path"`    // Gateway path; ":name" matches one segment, a final "*" the rest
+	Access  string   `yaml:"access"`  // public or authenticated (default)
+	Roles   []string `yaml:"roles"`   // Caller needs one of these X-User-Roles entries; implies authenticated
+}
+
+func (r Route) matches(method, path string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchPath(r.Path, path)
+}
+
+// matchPath matches a request path against a route pattern, ignoring trailing slashes.
+func matchPath(pattern, path string) bool {
+	pp := strings.Split(strings.Trim(pattern, "/"), "/")
+	rp := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range pp {
+		if seg == "*" && i == len(pp)-1 {
+			return true
+		}
+		if i >= len(rp) {
+			return false
+		}
+		if strings.HasPrefix(seg, ":") {
+			if rp[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != rp[i] {
+			return false
+		}
+	}
+	return len(pp) == len(rp)
+}
+
+// policy returns the access level and required roles for a request to this service.
+func (p Proxy) policy(method, path string) (string, []string) {
+	for _, r := range p.Routes {
+		if !r.matches(method, path) {
+			continue
+		}
+		if len(r.Roles) > 0 || r.Access == "" {
+			return AccessAuthenticated, r.Roles
+		}
+		return r.Access, nil
+	}
+	if p.Access == "" {
+		return AccessAuthenticated, nil
+	}
+	return p.Access, nil
+}
+
+// RateLimitConfig sets the token buckets callers draw from. Signed-in callers are counted by user ID,
+// anonymous ones by client IP. A matching route gets its own, usually stricter, bucket instead.
+type RateLimitConfig struct {
+	User      Limit            `yaml:"user"`
+	Anonymous Limit            `yaml:"anonymous"`
+	Routes    []RateLimitRoute `yaml:"routes"` // First match wins
+}
+
+// Limit allows Requests per Per on average, in bursts of up to Burst (default Requests). Zero Requests is unlimited.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+type RateLimitRoute struct {
+	Methods []string `yaml:"methods"` // Empty matches every method
+	Path    string   `yaml:"path"`    // Same patterns as access routes
+	Limit   `yaml:",inline"`
+}
+
+func (r RateLimitRoute) matches(method, path string) bool {
+	return Route{Methods: r.Methods, Path: r.Path}.matches(method, path)
+}
+
+// limitFor returns the bucket name and limit for a request, by whether the caller is signed in.
+func (c RateLimitConfig) limitFor(method, path string, signedIn bool) (string, Limit) {
+	for _, r := range c.Routes {
+		if r.matches(method, path) {
+			// Named after the rule, not its position, so buckets survive a reload that reorders rules
+			return "route:" + strings.Join(r.Methods, ",") + ":" + r.Path, r.Limit
+		}
+	}
+	if signedIn {
+		return "user", c.User
+	}
+	return "anonymous", c.Anonymous
+}
+
+func (l Limit) validate() error {
+	switch {
+	case l.Requests < 0 || l.Burst < 0:
+		return fmt.Errorf("requests and burst cannot be negative")
+	case l.Requests > 0 && l.Per <= 0:
+		return fmt.Errorf("per must be a positive duration")
+	}
+	return nil
+}
+
+func flatten(lists map[string][]string) []string {
+	var all []string
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+func validMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func validAccess(access string) bool {
+	return access == "" || access == AccessPublic || access == AccessAuthenticated
+}
+
+// validate rejects rules that would silently not apply as intended.
+func (c *Config) validate() error {
+	if c.UserServiceURL() == "" {
+		return fmt.Errorf("no user-service entry")
+	}
+	for _, s := range c.Services {
+		if s.URL != "" && len(s.URLs) > 0 {
+			return fmt.Errorf("service %s: set url or urls, not both", s.Name)
+		}
+		for _, target := range s.Targets() {
+			if u, err := url.Parse(target); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("service %s: invalid url %q", s.Name, target)
+			}
+		}
+		if u := s.Upstream; u.Timeout < 0 || u.MaxFailures < 0 || u.EjectFor < 0 || u.HealthCheck.Timeout < 0 {
+			return fmt.Errorf("service %s: upstream settings cannot be negative", s.Name)
+		}
+		if !validAccess(s.Proxy.Access) {
+			return fmt.Errorf("service %s: unknown access %q", s.Name, s.Proxy.Access)
+		}
+		for i, r := range s.Proxy.Routes {
+			if !validAccess(r.Access) {
+				return fmt.Errorf("service %s, route %d: unknown access %q", s.Name, i, r.Access)
+			}
+			if r.Access == AccessPublic && len(r.Roles) > 0 {
+				return fmt.Errorf("service %s, route %d: a public route cannot require roles", s.Name, i)
+			}
+			if !matchPath(s.Proxy.Path+"/*", r.Path) {
+				return fmt.Errorf("service %s, route %d: %q is outside %s", s.Name, i, r.Path, s.Proxy.Path)
+			}
+			for _, m := range r.Methods {
+				if !validMethod(m) {
+					return fmt.Errorf("service %s, route %d: unknown method %q", s.Name, i, m)
+				}
+			}
+		}
+	}
+
+	for _, origin := range append(c.CORS.origins(""), flatten(c.CORS.Environments)...) {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				return fmt.Errorf("cors: origin * cannot be combined with allowCredentials")
+			}
+			continue
+		}
+		if !validOriginPattern(origin) {
+			return fmt.Errorf("cors: invalid origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+	for _, m := range c.CORS.AllowMethods {
+		if !validMethod(m) {
+			return fmt.Errorf("cors: unknown method %q", m)
+		}
+	}
+	switch strings.ToUpper(c.Security.FrameOptions) {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("security: frameOptions must be DENY or SAMEORIGIN")
+	}
+	if c.Security.HSTSMaxAge < 0 {
+		return fmt.Errorf("security: hstsMaxAge cannot be negative")
+	}
+	if _, err := parseBodyLimit(c.BodyLimit.Default); err != nil {
+		return fmt.Errorf("bodyLimit.default: %w", err)
+	}
+	for i, r := range c.BodyLimit.Routes {
+		if _, err := parseBodyLimit(r.Limit); err != nil || r.Limit == "" {
+			return fmt.Errorf("bodyLimit route %d: invalid limit %q", i, r.Limit)
+		}
+		for _, m := range r.Methods {
+			if !validMethod(m) {
+				return fmt.Errorf("bodyLimit route %d: unknown method %q", i, m)
+			}
+		}
+	}
+
+	if err := c.RateLimit.User.validate(); err != nil {
+		return fmt.Errorf("rateLimit.user: %w", err)
+	}
+	if err := c.RateLimit.Anonymous.validate(); err != nil {
+		return fmt.Errorf("rateLimit.anonymous: %w", err)
+	}
+	for i, r := range c.RateLimit.Routes {
+		if err := r.Limit.validate(); err != nil {
+			return fmt.Errorf("rateLimit route %d: %w", i, err)
+		}
+		if !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("rateLimit route %d: path %q must start with /", i, r.Path)
+		}
+		for _, m := range r.Methods {
+			if !validMethod(m) {
+				return fmt.Errorf("rateLimit route %d: unknown method %q", i, m)
+			}
+		}
+	}
+	return nil
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -26,12 +392,19 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseConfig(data)
+}
 
+// parseConfig decodes and validates a config file's contents.
+func parseConfig(data []byte) (*Config, error) {
 	var config Config
-	err = yaml.Unmarshal(data, &config)
+	err := yaml.Unmarshal(data, &config)
 	if err != nil {
 		return nil, err
 	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,4 +41,190 @@ services:
 func TestLoadConfig_NotFound(t *testing.T) {
 	_, err := LoadConfig("non-existent-file.yaml")
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+func TestLoadConfig_RepoConfig(t *testing.T) {
+	config, err := LoadConfig("config.yaml")
+	require.NoError(t, err)
+
+	byName := map[string]Proxy{}
+	for _, s := range config.Services {
+		byName[s.Name] = s.Proxy
+	}
+
+	tests := []struct {
+		service, method, path string
+		access                string
+		roles                 []string
+	}{
+		{"tournament-service", "GET", "/api/tournaments", AccessPublic, nil},
+		{"tournament-service", "GET", "/api/tournaments/", AccessPublic, nil},
+		{"tournament-service", "GET", "/api/tournaments/t1", AccessPublic, nil},
+		{"tournament-service", "POST", "/api/tournaments", AccessAuthenticated, nil},
+		{"tournament-service", "PATCH", "/api/tournaments/t1", AccessAuthenticated, nil},
+		{"tournament-service", "GET", "/api/tournaments/t1/payouts", AccessAuthenticated, nil},
+		{"tournament-service", "GET", "/api/tournaments/t1/payments", AccessAuthenticated, nil},
+		{"team-service", "GET", "/api/teams", AccessPublic, nil},
+		{"team-service", "GET", "/api/teams/me/teams", AccessAuthenticated, nil},
+		{"team-service", "DELETE", "/api/teams/x", AccessAuthenticated, nil},
+		{"bracket-service", "GET", "/api/brackets/t1", AccessPublic, nil},
+		{"bracket-service", "GET", "/api/brackets/t1/stream", AccessPublic, nil},
+		{"bracket-service", "GET", "/api/brackets/matches/m1/reschedule", AccessAuthenticated, nil},
+		{"bracket-service", "POST", "/api/brackets/generate", AccessAuthenticated, nil},
+		{"ratings", "GET", "/api/ratings/chess/leaderboard", AccessPublic, nil},
+		{"ratings", "POST", "/api/ratings/recompute", AccessAuthenticated, []string{"SuperAdmin"}},
+		{"user-service", "GET", "/api/users/u1", AccessAuthenticated, nil},
+		{"user-service", "GET", "/api/users/u1/stats", AccessPublic, nil},
+	}
+	for _, tc := range tests {
+		access, roles := byName[tc.service].policy(tc.method, tc.path)
+		assert.Equal(t, tc.access, access, "%s %s", tc.method, tc.path)
+		assert.Equal(t, tc.roles, roles, "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestLoadConfig_RateLimits(t *testing.T) {
+	config, err := LoadConfig("config.yaml")
+	require.NoError(t, err)
+
+	name, limit := config.RateLimit.limitFor("POST", "/api/register", false)
+	assert.Equal(t, "route:POST:/api/register", name)
+	assert.Equal(t, 10*time.Minute, limit.Per)
+
+	name, _ = config.RateLimit.limitFor("POST", "/api/tournaments/t1/register", true)
+	assert.Equal(t, "route:POST:/api/tournaments/:id/register", name)
+
+	name, limit = config.RateLimit.limitFor("GET", "/api/tournaments/t1", true)
+	assert.Equal(t, "user", name)
+	assert.Equal(t, config.RateLimit.User, limit)
+
+	name, _ = config.RateLimit.limitFor("GET", "/api/tournaments/t1", false)
+	assert.Equal(t, "anonymous", name)
+}
+
+func TestLoadConfig_SecuritySettings(t *testing.T) {
+	config, err := LoadConfig("config.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://t-hub.ltu-m7011e-4.se"}, config.CORS.origins("prod"))
+	assert.Contains(t, config.CORS.origins("dev"), "http://localhost:5173")
+	assert.Equal(t, "DENY", config.Security.FrameOptions)
+	assert.Equal(t, "1M", config.BodyLimit.limitFor("POST", "/api/teams"))
+	assert.Equal(t, "3M", config.BodyLimit.limitFor("PUT", "/api/users/me/avatar"))
+}
+
+func TestMatchPath(t *testing.T) {
+	assert.True(t, matchPath("/api/brackets/:id", "/api/brackets/t1"))
+	assert.False(t, matchPath("/api/brackets/:id", "/api/brackets"))
+	assert.False(t, matchPath("/api/brackets/:id", "/api/brackets/t1/stream"))
+	assert.True(t, matchPath("/api/ratings/*", "/api/ratings/chess/leaderboard"))
+	assert.True(t, matchPath("/api/ratings/*", "/api/ratings"))
+	assert.False(t, matchPath("/api/ratings/*", "/api/ratingsX"))
+	assert.False(t, matchPath("/api/teams", "/api/teams2"))
+}
+
+func TestLoadConfig_InvalidRoutes(t *testing.T) {
+	invalid := map[string]string{
+		"unknown access": `
+services:
+  - name: "user-service"
+    url: "http://s"
+    proxy:
+      path: "/api/s"
+      routes: [{path: "/api/s", access: "everyone"}]
+`,
+		"public with roles": `
+services:
+  - name: "user-service"
+    url: "http://s"
+    proxy:
+      path: "/api/s"
+      routes: [{path: "/api/s", access: public, roles: ["SuperAdmin"]}]
+`,
+		"outside service": `
+services:
+  - name: "user-service"
+    url: "http://s"
+    proxy:
+      path: "/api/s"
+      routes: [{path: "/api/other", access: public}]
+`,
+		"rate limit without period": `
+services:
+  - name: "user-service"
+    url: "http://s"
+    proxy: {path: "/api/s"}
+rateLimit:
+  user: {requests: 10}
+`,
+		"no user-service": `
+services:
+  - name: "team-service"
+    url: "http://s"
+    proxy: {path: "/api/s"}
+`,
+		"invalid url": `
+services:
+  - name: "user-service"
+    url: "s:8080"
+    proxy: {path: "/api/s"}
+`,
+		"url and urls": `
+services:
+  - name: "user-service"
+    url: "http://a"
+    urls: ["http://b"]
+    proxy: {path: "/api/s"}
+`,
+		"origin with path": `
+services:
+  - name: "user-service"
+    url: "http://a"
+    proxy: {path: "/api/s"}
+cors:
+  allowOrigins: ["https://t-hub.example.com/app"]
+`,
+		"wildcard origin with credentials": `
+services:
+  - name: "user-service"
+    url: "http://a"
+    proxy: {path: "/api/s"}
+cors:
+  allowOrigins: ["*"]
+  allowCredentials: true
+`,
+		"unknown frame option": `
+services:
+  - name: "user-service"
+    url: "http://a"
+    proxy: {path: "/api/s"}
+security:
+  frameOptions: ALLOW-FROM https://x
+`,
+		"invalid body limit": `
+services:
+  - name: "user-service"
+    url: "http://a"
+    proxy: {path: "/api/s"}
+bodyLimit:
+  default: "lots"
+`,
+		"unknown method": `
+services:
+  - name: "user-service"
+    url: "http://s"
+    proxy:
+      path: "/api/s"
+      routes: [{methods: ["FETCH"], path: "/api/s", access: public}]
+`,
+	}
+	for name, content := range invalid {
+		tmpfile, err := os.CreateTemp(t.TempDir(), "config_*.yaml")
+		require.NoError(t, err)
+		_, err = tmpfile.WriteString(content)
+		require.NoError(t, err)
+		tmpfile.Close()
+
+		_, err = LoadConfig(tmpfile.Name())
+		assert.Error(t, err, name)
+	}
+}
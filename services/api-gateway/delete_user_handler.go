@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -27,13 +28,13 @@ func (h *DeletionHandler) Handle(c echo.Context) error {
 
 	token, err := h.Keycloak.Client.LoginAdmin(ctx, adminUser, adminPassword, "master")
 	if err != nil {
-		c.Logger().Errorf("Failed to login as admin: %v", err)
+		slog.ErrorContext(ctx, "failed to log in to Keycloak as admin", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
 	}
 
 	err = h.Keycloak.Client.DeleteUser(ctx, token.AccessToken, h.Keycloak.Realm, userID)
 	if err != nil {
-		c.Logger().Errorf("Failed to delete user from Keycloak: %v", err)
+		slog.ErrorContext(ctx, "failed to delete user from Keycloak", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete user account")
 	}
 
@@ -43,10 +44,13 @@ func (h *DeletionHandler) Handle(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create request to user-service")
 	}
+	// user-service checks the token itself and only lets users delete their own profile
+	req.Header.Set("Authorization", c.Request().Header.Get("Authorization"))
+	req.Header.Set(echo.HeaderXRequestID, c.Request().Header.Get(echo.HeaderXRequestID))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := tracedClient.Do(req)
 	if err != nil {
-		c.Logger().Errorf("Failed to call user-service delete: %v", err)
+		slog.ErrorContext(ctx, "failed to call user-service delete", "error", err)
 		// Note: Keycloak deletion already succeeded, so we have a partial consistency state.
 		// ideally, we would log this for manual cleanup, but for now we report error.
 		return echo.NewHTTPError(http.StatusBadGateway, "Account deleted from login, but failed to clean up profile data")
@@ -54,7 +58,7 @@ func (h *DeletionHandler) Handle(c echo.Context) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
-		c.Logger().Errorf("User-service returned unexpected status: %d", resp.StatusCode)
+		slog.ErrorContext(ctx, "user-service delete returned unexpected status", "status", resp.StatusCode)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to clean up user profile")
 	}
 
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Identity headers forwarded to the services. They are only ever set by AuthMiddleware from a
+// verified token; anything a client sends under these names is dropped first.
+//
+//	X-User-Id     Keycloak subject
+//	X-User-Name   preferred_username
+//	X-User-Email  email ("" if the token has none)
+//	X-User-Roles  comma separated: realm roles as is (e.g. "SuperAdmin"), client roles as "<client>:<role>"
+const (
+	HeaderUserID        = "X-User-Id"
+	HeaderUserName      = "X-User-Name"
+	HeaderUserEmail     = "X-User-Email"
+	HeaderUserRoles     = "X-User-Roles"
+	HeaderGatewaySecret = "X-Gateway-Secret"
+)
+
+// identityClaims are the token claims the identity headers are built from.
+type identityClaims struct {
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	RealmAccess       struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
+	ResourceAccess map[string]struct {
+		Roles []string `json:"roles"`
+	} `json:"resource_access"`
+}
+
+// roles flattens realm and client roles into the X-User-Roles format, sorted and without duplicates.
+// Names that would break the comma separated list are skipped.
+func (c identityClaims) roles() []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(role string) {
+		if role == "" || strings.ContainsAny(role, ",\r\n") || seen[role] {
+			return
+		}
+		seen[role] = true
+		out = append(out, role)
+	}
+	for _, r := range c.RealmAccess.Roles {
+		add(r)
+	}
+	for client, access := range c.ResourceAccess {
+		for _, r := range access.Roles {
+			if r != "" {
+				add(client + ":" + r)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// isIdentityHeader reports whether a (canonical) header name belongs to the gateway's identity set.
+// Every X-User-* header counts, so future additions are covered too.
+func isIdentityHeader(name string) bool {
+	return strings.HasPrefix(name, "X-User-") || name == HeaderGatewaySecret || name == HeaderIdentityAssertion
+}
+
+// stripIdentityHeaders removes client supplied identity headers from the request.
+func stripIdentityHeaders(h http.Header) {
+	for name := range h {
+		if isIdentityHeader(http.CanonicalHeaderKey(name)) {
+			delete(h, name)
+		}
+	}
+}
+
+// StripIdentityHeaders runs before routing, so no path (public ones included) forwards forged identity.
+func StripIdentityHeaders() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			stripIdentityHeaders(c.Request().Header)
+			return next(c)
+		}
+	}
+}
+
+// setIdentityHeaders overwrites the identity headers with the verified caller.
+func setIdentityHeaders(h http.Header, userID string, claims identityClaims, gatewaySecret string) {
+	stripIdentityHeaders(h)
+	h.Set(HeaderUserID, userID)
+	h.Set(HeaderUserName, claims.PreferredUsername)
+	h.Set(HeaderUserEmail, claims.Email)
+	h.Set(HeaderUserRoles, strings.Join(claims.roles(), ","))
+	if gatewaySecret != "" {
+		h.Set(HeaderGatewaySecret, gatewaySecret)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityClaims_Roles(t *testing.T) {
+	var c identityClaims
+	c.RealmAccess.Roles = []string{"user", "SuperAdmin", "user", "bad,role", ""}
+	c.ResourceAccess = map[string]struct {
+		Roles []string `json:"roles"`
+	}{
+		"account":        {Roles: []string{"view-profile"}},
+		"t-hub-frontend": {Roles: []string{"organizer", ""}},
+	}
+
+	assert.Equal(t, []string{"SuperAdmin", "account:view-profile", "t-hub-frontend:organizer", "user"}, c.roles())
+	assert.Empty(t, identityClaims{}.roles())
+}
+
+func TestStripIdentityHeaders_PublicRoute(t *testing.T) {
+	e := echo.New()
+	e.Pre(StripIdentityHeaders())
+	e.POST("/api/register", func(c echo.Context) error {
+		h := c.Request().Header
+		assert.Empty(t, h.Get("X-User-Id"))
+		assert.Empty(t, h.Get("X-User-Roles"))
+		assert.Empty(t, h.Get("X-Gateway-Secret"))
+		assert.Equal(t, "application/json", h.Get("Content-Type"))
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/register", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", "admin-id")
+	req.Header.Set("x-user-roles", "SuperAdmin")
+	req.Header.Set("X-Gateway-Secret", "guess")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
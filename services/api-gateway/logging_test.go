@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs sends slog's records to a buffer for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestLogger_Redacts(t *testing.T) {
+	buf := captureLogs(t)
+
+	slog.Info("invite sent to jane.doe@example.com",
+		"email", "jane.doe@example.com",
+		"header", "Bearer abc.def-ghi",
+		"error", errors.New("token eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJ1MSJ9.c2ln rejected"))
+
+	out := buf.String()
+	assert.NotContains(t, out, "jane.doe@example.com")
+	assert.NotContains(t, out, "abc.def-ghi")
+	assert.NotContains(t, out, "eyJ")
+
+	r := logRecords(t, buf)[0]
+	assert.Equal(t, "invite sent to [email]", r["msg"])
+	assert.Equal(t, "[redacted]", r["email"])
+	assert.Equal(t, "[token]", r["header"])
+	assert.Equal(t, "token [token] rejected", r["error"])
+	assert.Equal(t, "api-gateway", r["service"])
+}
+
+func TestRequestLogging(t *testing.T) {
+	buf := captureLogs(t)
+
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(echo.HeaderXRequestID)
+		w.Header().Set(echo.HeaderXRequestID, forwarded)
+	}))
+	defer upstream.Close()
+
+	e, _ := newTestProxy(t, UpstreamConfig{}, upstream.URL)
+	e.Use(RequestIDMiddleware(), RequestLogMiddleware())
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			setLogUser(c.Request().Context(), "user-1") // As AuthMiddleware does
+			slog.InfoContext(c.Request().Context(), "authenticated")
+			return next(c)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/s/x", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"client-id-1"}, rec.Header().Values(echo.HeaderXRequestID))
+	assert.Equal(t, "client-id-1", forwarded)
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "authenticated", records[0]["msg"])
+	assert.Equal(t, "client-id-1", records[0]["request_id"])
+	access := records[1]
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "client-id-1", access["request_id"])
+	assert.Equal(t, "user-1", access["user_id"])
+	assert.Equal(t, "/api/s/*", access["route"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+	assert.Contains(t, access, "latency_ms")
+
+	// IDs that could garble the logs are replaced
+	req = httptest.NewRequest(http.MethodGet, "/api/s/x", nil)
+	req.Header.Set(echo.HeaderXRequestID, strings.Repeat("x", 200))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Len(t, rec.Header().Get(echo.HeaderXRequestID), 32)
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), forwarded)
+}
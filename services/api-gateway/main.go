@@ -2,63 +2,88 @@ package main
 
 import (
 	"context"
+	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 )
 
 func main() {
-	// Load configuration
-	config, err := LoadConfig("config.yaml")
-	if err != nil {
-		panic(err)
-	}
+	InitLogging()
 
 	// Configuration via environment variables
 	keycloakURL := getEnv("KEYCLOAK_URL", "")
 	keycloakRealm := getEnv("KEYCLOAK_REALM", "t-hub")
 	port := getEnv("PORT", ":8080")
-
-	// Find the user-service URL from the config, making it the single source of truth
-	var userServiceURL string
-	for _, service := range config.Services {
-		if service.Name == "user-service" {
-			userServiceURL = service.URL
-			break
-		}
-	}
-	if userServiceURL == "" {
-		panic("user-service URL not found in config.yaml")
+	// Mount the ConfigMap as a directory (not subPath), otherwise Kubernetes never updates the file
+	configPath := getEnv("CONFIG_PATH", "config.yaml")
+	reloadInterval, err := time.ParseDuration(getEnv("CONFIG_RELOAD_INTERVAL", "10s"))
+	if err != nil {
+		log.Fatalf("invalid CONFIG_RELOAD_INTERVAL: %v", err)
 	}
 
 	// With production certificates, we use the default HTTP client which performs TLS verification.
 	ctx := context.Background()
 
+	// Traces start here and continue in every service the request reaches
+	shutdownTracing, err := InitTracing(ctx, serviceName)
+	if err != nil {
+		log.Fatalf("init tracing: %v", err)
+	}
+	defer shutdownTracing(ctx)
+
 	// Initialize the OIDC provider
 	provider, err := oidc.NewProvider(ctx, keycloakURL+"/realms/"+keycloakRealm)
 	if err != nil {
 		panic(err)
 	}
 
+	// Signs the identity assertions services verify (disabled without a key)
+	signer, err := NewAssertionSignerFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	// Rate limit buckets, shared between replicas when Redis is configured
+	limits := NewRateLimitStoreFromEnv()
+
+	// Upstream targets and their health, kept across config reloads
+	upstreams := NewUpstreams()
+
 	// Create the Keycloak client
 	keycloakClient := NewKeycloakClient(keycloakURL, keycloakRealm)
 
-	// Create the registration handler
-	registrationHandler := &RegistrationHandler{
-		Keycloak:    keycloakClient,
-		UserService: userServiceURL,
-	}
+	// Everything that depends on the config file is rebuilt when it changes
+	reloader := &Reloader{Path: configPath, Upstreams: upstreams}
+	reloader.Build = func(config *Config) http.Handler {
+		// The user-service entry is the single source of truth for its URL
+		userServiceURL := config.UserServiceURL()
 
-	deletionHandler := &DeletionHandler{
-        Keycloak:    keycloakClient,
-        UserService: userServiceURL,
-    }
+		registrationHandler := &RegistrationHandler{
+			Keycloak:    keycloakClient,
+			UserService: userServiceURL,
+			Signer:      signer,
+		}
+		deletionHandler := &DeletionHandler{
+			Keycloak:    keycloakClient,
+			UserService: userServiceURL,
+		}
 
-	// Create the router
-	e := NewRouter(config, provider, registrationHandler, deletionHandler, userServiceURL)
+		e := NewRouter(config, provider, signer, limits, upstreams, registrationHandler, deletionHandler, userServiceURL)
+		e.GET("/admin/config", reloader.Status, RequireRoles(provider, signer, "SuperAdmin"))
+		return e
+	}
+	if _, err := reloader.Load(); err != nil {
+		log.Fatalf("load %s: %v", configPath, err)
+	}
+	slog.Info("serving config", "version", reloader.Version())
+	go reloader.Watch(ctx, reloadInterval)
 
 	// Start the server
-	e.Logger.Fatal(e.Start(port))
+	log.Fatal(http.ListenAndServe(port, reloader))
 }
 
 // Helper to read env vars
@@ -67,4 +92,4 @@ func getEnv(key, fallback string) string {
 		return value
 	}
 	return fallback
-}
\ No newline at end of file
+}
@@ -4,6 +4,7 @@ import (
 	// "context"
 	// "encoding/json"
 	// "fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -18,50 +19,169 @@ type User struct {
 	Email    string `json:"email"`
 }
 
-func AuthMiddleware(provider *oidc.Provider, userServiceURL string) echo.MiddlewareFunc {
-	verifier := provider.Verifier(&oidc.Config{
-		SkipClientIDCheck: true, // We trust the issuer (Keycloak), any client is fine
-	})
+// authenticator verifies Keycloak tokens and turns them into identity headers.
+type authenticator struct {
+	verifier      *oidc.IDTokenVerifier
+	signer        *AssertionSigner
+	gatewaySecret string
+}
+
+func newAuthenticator(provider *oidc.Provider, signer *AssertionSigner) *authenticator {
+	return &authenticator{
+		verifier: provider.Verifier(&oidc.Config{
+			SkipClientIDCheck: true, // We trust the issuer (Keycloak), any client is fine
+		}),
+		signer: signer,
+		// Lets services that trust our identity headers (e.g. team-service) tell us apart from other callers
+		gatewaySecret: getEnv("GATEWAY_SHARED_SECRET", ""),
+	}
+}
+
+// hasCredentials reports whether the request carries a token to verify.
+func hasCredentials(c echo.Context) bool {
+	return c.Request().Header.Get("Authorization") != "" ||
+		(isStreamRequest(c) && c.Request().URL.Query().Get("access_token") != "")
+}
+
+// authenticate verifies the caller's token and sets the identity headers (and assertion) for the services.
+func (a *authenticator) authenticate(c echo.Context) (identityClaims, error) {
+	var claims identityClaims
+
+	// Extract Token
+	rawToken := c.Request().Header.Get("Authorization")
+	if rawToken == "" && isStreamRequest(c) {
+		rawToken = takeQueryToken(c.Request())
+	}
+	if rawToken == "" {
+		return claims, echo.NewHTTPError(http.StatusUnauthorized, "Missing Authorization header")
+	}
+
+	// Remove "Bearer " prefix if present
+	parts := strings.Split(rawToken, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return claims, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token format")
+	}
+
+	// Verify Token
+	idToken, err := a.verifier.Verify(c.Request().Context(), parts[1])
+	if err != nil {
+		return claims, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token: "+err.Error())
+	}
+
+	// Extract User ID (Subject)
+	// This is the unique ID from Keycloak (e.g., a UUID)
+	userID := idToken.Subject
+	setLogUser(c.Request().Context(), userID)
+
+	// Extract claims into the struct
+	if err := idToken.Claims(&claims); err != nil {
+		return claims, echo.NewHTTPError(http.StatusUnauthorized, "Failed to parse token claims")
+	}
+
+	// Inject Headers for downstream service, replacing anything the client sent
+	setIdentityHeaders(c.Request().Header, userID, claims, a.gatewaySecret)
+	if a.signer != nil {
+		assertion, err := a.signer.Mint(userID, claims)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "failed to sign identity assertion", "error", err)
+			return claims, echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+		}
+		c.Request().Header.Set(HeaderIdentityAssertion, assertion)
+	}
+	return claims, nil
+}
+
+// AuthMiddleware verifies the caller's Keycloak token and forwards who they are as identity headers,
+// plus a signed identity assertion when signer is set.
+func AuthMiddleware(provider *oidc.Provider, userServiceURL string, signer *AssertionSigner) echo.MiddlewareFunc {
+	a := newAuthenticator(provider, signer)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Extract Token
-			rawToken := c.Request().Header.Get("Authorization")
-			if rawToken == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Missing Authorization header")
+			if _, err := a.authenticate(c); err != nil {
+				return err
 			}
+			return next(c)
+		}
+	}
+}
 
-			// Remove "Bearer " prefix if present
-			parts := strings.Split(rawToken, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token format")
+// RouteAuthMiddleware applies the access rules of a proxied service. Public routes pass without a
+// token; with one, the caller is identified as usual (an invalid one just leaves them anonymous).
+// Other routes need a valid token and, if the route lists roles, one of those roles.
+func RouteAuthMiddleware(proxy Proxy, provider *oidc.Provider, signer *AssertionSigner) echo.MiddlewareFunc {
+	a := newAuthenticator(provider, signer)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			access, roles := proxy.policy(c.Request().Method, c.Request().URL.Path)
+
+			if access == AccessPublic {
+				if hasCredentials(c) {
+					if _, err := a.authenticate(c); err != nil {
+						stripIdentityHeaders(c.Request().Header)
+					}
+				}
+				return next(c)
 			}
 
-			// Verify Token
-			idToken, err := verifier.Verify(c.Request().Context(), parts[1])
+			claims, err := a.authenticate(c)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token: "+err.Error())
+				return err
 			}
+			if len(roles) > 0 && !hasAnyRole(claims.roles(), roles) {
+				return echo.NewHTTPError(http.StatusForbidden, "Insufficient role")
+			}
+			return next(c)
+		}
+	}
+}
 
-			// Extract User ID (Subject)
-			// This is the unique ID from Keycloak (e.g., a UUID)
-			userID := idToken.Subject
+// RequireRoles lets through callers with a valid token and one of the given roles.
+func RequireRoles(provider *oidc.Provider, signer *AssertionSigner, roles ...string) echo.MiddlewareFunc {
+	a := newAuthenticator(provider, signer)
 
-			var claims struct {
-                PreferredUsername string `json:"preferred_username"`
-                //Email             string `json:"email"` // Add if it becomes necessary.
-            }
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := a.authenticate(c)
+			if err != nil {
+				return err
+			}
+			if !hasAnyRole(claims.roles(), roles) {
+				return echo.NewHTTPError(http.StatusForbidden, "Insufficient role")
+			}
+			return next(c)
+		}
+	}
+}
 
-			// Extract claims into the struct
-            if err := idToken.Claims(&claims); err != nil {
-                return echo.NewHTTPError(http.StatusUnauthorized, "Failed to parse token claims")
-            }
+func hasAnyRole(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-			// Inject Headers for downstream service
-			c.Request().Header.Set("X-User-Id", userID)
-			c.Request().Header.Set("X-User-Name", claims.PreferredUsername)
+// isStreamRequest reports whether the request opens a Server-Sent Events stream or a WebSocket.
+// Browsers cannot set the Authorization header for either, so these may pass the token as ?access_token=.
+func isStreamRequest(c echo.Context) bool {
+	return c.IsWebSocket() || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/event-stream")
+}
 
-			return next(c)
-		}
+// takeQueryToken moves ?access_token= out of the URL, so it is neither proxied nor logged,
+// and returns it as a bearer Authorization value ("" if absent).
+func takeQueryToken(req *http.Request) string {
+	query := req.URL.Query()
+	token := query.Get("access_token")
+	if token == "" {
+		return ""
 	}
+	query.Del("access_token")
+	req.URL.RawQuery = query.Encode()
+	req.RequestURI = req.URL.RequestURI()
+	return "Bearer " + token
 }
@@ -134,7 +134,7 @@ func TestAuthMiddleware(t *testing.T) {
 				return c.String(http.StatusOK, "success")
 			}
 
-			middleware := AuthMiddleware(provider, "http://user-service")
+			middleware := AuthMiddleware(provider, "http://user-service", nil)
 			err := middleware(handler)(c)
 
 			if tc.expectedStatus == http.StatusOK {
@@ -150,4 +150,212 @@ func TestAuthMiddleware(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestAuthMiddleware_StreamQueryToken(t *testing.T) {
+	provider, issuer, signer := setupMockOIDCProvider(t)
+
+	claims := map[string]interface{}{
+		"sub":                "user-123",
+		"iss":                issuer,
+		"aud":                "test-client",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "testuser",
+	}
+	tokenString, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+
+	middleware := AuthMiddleware(provider, "http://user-service", nil)
+	handler := func(c echo.Context) error {
+		assert.Equal(t, "user-123", c.Request().Header.Get("X-User-Id"))
+		// The token must not reach the upstream service or the access log
+		assert.Empty(t, c.Request().URL.Query().Get("access_token"))
+		assert.NotContains(t, c.Request().RequestURI, "access_token")
+		assert.Equal(t, "x", c.Request().URL.Query().Get("keep"))
+		return c.String(http.StatusOK, "success")
+	}
+
+	t.Run("Event Stream", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/brackets/t1/stream?keep=x&access_token="+tokenString, nil)
+		req.Header.Set(echo.HeaderAccept, "text/event-stream")
+		rec := httptest.NewRecorder()
+
+		err := middleware(handler)(e.NewContext(req, rec))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Regular Request", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/brackets/t1?access_token="+tokenString, nil)
+		rec := httptest.NewRecorder()
+
+		err := middleware(handler)(e.NewContext(req, rec))
+
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*echo.HTTPError).Code)
+		}
+	})
+}
+
+func TestAuthMiddleware_IdentityHeaders(t *testing.T) {
+	provider, issuer, signer := setupMockOIDCProvider(t)
+
+	claims := map[string]interface{}{
+		"sub":                "user-123",
+		"iss":                issuer,
+		"aud":                "test-client",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "testuser",
+		"email":              "test@example.com",
+		"realm_access":       map[string]interface{}{"roles": []string{"user", "SuperAdmin"}},
+		"resource_access": map[string]interface{}{
+			"t-hub-frontend": map[string]interface{}{"roles": []string{"organizer"}},
+		},
+	}
+	tokenString, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	// Forged by the client, must be replaced
+	req.Header.Set("X-User-Id", "someone-else")
+	req.Header.Set("X-User-Roles", "SuperAdmin,god")
+	req.Header.Set("X-User-Impersonate", "admin")
+	rec := httptest.NewRecorder()
+
+	handler := func(c echo.Context) error {
+		h := c.Request().Header
+		assert.Equal(t, "user-123", h.Get("X-User-Id"))
+		assert.Equal(t, "testuser", h.Get("X-User-Name"))
+		assert.Equal(t, "test@example.com", h.Get("X-User-Email"))
+		assert.Equal(t, "SuperAdmin,t-hub-frontend:organizer,user", h.Get("X-User-Roles"))
+		assert.Len(t, h.Values("X-User-Roles"), 1)
+		assert.Empty(t, h.Get("X-User-Impersonate"))
+		return c.String(http.StatusOK, "success")
+	}
+
+	err = AuthMiddleware(provider, "http://user-service", nil)(handler)(e.NewContext(req, rec))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRouteAuthMiddleware(t *testing.T) {
+	provider, issuer, signer := setupMockOIDCProvider(t)
+
+	token := func(roles ...string) string {
+		claims := map[string]interface{}{
+			"sub":                "user-123",
+			"iss":                issuer,
+			"aud":                "test-client",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"preferred_username": "testuser",
+			"realm_access":       map[string]interface{}{"roles": roles},
+		}
+		tokenString, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return "Bearer " + tokenString
+	}
+
+	proxy := Proxy{
+		Path: "/api/ratings",
+		Routes: []Route{
+			{Methods: []string{"POST"}, Path: "/api/ratings/recompute", Roles: []string{"SuperAdmin"}},
+			{Methods: []string{"GET"}, Path: "/api/ratings/*", Access: AccessPublic},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method, path   string
+		authHeader     string
+		forgedUserID   string
+		expectedStatus int
+		expectedUserID string
+	}{
+		{name: "Public Without Token", method: "GET", path: "/api/ratings/chess", expectedStatus: http.StatusOK},
+		{name: "Public Forged Identity", method: "GET", path: "/api/ratings/chess", forgedUserID: "admin-id", expectedStatus: http.StatusOK},
+		{name: "Public With Token", method: "GET", path: "/api/ratings/chess", authHeader: token(), expectedStatus: http.StatusOK, expectedUserID: "user-123"},
+		{name: "Public With Invalid Token", method: "GET", path: "/api/ratings/chess", authHeader: "Bearer invalid.token.string", forgedUserID: "admin-id", expectedStatus: http.StatusOK},
+		{name: "Default Requires Token", method: "DELETE", path: "/api/ratings/chess", expectedStatus: http.StatusUnauthorized},
+		{name: "Default With Token", method: "DELETE", path: "/api/ratings/chess", authHeader: token(), expectedStatus: http.StatusOK, expectedUserID: "user-123"},
+		{name: "Role Without Token", method: "POST", path: "/api/ratings/recompute", expectedStatus: http.StatusUnauthorized},
+		{name: "Role Missing", method: "POST", path: "/api/ratings/recompute", authHeader: token("user"), expectedStatus: http.StatusForbidden},
+		{name: "Role Present", method: "POST", path: "/api/ratings/recompute", authHeader: token("user", "SuperAdmin"), expectedStatus: http.StatusOK, expectedUserID: "user-123"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.authHeader != "" {
+				req.Header.Set("Authorization", tc.authHeader)
+			}
+			if tc.forgedUserID != "" {
+				req.Header.Set("X-User-Id", tc.forgedUserID)
+			}
+			rec := httptest.NewRecorder()
+
+			var seenUserID string
+			handler := func(c echo.Context) error {
+				seenUserID = c.Request().Header.Get("X-User-Id")
+				return c.String(http.StatusOK, "success")
+			}
+
+			// StripIdentityHeaders runs as pre-middleware in the router
+			err := StripIdentityHeaders()(RouteAuthMiddleware(proxy, provider, nil)(handler))(e.NewContext(req, rec))
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedUserID, seenUserID)
+			} else if assert.Error(t, err) {
+				he, ok := err.(*echo.HTTPError)
+				assert.True(t, ok)
+				assert.Equal(t, tc.expectedStatus, he.Code)
+			}
+		})
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	provider, issuer, signer := setupMockOIDCProvider(t)
+
+	token := func(roles ...string) string {
+		claims := map[string]interface{}{
+			"sub":          "user-123",
+			"iss":          issuer,
+			"aud":          "test-client",
+			"exp":          time.Now().Add(time.Hour).Unix(),
+			"realm_access": map[string]interface{}{"roles": roles},
+		}
+		tokenString, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return "Bearer " + tokenString
+	}
+
+	tests := map[string]struct {
+		authHeader     string
+		expectedStatus int
+	}{
+		"Missing Header": {"", http.StatusUnauthorized},
+		"Not Admin":      {token("user"), http.StatusForbidden},
+		"Admin":          {token("user", "SuperAdmin"), http.StatusOK},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			if tc.authHeader != "" {
+				req.Header.Set("Authorization", tc.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			e.GET("/admin/config", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			}, RequireRoles(provider, nil, "SuperAdmin"))
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
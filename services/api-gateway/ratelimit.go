@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitStore keeps the token buckets. The in-memory store counts per replica;
+// a shared store (see RedisStore) makes the limits hold across all replicas.
+type RateLimitStore interface {
+	// Allow takes a token from the bucket at key. When it is empty it returns false and how long
+	// until the next token is available.
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore is the default RateLimitStore, local to this process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time // For tests
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	rate, burst := limit.rate(), float64(limit.burst())
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+}
+
+// sweep drops idle buckets once a minute. A bucket idle for an hour is full again for any
+// limit we configure, so forgetting it changes nothing.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitMiddleware limits requests per user ID, or per client IP for anonymous callers.
+// It must run after the auth middleware, so X-User-Id is one the gateway verified.
+// If the store fails, requests are let through rather than taking the site down with it.
+func RateLimitMiddleware(config RateLimitConfig, store RateLimitStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			userID := req.Header.Get(HeaderUserID)
+
+			name, limit := config.limitFor(req.Method, req.URL.Path, userID != "")
+			if limit.Requests == 0 {
+				return next(c)
+			}
+			key := "ratelimit:" + name + ":ip:" + c.RealIP()
+			if userID != "" {
+				key = "ratelimit:" + name + ":user:" + userID
+			}
+
+			allowed, retryAfter, err := store.Allow(req.Context(), key, limit)
+			if err != nil {
+				slog.WarnContext(req.Context(), "rate limit store unavailable, not limiting", "error", err)
+				return next(c)
+			}
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests")
+			}
+			return next(c)
+		}
+	}
+}
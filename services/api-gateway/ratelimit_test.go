@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := store.Allow(context.Background(), "k", limit)
+		require.NoError(t, err)
+		assert.True(t, ok, "request %d is within the burst", i)
+	}
+	ok, retryAfter, _ := store.Allow(context.Background(), "k", limit)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Other keys have their own bucket
+	ok, _, _ = store.Allow(context.Background(), "other", limit)
+	assert.True(t, ok)
+
+	// One token per second comes back
+	now = now.Add(time.Second)
+	ok, _, _ = store.Allow(context.Background(), "k", limit)
+	assert.True(t, ok)
+	ok, _, _ = store.Allow(context.Background(), "k", limit)
+	assert.False(t, ok)
+}
+
+func TestMemoryStore_SweepsIdleBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	store.Allow(context.Background(), "idle", limit)
+	now = now.Add(2 * time.Hour)
+	store.Allow(context.Background(), "active", limit)
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "active")
+}
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	config := RateLimitConfig{
+		User:      Limit{Requests: 2, Per: time.Minute},
+		Anonymous: Limit{Requests: 1, Per: time.Minute},
+		Routes: []RateLimitRoute{
+			{Methods: []string{"POST"}, Path: "/api/register", Limit: Limit{Requests: 1, Per: time.Hour}},
+		},
+	}
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	e.Use(RateLimitMiddleware(config, NewMemoryStore()))
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	e.GET("/api/tournaments", ok)
+	e.POST("/api/register", ok)
+
+	send := func(method, path, userID, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if userID != "" {
+			req.Header.Set("X-User-Id", userID)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Anonymous callers are counted per IP
+	assert.Equal(t, http.StatusOK, send("GET", "/api/tournaments", "", "10.0.0.1").Code)
+	rec := send("GET", "/api/tournaments", "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, send("GET", "/api/tournaments", "", "10.0.0.2").Code)
+
+	// Signed-in callers are counted per user, wherever they come from
+	assert.Equal(t, http.StatusOK, send("GET", "/api/tournaments", "user-1", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/api/tournaments", "user-1", "10.0.0.3").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("GET", "/api/tournaments", "user-1", "10.0.0.4").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/api/tournaments", "user-2", "10.0.0.4").Code)
+
+	// Route overrides have their own bucket
+	assert.Equal(t, http.StatusOK, send("POST", "/api/register", "", "10.0.0.1").Code)
+	rec = send("POST", "/api/register", "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+}
+
+func TestRateLimitMiddleware_StoreDownLetsRequestsThrough(t *testing.T) {
+	e := echo.New()
+	e.Use(RateLimitMiddleware(RateLimitConfig{Anonymous: Limit{Requests: 1, Per: time.Minute}}, failingStore{}))
+	e.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
+
+// fakeRedis answers every command with reply and records the commands it got.
+func fakeRedis(t *testing.T, reply string) (string, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	commands := make(chan []string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					header, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+					args := make([]string, n)
+					for i := range args {
+						size, _ := r.ReadString('\n')
+						l, _ := strconv.Atoi(strings.TrimSpace(size[1:]))
+						data := make([]byte, l+2)
+						if _, err := io.ReadFull(r, data); err != nil {
+							return
+						}
+						args[i] = string(data[:l])
+					}
+					commands <- args
+					if args[0] == "AUTH" {
+						conn.Write([]byte("+OK\r\n"))
+						continue
+					}
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), commands
+}
+
+func TestRedisStore_Allow(t *testing.T) {
+	addr, commands := fakeRedis(t, "*2\r\n:0\r\n:1500\r\n")
+	store := NewRedisStore(addr, "hunter2")
+
+	ok, retryAfter, err := store.Allow(context.Background(), "ratelimit:user:user:u1", Limit{Requests: 60, Per: time.Minute, Burst: 10})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, retryAfter)
+
+	assert.Equal(t, []string{"AUTH", "hunter2"}, <-commands)
+	eval := <-commands
+	assert.Equal(t, "EVAL", eval[0])
+	assert.Equal(t, []string{"1", "ratelimit:user:user:u1", "1", "10"}, eval[2:])
+
+	// The connection is reused, no second AUTH
+	_, _, err = store.Allow(context.Background(), "k", Limit{Requests: 1, Per: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "EVAL", (<-commands)[0])
+}
+
+func TestRedisStore_ErrorReply(t *testing.T) {
+	addr, _ := fakeRedis(t, "-NOSCRIPT scripts disabled\r\n")
+	store := NewRedisStore(addr, "")
+
+	_, _, err := store.Allow(context.Background(), "k", Limit{Requests: 1, Per: time.Second})
+	assert.EqualError(t, err, "redis: NOSCRIPT scripts disabled")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// tokenBucketScript refills and takes from the bucket atomically, using the Redis clock so replicas
+// with skewed clocks agree. It returns {allowed, milliseconds until the next token}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`
+
+// RedisStore keeps the buckets in Redis (or anything speaking its protocol, e.g. Valkey or KeyDB),
+// so every gateway replica draws from the same buckets.
+type RedisStore struct {
+	Addr     string
+	Password string
+	Timeout  time.Duration // Per command, when the context has no earlier deadline
+
+	conns chan *redisConn // Idle connections
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func NewRedisStore(addr, password string) *RedisStore {
+	return &RedisStore{Addr: addr, Password: password, Timeout: time.Second, conns: make(chan *redisConn, 8)}
+}
+
+// NewRateLimitStoreFromEnv uses Redis at RATE_LIMIT_REDIS_ADDR (host:port, password in
+// RATE_LIMIT_REDIS_PASSWORD) and falls back to the in-memory store.
+func NewRateLimitStoreFromEnv() RateLimitStore {
+	if addr := os.Getenv("RATE_LIMIT_REDIS_ADDR"); addr != "" {
+		return NewRedisStore(addr, os.Getenv("RATE_LIMIT_REDIS_PASSWORD"))
+	}
+	return NewMemoryStore()
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	reply, err := s.do(ctx, "EVAL", tokenBucketScript, "1", key,
+		strconv.FormatFloat(limit.rate(), 'f', -1, 64), strconv.Itoa(limit.burst()))
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// do sends one command and reads its reply, reusing an idle connection when there is one.
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	reply, err := conn.command(args...)
+	if err != nil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			// The connection state is unknown after an I/O error
+			conn.Close()
+			return nil, err
+		}
+	}
+	select {
+	case s.conns <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-s.conns:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: s.Timeout}
+	nc, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if s.Password != "" {
+		c.SetDeadline(time.Now().Add(s.Timeout))
+		if _, err := c.command("AUTH", s.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error reply; the connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisConn) command(args ...string) (interface{}, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"+a+"\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err // -1 is a nil reply
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			v, err := c.readReply()
+			var redisErr redisError
+			if errors.As(err, &redisErr) {
+				// Keep reading so the rest of the array is not left on the connection
+				v = redisErr
+			} else if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"github.com/Nerzal/gocloak/v13"
@@ -18,6 +19,7 @@ type RegistrationRequest struct {
 type RegistrationHandler struct {
 	Keycloak    *KeycloakClient
 	UserService string
+	Signer      *AssertionSigner // Vouches to user-service for the new user's ID; nil sends no assertion
 }
 
 func (h *RegistrationHandler) Handle(c echo.Context) error {
@@ -34,7 +36,7 @@ func (h *RegistrationHandler) Handle(c echo.Context) error {
 	
 	userID, err := h.Keycloak.CreateUser(c.Request().Context(), user, req.Password)
 	if err != nil {
-		c.Logger().Errorf("Failed to create user in Keycloak: %v", err)
+		slog.ErrorContext(c.Request().Context(), "failed to create user in Keycloak", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user in Keycloak")
 	}
 
@@ -54,12 +56,28 @@ func (h *RegistrationHandler) Handle(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create request for user-service")
 	}
 
-	resp, err := http.Post(h.UserService+"/register", "application/json", bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, h.UserService+"/register", bytes.NewBuffer(body))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create request for user-service")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(echo.HeaderXRequestID, c.Request().Header.Get(echo.HeaderXRequestID))
+	if h.Signer != nil {
+		assertion, err := h.Signer.Mint(userID, identityClaims{PreferredUsername: req.Username, Email: req.Email})
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "failed to sign identity assertion", "error", err)
+			_ = h.Keycloak.Client.DeleteUser(c.Request().Context(), "", h.Keycloak.Realm, userID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to register user in user-service")
+		}
+		httpReq.Header.Set(HeaderIdentityAssertion, assertion)
+	}
+
+	resp, err := tracedClient.Do(httpReq)
 	if err != nil || (resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK) {
 		if err != nil {
-			c.Logger().Errorf("Error registering user with user-service: %v", err)
+			slog.ErrorContext(c.Request().Context(), "failed to register user with user-service", "error", err)
 		} else {
-			c.Logger().Errorf("User-service returned non-successful status: %d", resp.StatusCode)
+			slog.ErrorContext(c.Request().Context(), "user-service registration returned unexpected status", "status", resp.StatusCode)
 		}
 
 		// Attempt to delete the user from Keycloak if the user-service registration fails
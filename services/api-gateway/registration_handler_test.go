@@ -2,6 +2,9 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,13 +13,19 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegistrationHandler_Handle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
 	// mock us
+	var assertion string
 	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/register", r.URL.Path)
 		assert.Equal(t, http.MethodPost, r.Method)
+		assertion = r.Header.Get(HeaderIdentityAssertion)
 		w.WriteHeader(http.StatusCreated)
 	}))
 	defer userService.Close()
@@ -58,6 +67,7 @@ func TestRegistrationHandler_Handle(t *testing.T) {
 	handler := &RegistrationHandler{
 		Keycloak:    kcClient,
 		UserService: userService.URL,
+		Signer:      &AssertionSigner{Key: priv},
 	}
 
 	// send rq
@@ -68,10 +78,21 @@ func TestRegistrationHandler_Handle(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	err := handler.Handle(c)
+	err = handler.Handle(c)
 
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// user-service is told which ID the gateway created the user under
+	parts := strings.Split(assertion, ".")
+	require.Len(t, parts, 3)
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.True(t, ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), sig))
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims AssertionClaims
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "new-user-id-123", claims.Subject)
+	assert.Equal(t, "john", claims.Name)
 }
 
 func TestRegistrationHandler_InvalidBody(t *testing.T) {
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Reloader serves requests with the router built from the config file at Path, and swaps in a new
+// router when the file changes (e.g. when Kubernetes updates the mounted ConfigMap). A new config
+// only takes effect once it parsed and validated; until then the old one keeps serving.
+type Reloader struct {
+	Path      string
+	Build     func(*Config) http.Handler
+	Upstreams *Upstreams // Optional; Status reports the targets' health from it
+
+	active atomic.Pointer[activeConfig]
+
+	mu         sync.Mutex // Serializes reloads
+	failedHash string     // Content that failed last time, not retried until the file changes again
+	lastError  string
+	lastFailed time.Time
+}
+
+type activeConfig struct {
+	config   *Config
+	version  string
+	loadedAt time.Time
+	handler  http.Handler
+}
+
+// configVersion identifies a config file by its contents.
+func configVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// Load reads the config file and, if it changed and is valid, makes it active.
+// It reports whether a new config was activated.
+func (r *Reloader) Load() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.Path)
+	if err != nil {
+		return false, err
+	}
+	version := configVersion(data)
+	if current := r.active.Load(); current != nil && current.version == version {
+		return false, nil
+	}
+	if version == r.failedHash {
+		return false, nil
+	}
+
+	config, err := parseConfig(data)
+	if err != nil {
+		r.failedHash, r.lastError, r.lastFailed = version, err.Error(), time.Now()
+		return false, err
+	}
+	r.active.Store(&activeConfig{config: config, version: version, loadedAt: time.Now(), handler: r.Build(config)})
+	r.failedHash, r.lastError = "", ""
+	return true, nil
+}
+
+// Watch checks the config file every interval until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.reload()
+	}
+}
+
+// reload is one check of Watch: Load, logging what changed.
+func (r *Reloader) reload() {
+	changed, err := r.Load()
+	switch {
+	case err != nil:
+		slog.Warn("config rejected, keeping the active version", "version", r.Version(), "path", r.Path, "error", err)
+	case changed:
+		slog.Info("config reloaded", "version", r.Version())
+	}
+}
+
+// Version of the active config, empty before the first Load.
+func (r *Reloader) Version() string {
+	if current := r.active.Load(); current != nil {
+		return current.version
+	}
+	return ""
+}
+
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	current := r.active.Load()
+	if current == nil {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	current.handler.ServeHTTP(w, req)
+}
+
+type configService struct {
+	Name    string         `json:"name"`
+	Path    string         `json:"path"`
+	Targets []TargetStatus `json:"targets"`
+}
+
+// Status shows which config is active, the health of every service's targets and, if the file
+// holds a newer config that was rejected, why.
+func (r *Reloader) Status(c echo.Context) error {
+	current := r.active.Load()
+	if current == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "No config loaded")
+	}
+
+	services := make([]configService, 0, len(current.config.Services))
+	for _, s := range current.config.Services {
+		services = append(services, configService{Name: s.Name, Path: s.Proxy.Path, Targets: r.Upstreams.status(s)})
+	}
+	status := map[string]interface{}{
+		"version":  current.version,
+		"loadedAt": current.loadedAt,
+		"path":     r.Path,
+		"services": services,
+	}
+
+	r.mu.Lock()
+	if r.lastError != "" {
+		status["rejected"] = map[string]interface{}{"version": r.failedHash, "at": r.lastFailed, "error": r.lastError}
+	}
+	r.mu.Unlock()
+
+	return c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, path, userServiceURL string) {
+	content := `
+services:
+  - name: "user-service"
+    url: "` + userServiceURL + `"
+    proxy:
+      path: "/api/users"
+      rewrite: "/users"
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// newTestReloader builds a router that answers with the user-service URL of its config.
+func newTestReloader(t *testing.T) (*Reloader, string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "http://users-v1")
+
+	r := &Reloader{Path: path}
+	r.Build = func(config *Config) http.Handler {
+		e := echo.New()
+		e.GET("/upstream", func(c echo.Context) error {
+			return c.String(http.StatusOK, config.UserServiceURL())
+		})
+		e.GET("/admin/config", r.Status)
+		return e
+	}
+	return r, path
+}
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestReloader_SwapsConfig(t *testing.T) {
+	r, path := newTestReloader(t)
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(r, "/upstream").Code)
+
+	changed, err := r.Load()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "http://users-v1", get(r, "/upstream").Body.String())
+	v1 := r.Version()
+
+	// Unchanged file, nothing to do
+	changed, err = r.Load()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	writeConfig(t, path, "http://users-v2")
+	changed, err = r.Load()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "http://users-v2", get(r, "/upstream").Body.String())
+	assert.NotEqual(t, v1, r.Version())
+}
+
+func TestReloader_KeepsOldConfigOnError(t *testing.T) {
+	r, path := newTestReloader(t)
+	_, err := r.Load()
+	require.NoError(t, err)
+	version := r.Version()
+
+	// No user-service entry
+	require.NoError(t, os.WriteFile(path, []byte("services: []\n"), 0o644))
+	changed, err := r.Load()
+	assert.Error(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, version, r.Version())
+	assert.Equal(t, "http://users-v1", get(r, "/upstream").Body.String())
+
+	// The same broken file is not reported again
+	_, err = r.Load()
+	assert.NoError(t, err)
+
+	var status map[string]interface{}
+	rec := get(r, "/admin/config")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, version, status["version"])
+	rejected, ok := status["rejected"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, rejected["error"], "user-service")
+
+	// Fixing the file clears the error
+	writeConfig(t, path, "http://users-v3")
+	changed, err = r.Load()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	status = nil
+	require.NoError(t, json.Unmarshal(get(r, "/admin/config").Body.Bytes(), &status))
+	assert.Equal(t, r.Version(), status["version"])
+	assert.Nil(t, status["rejected"])
+	targets := status["services"].([]interface{})[0].(map[string]interface{})["targets"].([]interface{})
+	assert.Equal(t, "http://users-v3", targets[0].(map[string]interface{})["url"])
+}
+
+func TestReloader_LogsReloadOutcome(t *testing.T) {
+	logs := captureLogs(t)
+	r, path := newTestReloader(t)
+	_, err := r.Load()
+	require.NoError(t, err)
+	v1 := r.Version()
+
+	require.NoError(t, os.WriteFile(path, []byte("services: []\n"), 0o644))
+	r.reload()
+	writeConfig(t, path, "http://users-v2")
+	r.reload()
+
+	records := logRecords(t, logs)
+	require.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, v1, records[0]["version"], "a rejected config names the version still served")
+	assert.Contains(t, records[0]["error"], "user-service")
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, r.Version(), records[1]["version"])
+}
+
+func TestReloader_StatusListsTargetHealth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+services:
+  - name: "user-service"
+    urls: ["http://users-a", "http://users-b"]
+    proxy:
+      path: "/api/users"
+      rewrite: "/users"
+    upstream:
+      healthCheck:
+        interval: -1s
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	upstreams := NewUpstreams()
+	r := &Reloader{Path: path, Upstreams: upstreams}
+	r.Build = func(config *Config) http.Handler {
+		e := echo.New()
+		for _, s := range config.Services {
+			_, err := upstreams.pool(s)
+			require.NoError(t, err)
+		}
+		e.GET("/admin/config", r.Status)
+		return e
+	}
+	_, err := r.Load()
+	require.NoError(t, err)
+
+	// users-b failed its health check and was ejected
+	pool := upstreams.pools["user-service"]
+	pool.targets[1].healthy = false
+	pool.targets[1].ejectedUntil = time.Now().Add(time.Minute)
+
+	var status struct {
+		Services []configService `json:"services"`
+	}
+	require.NoError(t, json.Unmarshal(get(r, "/admin/config").Body.Bytes(), &status))
+	require.Len(t, status.Services, 1)
+	targets := status.Services[0].Targets
+	require.Len(t, targets, 2)
+	assert.Equal(t, "http://users-a", targets[0].URL)
+	assert.True(t, *targets[0].Healthy)
+	assert.False(t, targets[0].Ejected)
+	assert.Equal(t, "http://users-b", targets[1].URL)
+	assert.False(t, *targets[1].Healthy)
+	assert.True(t, targets[1].Ejected)
+	assert.NotNil(t, targets[1].EjectedUntil)
+}
@@ -2,23 +2,35 @@ package main
 
 import (
 	"net/http"
-	"net/url"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func NewRouter(config *Config, provider *oidc.Provider, registrationHandler *RegistrationHandler, deletionHandler *DeletionHandler, userServiceURL string) *echo.Echo {
+func NewRouter(config *Config, provider *oidc.Provider, signer *AssertionSigner, limits RateLimitStore, upstreams *Upstreams, registrationHandler *RegistrationHandler, deletionHandler *DeletionHandler, userServiceURL string) *echo.Echo {
 	e := echo.New()
-	e.Use(middleware.Logger())
+	// Client IP for anonymous rate limits: X-Forwarded-For is only trusted from the ingress (private ranges)
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	if limits == nil {
+		limits = NewMemoryStore()
+	}
+	if upstreams == nil {
+		upstreams = NewUpstreams()
+	}
+	// Runs after the auth middleware, so callers are counted by their verified user ID
+	rateLimit := RateLimitMiddleware(config.RateLimit, limits)
+	// Identity headers are only trusted when the gateway set them
+	e.Pre(StripIdentityHeaders())
+	e.Use(TracingMiddleware())
+	e.Use(RequestIDMiddleware())
+	e.Use(RequestLogMiddleware())
 	e.Use(middleware.Recover())
 
-	// CORS Middleware
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"https://t-hub.ltu-m7011e-4.se"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-	}))
+	e.Use(SecurityHeaders(config.Security))
+	// CORS Middleware, with the extra origins of the environment we run in (e.g. dev, staging)
+	e.Use(CORSMiddleware(config.CORS, getEnv("GATEWAY_ENV", "")))
+	e.Use(BodyLimitMiddleware(config.BodyLimit))
 
 	// Health Check
 	e.GET("/health", func(c echo.Context) error {
@@ -26,34 +38,48 @@ func NewRouter(config *Config, provider *oidc.Provider, registrationHandler *Reg
 	})
 
 	// Registration endpoint (public)
-	e.POST("/api/register", registrationHandler.Handle)
+	e.POST("/api/register", registrationHandler.Handle, rateLimit)
 	// User Deletion Endpoint (Authenticated)
 	// We create a group for protected user routes
 	userGroup := e.Group("/api/users")
-	userGroup.Use(AuthMiddleware(provider, userServiceURL))
+	userGroup.Use(AuthMiddleware(provider, userServiceURL, signer), rateLimit)
 	userGroup.DELETE("/me", deletionHandler.Handle)
 
 	for _, service := range config.Services {
-		target, err := url.Parse(service.URL)
+		pool, err := upstreams.pool(service)
 		if err != nil {
 			e.Logger.Fatal("Invalid service URL: ", err)
 		}
 
 		apiGroup := e.Group(service.Proxy.Path)
-		apiGroup.Use(AuthMiddleware(provider, userServiceURL))
-
-		proxyConfig := middleware.ProxyConfig{
-			Balancer: middleware.NewRoundRobinBalancer([]*middleware.ProxyTarget{
-				{URL: target},
-			}),
-			Rewrite: map[string]string{
-				service.Proxy.Path + "/*": service.Proxy.Rewrite + "/$1",
-				service.Proxy.Path:       service.Proxy.Rewrite,
-			},
-		}
-
-		apiGroup.Use(middleware.ProxyWithConfig(proxyConfig))
+		apiGroup.Use(RouteAuthMiddleware(service.Proxy, provider, signer), rateLimit)
+		apiGroup.Use(serviceProxy(service.Proxy, pool))
 	}
+	upstreams.retain(config.Services)
 
 	return e
 }
+
+// serviceProxy forwards requests to the service's pool of targets, rewriting the gateway path.
+func serviceProxy(proxy Proxy, pool *upstreamPool) echo.MiddlewareFunc {
+	proxyConfig := middleware.ProxyConfig{
+		// The pool picks healthy targets and, as the transport, learns which ones fail
+		Balancer:    pool,
+		Transport:   pool,
+		RetryCount:  pool.config.Retries,
+		RetryFilter: retryIdempotent,
+		// The gateway already answers with the request's ID
+		ModifyResponse: func(res *http.Response) error {
+			res.Header.Del(echo.HeaderXRequestID)
+			return nil
+		},
+		Rewrite: map[string]string{
+			proxy.Path + "/*": proxy.Rewrite + "/$1",
+			proxy.Path:       proxy.Rewrite,
+		},
+	}
+
+	// WebSocket upgrades are tunnelled as raw connections and text/event-stream responses are
+	// flushed as they arrive, so live streams (e.g. /api/brackets/:id/stream) pass through as is.
+	return middleware.ProxyWithConfig(proxyConfig)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/bytes"
+)
+
+// matchOrigin reports whether a request's Origin is allowed by pattern (see CORSConfig).
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+	pScheme, pHost, ok := strings.Cut(strings.ToLower(pattern), "://")
+	oScheme, oHost, ok2 := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || !ok2 || pScheme != oScheme {
+		return false
+	}
+	if domain, ok := strings.CutPrefix(pHost, "*."); ok {
+		sub, ok := strings.CutSuffix(oHost, "."+domain)
+		return ok && validHostname(sub)
+	}
+	return pHost == oHost
+}
+
+// validHostname reports whether s is made of DNS labels only (no port, path or user info).
+func validHostname(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validOriginPattern(pattern string) bool {
+	u, err := url.Parse(strings.Replace(pattern, "*.", "wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.User == nil && u.RawQuery == "" && !strings.Contains(u.Host, "*")
+}
+
+// CORSMiddleware answers preflights and sets the CORS headers for the origins allowed in env.
+func CORSMiddleware(config CORSConfig, env string) echo.MiddlewareFunc {
+	origins := config.origins(env)
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	}
+	headers := config.AllowHeaders
+	if len(headers) == 0 {
+		headers = []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization}
+	}
+
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			for _, pattern := range origins {
+				if matchOrigin(pattern, origin) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		AllowMethods:     methods,
+		AllowHeaders:     headers,
+		ExposeHeaders:    config.ExposeHeaders,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           int(config.MaxAge.Seconds()),
+	})
+}
+
+// SecurityHeaders adds the configured security headers to every response.
+func SecurityHeaders(config SecurityConfig) echo.MiddlewareFunc {
+	return middleware.SecureWithConfig(middleware.SecureConfig{
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         strings.ToUpper(config.FrameOptions),
+		HSTSMaxAge:            int(config.HSTSMaxAge.Seconds()),
+		HSTSPreloadEnabled:    config.HSTSPreload,
+		ContentSecurityPolicy: config.ContentSecurityPolicy,
+		ReferrerPolicy:        config.ReferrerPolicy,
+	})
+}
+
+func parseBodyLimit(limit string) (int64, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	return bytes.Parse(limit)
+}
+
+// BodyLimitMiddleware rejects request bodies over the limit of their route with 413.
+func BodyLimitMiddleware(config BodyLimitConfig) echo.MiddlewareFunc {
+	// One limiter per distinct limit; limits were validated with the config
+	limiters := map[string]echo.MiddlewareFunc{}
+	for _, limit := range append([]string{config.Default}, bodyLimits(config.Routes)...) {
+		if limit != "" && limiters[limit] == nil {
+			limiters[limit] = middleware.BodyLimit(limit)
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		limited := map[string]echo.HandlerFunc{}
+		for limit, mw := range limiters {
+			limited[limit] = mw(next)
+		}
+		return func(c echo.Context) error {
+			if h, ok := limited[config.limitFor(c.Request().Method, c.Request().URL.Path)]; ok {
+				return h(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+func bodyLimits(routes []BodyLimitRoute) []string {
+	limits := make([]string, 0, len(routes))
+	for _, r := range routes {
+		limits = append(limits, r.Limit)
+	}
+	return limits
+}
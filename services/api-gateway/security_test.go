@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern, origin string
+		want            bool
+	}{
+		{"https://t-hub.example.com", "https://t-hub.example.com", true},
+		{"https://t-hub.example.com", "https://T-Hub.example.com", true},
+		{"https://t-hub.example.com", "http://t-hub.example.com", false},
+		{"https://t-hub.example.com", "https://t-hub.example.com:8443", false},
+		{"http://localhost:5173", "http://localhost:5173", true},
+		{"http://localhost:5173", "http://localhost:5174", false},
+		{"https://*.example.com", "https://pr-12.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"https://*.example.com", "https://evil.com/.example.com", false},
+		{"https://*.example.com", "https://evil.com:1@x.example.com", false},
+		{"https://*.example.com", "https://a.example.com:8443", false},
+		{"https://*.example.com", "http://a.example.com", false},
+		{"*", "https://anything.test", true},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, matchOrigin(tc.pattern, tc.origin), "%s vs %s", tc.pattern, tc.origin)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	config := CORSConfig{
+		AllowOrigins: []string{"https://t-hub.example.com"},
+		Environments: map[string][]string{"dev": {"http://localhost:5173"}},
+		MaxAge:       10 * time.Minute,
+	}
+
+	preflight := func(env, origin string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.Use(CORSMiddleware(config, env))
+		e.PATCH("/api/tournaments/1", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodOptions, "/api/tournaments/1", nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPatch)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("", "https://t-hub.example.com")
+	assert.Equal(t, "https://t-hub.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	for _, m := range []string{"PUT", "PATCH", "DELETE"} {
+		assert.Contains(t, rec.Header().Get(echo.HeaderAccessControlAllowMethods), m)
+	}
+	assert.Equal(t, "600", rec.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	// Dev origins only in dev
+	assert.Empty(t, preflight("", "http://localhost:5173").Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "http://localhost:5173", preflight("dev", "http://localhost:5173").Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	assert.Empty(t, preflight("dev", "https://evil.test").Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	e := echo.New()
+	e.Use(SecurityHeaders(SecurityConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		ContentSecurityPolicy: "default-src 'none'",
+		FrameOptions:          "deny",
+		ReferrerPolicy:        "no-referrer",
+	}))
+	e.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	h := rec.Header()
+	assert.Equal(t, "max-age=31536000; includeSubdomains", h.Get(echo.HeaderStrictTransportSecurity))
+	assert.Equal(t, "default-src 'none'", h.Get(echo.HeaderContentSecurityPolicy))
+	assert.Equal(t, "DENY", h.Get(echo.HeaderXFrameOptions))
+	assert.Equal(t, "nosniff", h.Get(echo.HeaderXContentTypeOptions))
+	assert.Equal(t, "no-referrer", h.Get(echo.HeaderReferrerPolicy))
+
+	// No HSTS over plain HTTP
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, rec.Header().Get(echo.HeaderStrictTransportSecurity))
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimitMiddleware(BodyLimitConfig{
+		Default: "1K",
+		Routes:  []BodyLimitRoute{{Methods: []string{"PUT"}, Path: "/api/users/me/avatar", Limit: "4K"}},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/api/teams", ok)
+	e.PUT("/api/users/me/avatar", ok)
+
+	send := func(method, path string, size int) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(strings.Repeat("a", size))))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/teams", 1000))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(http.MethodPost, "/api/teams", 1001))
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/api/users/me/avatar", 3000))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(http.MethodPut, "/api/users/me/avatar", 5000))
+}
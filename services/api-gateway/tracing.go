@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedClient is used for the gateway's own calls to services, so they continue the request's trace.
+var tracedClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// InitTracing installs the global tracer provider and W3C trace context propagation. Spans are
+// exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)
+// is set; everything else about the exporter comes from the standard OTEL_* variables.
+// The returned function flushes the remaining spans.
+func InitTracing(ctx context.Context, service string) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	)
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	installTracing(tp)
+	return tp.Shutdown, nil
+}
+
+func installTracing(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// TracingMiddleware starts a span for every request. The gateway is where traces begin: a
+// traceparent sent by a client is ignored, and the proxy injects the gateway's own span instead.
+func TracingMiddleware() echo.MiddlewareFunc {
+	return otelecho.Middleware(serviceName,
+		otelecho.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelecho.WithSkipper(func(c echo.Context) bool { return c.Path() == "/health" }),
+	)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracing records spans in memory for the duration of the test.
+func newTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	installTracing(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestTracing_ProxyInjectsTraceparent(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	e, _ := newTestProxy(t, UpstreamConfig{}, upstream.URL)
+	e.Use(TracingMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/s/x", nil)
+	// Clients cannot pick the trace their request joins
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	client, server := spans[0], spans[1]
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "GET /api/s/*", server.Name)
+	assert.False(t, server.Parent.IsValid(), "the gateway starts the trace")
+	assert.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+
+	// The service continues the gateway's trace from the proxied call's span
+	assert.Equal(t, "00-"+client.SpanContext.TraceID().String()+"-"+client.SpanContext.SpanID().String()+"-01", traceparent)
+	assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", server.SpanContext.TraceID().String())
+}
+
+func TestTracing_SkipsHealth(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	e := echo.New()
+	e.Use(TracingMiddleware())
+	e.GET("/health", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Empty(t, exporter.GetSpans())
+}
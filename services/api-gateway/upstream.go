@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// maxEjection caps how long a repeatedly failing target stays out of rotation.
+const maxEjection = 5 * time.Minute
+
+// Upstreams keeps a pool of targets per proxied service. A pool survives config reloads while the
+// service's targets and settings stay the same, so what it learned about their health is kept.
+type Upstreams struct {
+	mu    sync.Mutex
+	pools map[string]*upstreamPool
+}
+
+func NewUpstreams() *Upstreams {
+	return &Upstreams{pools: map[string]*upstreamPool{}}
+}
+
+// pool returns the pool for a service, replacing the current one if the service changed.
+func (u *Upstreams) pool(s Service) (*upstreamPool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	config := s.Upstream.withDefaults()
+	key := poolKey(s, config)
+	if p, ok := u.pools[s.Name]; ok {
+		if p.key == key {
+			return p, nil
+		}
+		p.close()
+	}
+
+	p, err := newUpstreamPool(s.Name, s.Targets(), config)
+	if err != nil {
+		return nil, err
+	}
+	p.key = key
+	u.pools[s.Name] = p
+	if config.HealthCheck.Interval > 0 {
+		go p.healthCheckLoop()
+	}
+	return p, nil
+}
+
+// poolKey identifies a service's targets and settings; a pool is rebuilt when it changes.
+func poolKey(s Service, config UpstreamConfig) string {
+	return fmt.Sprintf("%v %+v", s.Targets(), config)
+}
+
+// TargetStatus is what a pool currently knows about one of its targets.
+type TargetStatus struct {
+	URL          string     `json:"url"`
+	Healthy      *bool      `json:"healthy,omitempty"` // Unknown while no pool serves the service
+	Ejected      bool       `json:"ejected"`
+	EjectedUntil *time.Time `json:"ejectedUntil,omitempty"`
+	Failures     int        `json:"failures"` // Consecutive failed requests
+}
+
+// status lists the targets of a service, with their health and ejection state when a pool
+// serves exactly these targets.
+func (u *Upstreams) status(s Service) []TargetStatus {
+	var p *upstreamPool
+	if u != nil {
+		u.mu.Lock()
+		if candidate, ok := u.pools[s.Name]; ok && candidate.key == poolKey(s, s.Upstream.withDefaults()) {
+			p = candidate
+		}
+		u.mu.Unlock()
+	}
+	if p != nil {
+		return p.status()
+	}
+
+	targets := make([]TargetStatus, 0, len(s.Targets()))
+	for _, target := range s.Targets() {
+		targets = append(targets, TargetStatus{URL: target})
+	}
+	return targets
+}
+
+// retain closes the pools of services that are no longer configured.
+func (u *Upstreams) retain(services []Service) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	keep := map[string]bool{}
+	for _, s := range services {
+		keep[s.Name] = true
+	}
+	for name, p := range u.pools {
+		if !keep[name] {
+			p.close()
+			delete(u.pools, name)
+		}
+	}
+}
+
+// upstreamPool balances a service's requests over its available targets, round robin.
+// A target is unavailable while its health check fails or while it is ejected: after MaxFailures
+// consecutive failed requests its circuit opens for EjectFor (doubling on every repeat). Once that
+// time is up a single request tries it again, and its outcome closes or reopens the circuit.
+// With no target available, requests fail right away with 503.
+//
+// The pool is both the proxy's balancer and its transport, which is how it sees every outcome.
+type upstreamPool struct {
+	service   string
+	config    UpstreamConfig
+	key       string
+	targets   []*upstreamTarget
+	transport http.RoundTripper // Traced, injects the request's trace context
+	health    http.RoundTripper
+	now       func() time.Time // For tests
+
+	mu   sync.Mutex
+	next int
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type upstreamTarget struct {
+	proxy        *middleware.ProxyTarget
+	healthy      bool // Last health check passed
+	failures     int  // Consecutive failed requests
+	ejections    int  // Consecutive ejections; non-zero until a request succeeds again
+	ejectedUntil time.Time
+	probeUntil   time.Time // A request is trying the target after an ejection; expires in case it never reports back (e.g. WebSockets)
+}
+
+func newUpstreamPool(service string, targets []string, config UpstreamConfig) (*upstreamPool, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Only waiting for the headers is limited, event streams may stay open for as long as they like
+	transport.ResponseHeaderTimeout = config.Timeout
+
+	p := &upstreamPool{service: service, config: config, transport: otelhttp.NewTransport(transport), health: transport, now: time.Now, stop: make(chan struct{})}
+	for _, raw := range targets {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: invalid url %q: %w", service, raw, err)
+		}
+		p.targets = append(p.targets, &upstreamTarget{proxy: &middleware.ProxyTarget{Name: service, URL: u}, healthy: true})
+	}
+	return p, nil
+}
+
+func (p *upstreamPool) close() {
+	p.closeOnce.Do(func() { close(p.stop) })
+}
+
+func (t *upstreamTarget) available(now time.Time) bool {
+	return t.healthy && !now.Before(t.ejectedUntil) && !now.Before(t.probeUntil)
+}
+
+func (p *upstreamPool) status() []TargetStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	targets := make([]TargetStatus, 0, len(p.targets))
+	for _, t := range p.targets {
+		healthy := t.healthy
+		status := TargetStatus{URL: t.proxy.URL.String(), Healthy: &healthy, Failures: t.failures}
+		if now.Before(t.ejectedUntil) {
+			until := t.ejectedUntil
+			status.Ejected, status.EjectedUntil = true, &until
+		}
+		targets = append(targets, status)
+	}
+	return targets
+}
+
+// NextTarget picks the next available target, or fails with 503 when there is none.
+func (p *upstreamPool) NextTarget(c echo.Context) (*middleware.ProxyTarget, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	n := len(p.targets)
+	for i := 0; i < n; i++ {
+		t := p.targets[(p.next+i)%n]
+		if !t.available(now) {
+			continue
+		}
+		p.next = (p.next + i + 1) % n
+		if t.ejections > 0 {
+			t.probeUntil = now.Add(p.config.Timeout)
+		}
+		return t.proxy, nil
+	}
+
+	// Tell clients when it is worth trying again: the next circuit to close, or the next health check
+	retryAfter := p.config.HealthCheck.Interval
+	for _, t := range p.targets {
+		if wait := t.ejectedUntil.Sub(now); t.healthy && (retryAfter <= 0 || wait < retryAfter) {
+			retryAfter = wait
+		}
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return nil, echo.NewHTTPError(http.StatusServiceUnavailable, p.service+" is unavailable")
+}
+
+// Next, AddTarget and RemoveTarget complete middleware.ProxyBalancer; the proxy uses NextTarget.
+func (p *upstreamPool) Next(c echo.Context) *middleware.ProxyTarget {
+	t, _ := p.NextTarget(c)
+	return t
+}
+
+func (p *upstreamPool) AddTarget(*middleware.ProxyTarget) bool { return false }
+
+func (p *upstreamPool) RemoveTarget(string) bool { return false }
+
+// RoundTrip forwards a request and records whether its target handled it.
+func (p *upstreamPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := p.transport.RoundTrip(req)
+	// A client that went away or sent too large a body says nothing about the target
+	failed := req.Context().Err() == nil && !errors.Is(err, echo.ErrStatusRequestEntityTooLarge) &&
+		(err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	p.record(req.URL.Host, failed)
+	return resp, err
+}
+
+func (p *upstreamPool) record(host string, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var t *upstreamTarget
+	for _, candidate := range p.targets {
+		if candidate.proxy.URL.Host == host {
+			t = candidate
+			break
+		}
+	}
+	if t == nil {
+		return
+	}
+	wasProbing := !t.probeUntil.IsZero()
+	t.probeUntil = time.Time{}
+
+	if !failed {
+		if t.ejections > 0 {
+			slog.Info("upstream back in rotation", "service", p.service, "target", t.proxy.URL.String())
+		}
+		t.failures, t.ejections = 0, 0
+		return
+	}
+	t.failures++
+	if wasProbing || t.failures >= p.config.MaxFailures {
+		t.ejections++
+		ejectFor := p.config.EjectFor << (t.ejections - 1)
+		if ejectFor > maxEjection || ejectFor <= 0 {
+			ejectFor = maxEjection
+		}
+		t.ejectedUntil = p.now().Add(ejectFor)
+		t.failures = 0
+		slog.Warn("upstream ejected after failed requests", "service", p.service, "target", t.proxy.URL.String(), "eject_for", ejectFor)
+	}
+}
+
+func (p *upstreamPool) healthCheckLoop() {
+	client := &http.Client{Timeout: p.config.HealthCheck.Timeout, Transport: p.health}
+	ticker := time.NewTicker(p.config.HealthCheck.Interval)
+	defer ticker.Stop()
+	for {
+		p.checkHealth(client)
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth calls every target's health endpoint; anything but a 2xx takes it out of rotation.
+func (p *upstreamPool) checkHealth(client *http.Client) {
+	for _, t := range p.targets {
+		healthy := false
+		resp, err := client.Get(t.proxy.URL.JoinPath(p.config.HealthCheck.Path).String())
+		if err == nil {
+			resp.Body.Close()
+			healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+		}
+
+		p.mu.Lock()
+		if t.healthy != healthy {
+			if healthy {
+				slog.Info("upstream health check passing", "service", p.service, "target", t.proxy.URL.String())
+			} else {
+				slog.Warn("upstream health check failing", "service", p.service, "target", t.proxy.URL.String())
+			}
+		}
+		t.healthy = healthy
+		p.mu.Unlock()
+	}
+}
+
+// retryIdempotent retries requests that could not reach their target on another one, as long as
+// repeating them is safe and there is no request body that was already read.
+func retryIdempotent(c echo.Context, err error) bool {
+	req := c.Request()
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.ContentLength != 0 || len(req.TransferEncoding) > 0 {
+		return false
+	}
+	httpErr, ok := err.(*echo.HTTPError)
+	return ok && httpErr.Code == http.StatusBadGateway
+}
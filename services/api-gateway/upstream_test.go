@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backend answers with its name, or with status when it is set.
+type backend struct {
+	*httptest.Server
+	name   string
+	status atomic.Int32
+	hits   atomic.Int32
+}
+
+func newBackend(t *testing.T, name string) *backend {
+	b := &backend{name: name}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.hits.Add(1)
+		if status := b.status.Load(); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+		fmt.Fprint(w, b.name)
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// newTestProxy proxies /api/s to the given targets without active health checks.
+func newTestProxy(t *testing.T, config UpstreamConfig, targets ...string) (*echo.Echo, *upstreamPool) {
+	config.HealthCheck.Interval = -1
+	pool, err := NewUpstreams().pool(Service{Name: "s", URLs: targets, Upstream: config})
+	require.NoError(t, err)
+
+	e := echo.New()
+	g := e.Group("/api/s")
+	g.Use(serviceProxy(Proxy{Path: "/api/s", Rewrite: "/s"}, pool))
+	return e, pool
+}
+
+func send(e *echo.Echo, method string, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, "/api/s/x", nil)
+	} else {
+		req = httptest.NewRequest(method, "/api/s/x", strings.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpstream_RoundRobin(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	e, _ := newTestProxy(t, UpstreamConfig{}, a.URL, b.URL)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		rec := send(e, http.MethodGet, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		got = append(got, rec.Body.String())
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, got)
+}
+
+func TestUpstream_EjectsFailingTarget(t *testing.T) {
+	logs := captureLogs(t)
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	a.status.Store(http.StatusInternalServerError)
+	e, pool := newTestProxy(t, UpstreamConfig{MaxFailures: 2, EjectFor: time.Minute}, a.URL, b.URL)
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		send(e, http.MethodGet, "")
+	}
+	assert.Equal(t, int32(2), a.hits.Load())
+
+	// Only b gets traffic now
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", send(e, http.MethodGet, "").Body.String())
+	}
+	assert.Equal(t, int32(2), a.hits.Load())
+
+	// After the ejection one request tries a again; it recovered, so it is back in rotation
+	a.status.Store(0)
+	now = now.Add(time.Minute)
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, send(e, http.MethodGet, "").Body.String())
+	}
+	assert.ElementsMatch(t, []string{"a", "a", "b", "b"}, got)
+
+	var levels []string
+	for _, r := range logRecords(t, logs) {
+		if r["service"] == "s" && r["target"] == a.URL {
+			levels = append(levels, r["level"].(string)+" "+r["msg"].(string))
+		}
+	}
+	assert.Equal(t, []string{"WARN upstream ejected after failed requests", "INFO upstream back in rotation"}, levels)
+}
+
+func TestUpstream_CircuitOpenFailsFast(t *testing.T) {
+	a := newBackend(t, "a")
+	a.status.Store(http.StatusBadGateway)
+	e, pool := newTestProxy(t, UpstreamConfig{MaxFailures: 1, EjectFor: 10 * time.Second, Retries: -1}, a.URL)
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	assert.Equal(t, http.StatusBadGateway, send(e, http.MethodGet, "").Code)
+
+	rec := send(e, http.MethodGet, "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"message":"s is unavailable"}`, rec.Body.String())
+	assert.Equal(t, int32(1), a.hits.Load())
+
+	// The trial after the ejection fails too, so the circuit opens again for twice as long
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, http.StatusBadGateway, send(e, http.MethodGet, "").Code)
+	rec = send(e, http.MethodGet, "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "20", rec.Header().Get("Retry-After"))
+}
+
+func TestUpstream_RetriesIdempotentRequests(t *testing.T) {
+	down := newBackend(t, "down")
+	down.Close()
+	up := newBackend(t, "up")
+	e, _ := newTestProxy(t, UpstreamConfig{}, down.URL, up.URL)
+
+	rec := send(e, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "up", rec.Body.String())
+
+	// A POST is not repeated; the next one goes to the other target
+	assert.Equal(t, http.StatusBadGateway, send(e, http.MethodPost, `{"a":1}`).Code)
+	assert.Equal(t, http.StatusOK, send(e, http.MethodPost, `{"a":1}`).Code)
+}
+
+func TestUpstream_HealthCheck(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	e, pool := newTestProxy(t, UpstreamConfig{}, a.URL, b.URL)
+
+	a.status.Store(http.StatusServiceUnavailable)
+	pool.checkHealth(http.DefaultClient)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", send(e, http.MethodGet, "").Body.String())
+	}
+
+	b.status.Store(http.StatusServiceUnavailable)
+	pool.checkHealth(http.DefaultClient)
+	assert.Equal(t, http.StatusServiceUnavailable, send(e, http.MethodGet, "").Code)
+
+	a.status.Store(0)
+	pool.checkHealth(http.DefaultClient)
+	assert.Equal(t, "a", send(e, http.MethodGet, "").Body.String())
+}
+
+func TestUpstreams_KeepsPoolAcrossReloads(t *testing.T) {
+	upstreams := NewUpstreams()
+	service := Service{Name: "s", URL: "http://s", Upstream: UpstreamConfig{HealthCheck: HealthCheckConfig{Interval: -1}}}
+
+	p1, err := upstreams.pool(service)
+	require.NoError(t, err)
+	p2, _ := upstreams.pool(service)
+	assert.Same(t, p1, p2)
+
+	service.URL = "http://s2"
+	p3, _ := upstreams.pool(service)
+	assert.NotSame(t, p1, p3)
+
+	upstreams.retain(nil)
+	assert.Empty(t, upstreams.pools)
+}
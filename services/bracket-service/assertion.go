@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderIdentityAssertion carries the short-lived token api-gateway signs for every authenticated request.
+const HeaderIdentityAssertion = "X-Identity-Assertion"
+
+// assertionKey holds the caller's raw assertion in the request context, to pass on to tournament-service.
+type assertionKey struct{}
+
+// assertionFromContext returns the verified assertion of the request ctx belongs to ("" if none).
+func assertionFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(assertionKey{}).(string)
+	return s
+}
+
+// Issuer and audience of identity assertions, and the clock skew we tolerate
+const (
+	assertionIssuer   = "api-gateway"
+	assertionAudience = "t-hub-services"
+	assertionLeeway   = 5 * time.Second
+)
+
+// AssertionClaims is the payload of an identity assertion (a JWT signed with EdDSA).
+type AssertionClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Roles     string `json:"roles"` // Same format as X-User-Roles
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// AssertionVerifier checks identity assertions against the gateway's Ed25519 public keys.
+// The same verifier is in team-service/assertion.go, tournament-service/assertion.go
+// and user-service/assertion.go; keep the copies in step with each other and with the signer
+// in api-gateway/assertion.go.
+type AssertionVerifier struct {
+	Keys []ed25519.PublicKey
+	now  func() time.Time // For tests
+}
+
+// NewAssertionVerifierFromEnv reads the gateway's PKIX PEM public key from GATEWAY_ASSERTION_PUBLIC_KEY.
+// Several PEM blocks may be given while the gateway key is rotated. Returns nil when unset.
+func NewAssertionVerifierFromEnv() (*AssertionVerifier, error) {
+	data := []byte(os.Getenv("GATEWAY_ASSERTION_PUBLIC_KEY"))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	v := &AssertionVerifier{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse gateway public key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("gateway public key must be Ed25519")
+		}
+		v.Keys = append(v.Keys, edKey)
+	}
+	if len(v.Keys) == 0 {
+		return nil, errors.New("GATEWAY_ASSERTION_PUBLIC_KEY holds no PEM public key")
+	}
+	return v, nil
+}
+
+// IdentityVerifierFromEnv is NewAssertionVerifierFromEnv for startup. Without the key anyone who can
+// reach the service may claim any identity in the X-User-* headers, so a missing key is an error
+// unless TRUST_IDENTITY_HEADERS=true opts into that for local development.
+func IdentityVerifierFromEnv() (*AssertionVerifier, error) {
+	v, err := NewAssertionVerifierFromEnv()
+	if err != nil || v != nil {
+		return v, err
+	}
+	if os.Getenv("TRUST_IDENTITY_HEADERS") != "true" {
+		return nil, errors.New("GATEWAY_ASSERTION_PUBLIC_KEY is not set; set TRUST_IDENTITY_HEADERS=true to trust X-User-* headers as sent (local development only)")
+	}
+	slog.Warn("GATEWAY_ASSERTION_PUBLIC_KEY not set, trusting X-User-* headers as sent")
+	return nil, nil
+}
+
+// Verify checks the signature, issuer, audience and lifetime of a raw assertion.
+func (v *AssertionVerifier) Verify(raw string) (*AssertionClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed assertion")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed assertion header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "EdDSA" {
+		return nil, errors.New("unexpected assertion algorithm")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed assertion signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	valid := false
+	for _, k := range v.Keys {
+		if ed25519.Verify(k, signed, sig) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errors.New("invalid assertion signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed assertion payload")
+	}
+	var claims AssertionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed assertion payload")
+	}
+
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	t := now()
+	switch {
+	case claims.Issuer != assertionIssuer || claims.Audience != assertionAudience:
+		return nil, errors.New("assertion not issued by the gateway for us")
+	case claims.Subject == "":
+		return nil, errors.New("assertion has no subject")
+	case t.After(time.Unix(claims.ExpiresAt, 0).Add(assertionLeeway)):
+		return nil, errors.New("assertion expired")
+	case t.Add(assertionLeeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, errors.New("assertion issued in the future")
+	}
+	return &claims, nil
+}
+
+// IdentityMiddleware makes the X-User-* headers trustworthy: they are rebuilt from a verified identity
+// assertion, and dropped when there is none, so callers that bypass the gateway stay anonymous.
+// A present but invalid assertion is rejected. With a nil verifier (no key configured) headers pass as is.
+// The verified assertion is kept in the request context so lookups on behalf of the caller can forward it.
+func IdentityMiddleware(v *AssertionVerifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if v == nil {
+				setLogUser(c.Request().Context(), c.Request().Header.Get("X-User-Id"))
+				return next(c)
+			}
+
+			h := c.Request().Header
+			raw := h.Get(HeaderIdentityAssertion)
+			for name := range h {
+				if strings.HasPrefix(http.CanonicalHeaderKey(name), "X-User-") {
+					delete(h, name)
+				}
+			}
+			if raw == "" {
+				return next(c)
+			}
+
+			claims, err := v.Verify(raw)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid identity assertion"})
+			}
+			h.Set("X-User-Id", claims.Subject)
+			h.Set("X-User-Name", claims.Name)
+			h.Set("X-User-Email", claims.Email)
+			h.Set("X-User-Roles", claims.Roles)
+			setLogUser(c.Request().Context(), claims.Subject)
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), assertionKey{}, raw)))
+			return next(c)
+		}
+	}
+}
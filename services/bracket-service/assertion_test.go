@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mintAssertion signs claims the way api-gateway does.
+func mintAssertion(t *testing.T, key ed25519.PrivateKey, claims AssertionClaims) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+}
+
+func TestIdentityMiddleware_ForwardsAssertionToTournamentService(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+	assertion := mintAssertion(t, priv, AssertionClaims{
+		Issuer: assertionIssuer, Audience: assertionAudience, Subject: "org-1",
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(),
+	})
+
+	var forwarded string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(HeaderIdentityAssertion)
+		json.NewEncoder(w).Encode(map[string]string{"organizer_id": "org-1"})
+	}))
+	defer ts.Close()
+	h := &BracketHandler{TournamentServiceURL: ts.URL}
+
+	e := echo.New()
+	e.Use(IdentityMiddleware(&AssertionVerifier{Keys: []ed25519.PublicKey{pub}}))
+	e.GET("/brackets/:id/manage", func(c echo.Context) error {
+		allowed, err := h.canManageTournament(c, c.Param("id"), false)
+		if err != nil || !allowed {
+			return c.NoContent(http.StatusForbidden)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/brackets/t1/manage", nil)
+	req.Header.Set(HeaderIdentityAssertion, assertion)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, assertion, forwarded)
+
+	// Forged identity without an assertion is dropped before any lookup
+	forwarded = ""
+	req = httptest.NewRequest(http.MethodGet, "/brackets/t1/manage", nil)
+	req.Header.Set("X-User-Id", "org-1")
+	req.Header.Set("X-User-Roles", "SuperAdmin")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, forwarded)
+}
+
+func TestIdentityMiddleware_RejectsForeignAssertion(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	e := echo.New()
+	e.Use(IdentityMiddleware(&AssertionVerifier{Keys: []ed25519.PublicKey{pub}}))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderIdentityAssertion, mintAssertion(t, other, AssertionClaims{
+		Issuer: assertionIssuer, Audience: assertionAudience, Subject: "org-1",
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(),
+	}))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestIdentityVerifierFromEnv_RequiresKeyOutsideDev(t *testing.T) {
+	t.Setenv("GATEWAY_ASSERTION_PUBLIC_KEY", "")
+	t.Setenv("TRUST_IDENTITY_HEADERS", "")
+	_, err := IdentityVerifierFromEnv()
+	assert.Error(t, err, "forged X-User-* headers must not be trusted by default")
+
+	t.Setenv("TRUST_IDENTITY_HEADERS", "true")
+	v, err := IdentityVerifierFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
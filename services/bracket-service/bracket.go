@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -15,12 +18,17 @@ type BracketHandler struct {
 	DB                   DBClient
 	RMQ                  EventPublisher
 	TournamentServiceURL string
+	TeamServiceURL       string
+	Names                *NameCache // Optional; participant names are fetched on every read without it
+	Live                 *LiveHub   // Optional; live streaming is unavailable without it
+	InstanceID           string     // Identifies this replica in BracketUpdated events
 }
 
 // Struct to parse participants from Tournament Service
 type Participant struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"` // approved, disqualified
 }
 
 type Match struct {
@@ -30,13 +38,60 @@ type Match struct {
     MatchNumber  int     `json:"match_number"`
     Player1ID    *string `json:"player1_id"`
     Player2ID    *string `json:"player2_id"`
+    Player1Name  *string `json:"player1_name"` // Resolved from tournament-service; null when unknown
+    Player2Name  *string `json:"player2_name"`
     NextMatchID  *string `json:"next_match_id"`
     Status       string  `json:"status"`
-    ScoreA       *string `json:"score_a"` 
+    ScoreA       *string `json:"score_a"` // null until reported
     ScoreB       *string `json:"score_b"`
     WinnerID     *string `json:"winner_id"`
+    ResultType   string  `json:"result_type"` // normal, bye, forfeit, no_show, double_forfeit
+
+    // Best-of-N series; score_a/score_b hold the games won
+    BestOf int    `json:"best_of"`
+    Games  []Game `json:"games"`
+
+    // Scheduling
+    ScheduledAt *time.Time `json:"scheduled_at"`
+    DeadlineAt  *time.Time `json:"deadline_at"`
+    Venue       *string    `json:"venue"` // Physical venue, stream URL or lobby code
+    // True once the deadline has passed without a result; the organizer may then forfeit it.
+    Forfeitable bool `json:"forfeitable"`
+
+    // Third-place match, fed by the semi-final losers through loser_next_match_id
+    ThirdPlace       bool    `json:"third_place"`
+    LoserNextMatchID *string `json:"loser_next_match_id"`
 }
 
+// seedOrder returns the seed placed in each first-round slot of a bracket of size slots (a power of two),
+// e.g. 1, 8, 4, 5, 2, 7, 3, 6 for eight: seed 1 meets seed 8 and can only meet seed 2 in the final.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// firstRoundSlots places the participants, best seed first, into the first round of a bracket with
+// the given number of rounds. The standard seed order spreads the byes one per match, so every
+// first-round match has a player; with random seeding the order is just the shuffle.
+func firstRoundSlots(participants []Participant, rounds int) []*string {
+	slots := make([]*string, 1<<rounds)
+	for i, seed := range seedOrder(len(slots)) {
+		if seed <= len(participants) {
+			slots[i] = &participants[seed-1].ID
+		}
+	}
+	return slots
+}
+
+// GenerateBracket builds a single-elimination bracket. ?seeding=rating seeds by the participants'
+// ratings in the tournament's game instead of randomly; ?third_place=true adds a third-place match.
 func (h *BracketHandler) GenerateBracket(c echo.Context) error {
 	tournamentID := c.QueryParam("tournament_id")
 	if tournamentID == "" {
@@ -44,35 +99,100 @@ func (h *BracketHandler) GenerateBracket(c echo.Context) error {
 	}
 
 	// 1. Fetch Participants from Tournament Service
-	resp, err := http.Get(fmt.Sprintf("%s/tournaments/%s/participants", h.TournamentServiceURL, tournamentID))
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, fmt.Sprintf("%s/tournaments/%s/participants", h.TournamentServiceURL, tournamentID), nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch participants"})
+	}
+	req.Header.Set(echo.HeaderXRequestID, c.Request().Header.Get(echo.HeaderXRequestID))
+	resp, err := tracedClient.Do(req)
 	if err != nil || resp.StatusCode != 200 {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch participants"})
 	}
 	defer resp.Body.Close()
 
-	var participants []Participant
-	if err := json.NewDecoder(resp.Body).Decode(&participants); err != nil {
+	var registered []Participant
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decode participants"})
 	}
 
+	// Disqualified participants are not seeded
+	participants := make([]Participant, 0, len(registered))
+	for _, p := range registered {
+		if p.Status != "disqualified" {
+			participants = append(participants, p)
+		}
+	}
+
 	count := len(participants)
 	if count < 2 {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Not enough participants to generate a bracket"})
 	}
 
-	// 2. Shuffle Participants
+	// 2. Game and participant type of the tournament; ratings are kept per game and type
+	var meta struct {
+		Game            string `json:"game"`
+		ParticipantType string `json:"participant_type"`
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), tournamentLookupTimeout)
+	metaStatus, err := h.getTournamentJSON(ctx, fmt.Sprintf("/tournaments/%s", tournamentID),
+		c.Request().Header.Get("X-User-Id"), c.Request().Header.Get("X-User-Roles"), &meta)
+	cancel()
+	if err != nil || metaStatus != http.StatusOK {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch tournament"})
+	}
+
+	// 3. Shuffle Participants, then order them by rating when seeding by rating (ties stay random)
 	rand.Shuffle(count, func(i, j int) { participants[i], participants[j] = participants[j], participants[i] })
 
-	// 3. Calculate Bracket Depth
+	seeding := c.QueryParam("seeding")
+	switch seeding {
+	case "", "random":
+	case "rating":
+		ids := make([]string, count)
+		for i, p := range participants {
+			ids[i] = p.ID
+		}
+		ratings, err := h.ratingsByID(c.Request().Context(), meta.Game, meta.ParticipantType, ids)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch ratings"})
+		}
+		ratingOf := func(id string) float64 {
+			if r, ok := ratings[id]; ok {
+				return r
+			}
+			return InitialRating
+		}
+		sort.SliceStable(participants, func(i, j int) bool { return ratingOf(participants[i].ID) > ratingOf(participants[j].ID) })
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "seeding must be random or rating"})
+	}
+
+	// 4. Calculate Bracket Depth
 	power := math.Ceil(math.Log2(float64(count)))
 	rounds := int(power)
 
-	// 4. Generate Matches
-	tx, err := h.DB.Begin(context.Background())
+	slots := firstRoundSlots(participants, rounds)
+
+	// 5. Generate Matches
+	tx, err := h.DB.Begin(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Transaction failed"})
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(c.Request().Context())
+
+	// Optional match for 3rd place between the semi-final losers (needs at least two rounds)
+	thirdPlace := c.QueryParam("third_place") == "true"
+	if thirdPlace && rounds < 2 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A third-place match needs at least 3 participants"})
+	}
+
+	_, err = tx.Exec(c.Request().Context(), `
+		INSERT INTO brackets (tournament_id, game, participant_type) VALUES ($1, $2, $3)
+		ON CONFLICT (tournament_id) DO UPDATE SET game = EXCLUDED.game, participant_type = EXCLUDED.participant_type`,
+		tournamentID, meta.Game, meta.ParticipantType)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save bracket"})
+	}
 
 	// Map to keep track of created matches to link next_match_id
 	matchMap := make(map[string]string)
@@ -94,37 +214,45 @@ func (h *BracketHandler) GenerateBracket(c echo.Context) error {
 
 			var p1, p2, winnerID *string
 			status := "scheduled"
+			resultType := ResultNormal
 			
 			// Fill round 1 with players
 			if r == 1 {
-				idx1 := (m - 1) * 2
-				idx2 := idx1 + 1
-
-				if idx1 < count {
-					p1 = &participants[idx1].ID
-				}
-				if idx2 < count {
-					p2 = &participants[idx2].ID
-				}
+				p1 = slots[(m-1)*2]
+				p2 = slots[(m-1)*2+1]
 
 				// Handle BYE
 				if p1 != nil && p2 == nil {
 					status = "completed"
 					winnerID = p1
+					resultType = ResultBye
 				}
 			}
 
 			var matchID string
-			err := tx.QueryRow(context.Background(), `
-				INSERT INTO matches (tournament_id, round, match_number, player1_id, player2_id, next_match_id, status, winner_id)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
-			`, tournamentID, r, m, p1, p2, nextMatchID, status, winnerID).Scan(&matchID)
+			err := tx.QueryRow(c.Request().Context(), `
+				INSERT INTO matches (tournament_id, round, match_number, player1_id, player2_id, next_match_id, status, winner_id, result_type)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id
+			`, tournamentID, r, m, p1, p2, nextMatchID, status, winnerID, resultType).Scan(&matchID)
 
 			if err != nil {
 				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save match"})
 			}
 
 			matchMap[fmt.Sprintf("%d-%d", r, m)] = matchID
+
+			if thirdPlace && r == rounds {
+				// Shares the final round; match number 2 so the final stays match 1
+				var thirdPlaceID string
+				err := tx.QueryRow(c.Request().Context(), `
+					INSERT INTO matches (tournament_id, round, match_number, status, result_type, third_place)
+					VALUES ($1, $2, 2, 'scheduled', $3, true) RETURNING id
+				`, tournamentID, r, ResultNormal).Scan(&thirdPlaceID)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save third-place match"})
+				}
+				matchMap["third-place"] = thirdPlaceID
+			}
 			// Advance automatically for byes
 			if status == "completed" && winnerID != nil && nextMatchID != nil {
 				// Determine target slot (Odd -> P1, Even -> P2)
@@ -135,7 +263,7 @@ func (h *BracketHandler) GenerateBracket(c echo.Context) error {
 
 				// Update the Next Match immediately
 				advQuery := fmt.Sprintf("UPDATE matches SET %s = $1 WHERE id = $2", targetField)
-				_, err = tx.Exec(context.Background(), advQuery, winnerID, *nextMatchID)
+				_, err = tx.Exec(c.Request().Context(), advQuery, winnerID, *nextMatchID)
 				if err != nil {
 					return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to advance bye winner"})
 				}
@@ -143,56 +271,112 @@ func (h *BracketHandler) GenerateBracket(c echo.Context) error {
 		}
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
+	if thirdPlace {
+		_, err = tx.Exec(c.Request().Context(), `UPDATE matches SET loser_next_match_id = $1 WHERE tournament_id = $2 AND round = $3`,
+			matchMap["third-place"], tournamentID, rounds-1)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to link third-place match"})
+		}
+	}
+
+	if err := tx.Commit(c.Request().Context()); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit bracket"})
 	}
 
+	h.notifyBracketUpdated(c.Request().Context(), tournamentID, "", "generated")
+
 	return c.JSON(http.StatusOK, map[string]string{"message": "Bracket generated successfully", "rounds": fmt.Sprintf("%d", rounds)})
 }
 
+// GetBracket returns the matches of a tournament with participant names resolved, both as a flat
+// list and grouped by round. ?view=tree nests the matches along next_match_id instead.
 func (h *BracketHandler) GetBracket(c echo.Context) error {
 	tournamentID := c.Param("tournamentId") // Matches the :tournament_id in main.go
 
+	matches, err := h.loadBracket(c.Request().Context(), tournamentID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "bracket load failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bracket"})
+	}
+
+	// Names are a convenience; the bracket is still served if tournament-service is unavailable
+	names, err := h.callerParticipantNames(c, tournamentID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "participant name lookup failed", "error", err)
+	}
+	applyNames(matches, names)
+
+	if c.QueryParam("view") == "tree" {
+		root, thirdPlace := buildTree(matches)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"tournament_id": tournamentID,
+			"tree":          root,
+			"third_place":   thirdPlace,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tournament_id": tournamentID,
+		"matches":       matches,
+		"rounds":        groupRounds(matches),
+	})
+}
+
+// loadBracket reads every match of a tournament (final first) with its series games attached.
+func (h *BracketHandler) loadBracket(ctx context.Context, tournamentID string) ([]Match, error) {
 	// 1. Query Matches
     // We explicitly select columns to match your struct fields
 	query := `
 		SELECT id, tournament_id, round, match_number, 
                player1_id, player2_id, next_match_id, status,
-               COALESCE(score_a, ''), COALESCE(score_b, '')
+               score_a, score_b, winner_id,
+               COALESCE(result_type, 'normal'), best_of,
+               scheduled_at, deadline_at, venue,
+               third_place, loser_next_match_id
 		FROM matches 
 		WHERE tournament_id = $1
         ORDER BY round DESC, match_number ASC
 	`
-	rows, err := h.DB.Query(context.Background(), query, tournamentID)
+	rows, err := h.DB.Query(ctx, query, tournamentID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bracket"})
+		return nil, err
 	}
 	defer rows.Close()
 
 	matches := []Match{}
 	for rows.Next() {
 		var m Match
-        // Temporary vars for scores to handle potential NULLs safely
-        var sA, sB string 
 
 		err := rows.Scan(
             &m.ID, &m.TournamentID, &m.Round, &m.MatchNumber, 
             &m.Player1ID, &m.Player2ID, &m.NextMatchID, &m.Status,
-            &sA, &sB,
+            &m.ScoreA, &m.ScoreB, &m.WinnerID,
+            &m.ResultType, &m.BestOf,
+            &m.ScheduledAt, &m.DeadlineAt, &m.Venue,
+            &m.ThirdPlace, &m.LoserNextMatchID,
         )
 		if err != nil {
             // Log error but continue? Or return error. 
             // Ideally log it: log.Printf("Scan error: %v", err)
 			continue
 		}
-        m.ScoreA = &sA
-        m.ScoreB = &sB
+        m.Forfeitable = isForfeitable(m.Status, m.DeadlineAt, time.Now())
 		matches = append(matches, m)
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"matches": matches,
-	})
+	// 2. Attach the game breakdown of each series
+	games, err := h.loadGames(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		matches[i].Games = games[matches[i].ID]
+		if matches[i].Games == nil {
+			matches[i].Games = []Game{}
+		}
+	}
+
+	return matches, nil
 }
 
 
@@ -202,6 +386,8 @@ type ResultRequest struct {
 	WinnerID string `json:"winner_id"`
 }
 
+// UpdateMatchResult records the result of a single-game match; series are reported game by game
+// through RecordGame. Organizer, co-organizer or referee only.
 func (h *BracketHandler) UpdateMatchResult(c echo.Context) error {
 	matchID := c.Param("match_id")
 	var req ResultRequest
@@ -209,27 +395,51 @@ func (h *BracketHandler) UpdateMatchResult(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
 	}
 
-	ctx := context.Background()
+	ctx := c.Request().Context()
 
-	// 1. Start Transaction (Critical for integrity)
+	// 1. Check permissions before anything is locked
+	tournamentID, err := h.matchTournament(ctx, matchID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+	allowed, err := h.canManageTournament(c, tournamentID, true)
+	if err != nil {
+		slog.ErrorContext(ctx, "permission lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify permissions"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer or a referee can report results"})
+	}
+
+	// 2. Start Transaction (Critical for integrity)
 	tx, err := h.DB.Begin(ctx)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
 	}
 	defer tx.Rollback(ctx)
 
-	// 2. Fetch Current Match to get 'NextMatchID' and 'MatchNumber'
-	var nextMatchID *string
-	var matchNum int
-	err = tx.QueryRow(ctx, `SELECT next_match_id, match_number FROM matches WHERE id = $1`, matchID).Scan(&nextMatchID, &matchNum)
+	// 3. Lock Current Match to get 'NextMatchID', 'MatchNumber', the players (for the loser's path) and its format
+	var nextMatchID, loserNextMatchID, p1, p2 *string
+	var matchNum, bestOf int
+	err = tx.QueryRow(ctx, `SELECT next_match_id, loser_next_match_id, match_number, player1_id, player2_id, best_of FROM matches WHERE id = $1 FOR UPDATE`, matchID).
+		Scan(&nextMatchID, &loserNextMatchID, &matchNum, &p1, &p2, &bestOf)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
 	}
+	if bestOf > 1 {
+		return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("Match is a best-of-%d series, report its games at /brackets/matches/%s/games", bestOf, matchID)})
+	}
+	if p1 == nil || p2 == nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is still waiting for its participants"})
+	}
+	if !isPlayer(req.WinnerID, p1, p2) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "winner_id must be one of the match participants"})
+	}
 
-	// 3. Update Current Match
+	// 4. Update Current Match
 	_, err = tx.Exec(ctx, `
 		UPDATE matches 
-		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed' 
+		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed', completed_at = NOW()
 		WHERE id = $4`,
 		req.ScoreA, req.ScoreB, req.WinnerID, matchID,
 	)
@@ -237,28 +447,18 @@ func (h *BracketHandler) UpdateMatchResult(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update match result"})
 	}
 
-	// 4. Advance Winner to Next Match (if not the final)
-	if nextMatchID != nil {
-		// Logic: If MatchNumber is Odd (1,3,5), winner goes to Player1 slot of next match.
-		//        If MatchNumber is Even (2,4,6), winner goes to Player2 slot.
-		updateField := "player1_id"
-		if matchNum%2 == 0 {
-			updateField = "player2_id"
-		}
-
-		query := fmt.Sprintf("UPDATE matches SET %s = $1 WHERE id = $2", updateField)
-		_, err = tx.Exec(ctx, query, req.WinnerID, *nextMatchID)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to advance winner"})
-		}
+	// 5. Advance Winner to Next Match (if not the final), and the loser to the third-place match
+	if err := advanceParticipants(ctx, tx, nextMatchID, loserNextMatchID, matchNum, &req.WinnerID, opponentOf(req.WinnerID, p1, p2)); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to advance winner"})
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
 	}
 
-	// 5. Publish Event (for other services)
-	// _ = h.RMQ.Publish("events.match.completed", ...)
+	// 6. Publish Event (for other services)
+	h.publishMatchCompleted(ctx, tournamentID, matchID, req.WinnerID, p1, p2, req.ScoreA, req.ScoreB)
+	h.notifyBracketUpdated(ctx, tournamentID, matchID, "result")
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Match updated"})
 }
\ No newline at end of file
@@ -2,20 +2,26 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockRabbitMQ
 type MockRabbitMQ struct{}
-func (m *MockRabbitMQ) Publish(key, body string) error { return nil }
+func (m *MockRabbitMQ) Publish(ctx context.Context, key, body string) error { return nil }
+
+var lockedMatchColumns = []string{"next_match_id", "loser_next_match_id", "match_number", "player1_id", "player2_id", "best_of"}
 
 func TestGenerateBracket_Success(t *testing.T) {
 	e := echo.New()
@@ -26,6 +32,10 @@ func TestGenerateBracket_Success(t *testing.T) {
 
 	// 1. Mock External Service
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		participants := []Participant{
 			{ID: "p1", Name: "Player 1"}, {ID: "p2", Name: "Player 2"},
 			{ID: "p3", Name: "Player 3"}, {ID: "p4", Name: "Player 4"},
@@ -41,6 +51,9 @@ func TestGenerateBracket_Success(t *testing.T) {
 	}
 
 	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`INSERT INTO brackets`).
+		WithArgs("t1", "chess", "individual").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 	// 2. Expectations
 	// We use pgxmock.AnyArg() for ALL arguments to ensure the test passes 
@@ -48,16 +61,16 @@ func TestGenerateBracket_Success(t *testing.T) {
 	
 	// Round 2 (Final) - 1 Match
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-final"))
 
 	// Round 1 (Semis) - 2 Matches
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-1"))
 
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-2"))
 
 	mockDB.ExpectCommit()
@@ -73,6 +86,67 @@ func TestGenerateBracket_Success(t *testing.T) {
 	assert.NoError(t, mockDB.ExpectationsWereMet())
 }
 
+func TestGenerateBracket_ThirdPlace(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
+		participants := []Participant{
+			{ID: "p1", Name: "Player 1"}, {ID: "p2", Name: "Player 2"},
+			{ID: "p3", Name: "Player 3"}, {ID: "p4", Name: "Player 4"},
+			{ID: "p5", Name: "Cheater", Status: "disqualified"},
+		}
+		json.NewEncoder(w).Encode(participants)
+	}))
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`INSERT INTO brackets`).
+		WithArgs("t1", "chess", "individual").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	// Final, then the third-place match in the same round
+	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-final"))
+	mockDB.ExpectQuery(`(?s)INSERT INTO matches.*third_place`).
+		WithArgs("t1", 2, ResultNormal).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-third"))
+
+	// Semis (the disqualified participant is not seeded, so no byes)
+	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-1"))
+	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-2"))
+
+	// Semi-final losers drop into the third-place match
+	mockDB.ExpectExec(`UPDATE matches SET loser_next_match_id`).
+		WithArgs("match-third", "t1", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/brackets/generate?tournament_id=t1&third_place=true", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = h.GenerateBracket(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
 func TestGenerateBracket_Validation(t *testing.T) {
 	e := echo.New()
 	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
@@ -91,6 +165,10 @@ func TestGenerateBracket_Validation(t *testing.T) {
 	// Scenario 2: Not Enough Participants
 	// Mock returning 1 participant
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		participants := []Participant{{ID: "p1", Name: "Player 1"}}
 		json.NewEncoder(w).Encode(participants)
 	}))
@@ -112,7 +190,10 @@ func TestUpdateMatchResult_Advancement(t *testing.T) {
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
 
 	matchID := "match-1"
 	nextMatchID := "match-5"
@@ -120,19 +201,23 @@ func TestUpdateMatchResult_Advancement(t *testing.T) {
 	matchNum := 1 
 	body := `{"score_a": "2", "score_b": "1", "winner_id": "winner-user"}`
 
+	loserID := "loser-user"
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches WHERE id = $1`).
+		WithArgs(matchID).
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
 	mockDB.ExpectBegin()
 
 	// 1. Fetch
-	mockDB.ExpectQuery(`SELECT next_match_id, match_number FROM matches WHERE id = $1`).
+	mockDB.ExpectQuery(`SELECT next_match_id, loser_next_match_id, match_number, player1_id, player2_id, best_of FROM matches WHERE id = $1 FOR UPDATE`).
 		WithArgs(matchID).
-		WillReturnRows(pgxmock.NewRows([]string{"next_match_id", "match_number"}).
-			AddRow(&nextMatchID, matchNum))
+		WillReturnRows(pgxmock.NewRows(lockedMatchColumns).
+			AddRow(&nextMatchID, nil, matchNum, &winnerID, &loserID, 1))
 
 	// 2. Update Score
 	// NOTE: The whitespace must EXACTLY match the query in the handler
 	updateScoreSQL := `
 		UPDATE matches 
-		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed' 
+		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed', completed_at = NOW()
 		WHERE id = $4`
 	mockDB.ExpectExec(updateScoreSQL).
 		WithArgs("2", "1", winnerID, matchID).
@@ -144,10 +229,19 @@ func TestUpdateMatchResult_Advancement(t *testing.T) {
 		WithArgs(winnerID, nextMatchID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
+	// 4. Next match already has both players, so no walkover
+	opponentID := "other-user"
+	resolveSQL := `SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id = $1 FOR UPDATE`
+	mockDB.ExpectQuery(resolveSQL).
+		WithArgs(nextMatchID).
+		WillReturnRows(pgxmock.NewRows([]string{"player1_id", "player2_id", "status", "next_match_id", "loser_next_match_id", "match_number"}).
+			AddRow(&winnerID, &opponentID, "scheduled", nil, nil, 1))
+
 	mockDB.ExpectCommit()
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("match_id")
@@ -171,25 +265,39 @@ func TestGetBracket(t *testing.T) {
 	// Define specific types that match the Scan targets
 	// ID (string), TournamentID (string), Round (int), MatchNumber (int), 
 	// Player1ID (*string), Player2ID (*string), NextMatchID (*string), 
-	// Status (string), ScoreA (string), ScoreB (string)
+	// Status (string), ScoreA (*string), ScoreB (*string), WinnerID (*string), ResultType (string), BestOf (int),
+	// ScheduledAt (*time.Time), DeadlineAt (*time.Time), Venue (*string)
 	
 	p1 := "p1"
 	p2 := "p2"
 	next := "m2"
+	deadline := time.Now().Add(-time.Hour)
+	var noTime *time.Time
+	var noVenue *string
 	
 	mockDB.ExpectQuery(`(?s).*SELECT.*FROM matches.*`).
 		WithArgs("t1").
 		WillReturnRows(pgxmock.NewRows([]string{
 			"id", "tournament_id", "round", "match_number", 
 			"player1_id", "player2_id", "next_match_id", 
-			"status", "score_a", "score_b",
+			"status", "score_a", "score_b", "winner_id", "result_type", "best_of",
+			"scheduled_at", "deadline_at", "venue",
+			"third_place", "loser_next_match_id",
 		}).
 		AddRow(
 			"m1", "t1", 1, 1, 
 			&p1, &p2, &next, 
-			"scheduled", "0", "0",
+			"scheduled", noVenue, noVenue, noVenue, "normal", 3,
+			noTime, &deadline, noVenue,
+			false, noVenue,
 		))
 
+	winner := "p1"
+	mockDB.ExpectQuery(`(?s).*FROM match_games.*`).
+		WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"match_id", "game_number", "map", "score_a", "score_b", "winner_id"}).
+			AddRow("m1", 1, "Dust II", 16, 12, &winner))
+
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -201,6 +309,11 @@ func TestGetBracket(t *testing.T) {
 	// Verify we got the match back
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Contains(t, rec.Body.String(), "m1")
+	// Deadline passed without a result
+	assert.Contains(t, rec.Body.String(), `"forfeitable":true`)
+	assert.Contains(t, rec.Body.String(), `"map":"Dust II"`)
+	// Unreported scores stay null
+	assert.Contains(t, rec.Body.String(), `"score_a":null`)
 	assert.NoError(t, mockDB.ExpectationsWereMet())
 }
 
@@ -210,26 +323,32 @@ func TestUpdateMatchResult_FinalMatch(t *testing.T) {
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
 
 	matchID := "match-final"
 	winnerID := "winner-user"
 	matchNum := 1 
 	body := `{"score_a": "3", "score_b": "2", "winner_id": "winner-user"}`
 
+	loserID := "loser-user"
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches WHERE id = $1`).
+		WithArgs(matchID).
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
 	mockDB.ExpectBegin()
 
 	// 1. Fetch: Return NIL for next_match_id to simulate the Final
-	// Note: We use AddRow(nil, matchNum)
-	mockDB.ExpectQuery(`SELECT next_match_id, match_number FROM matches WHERE id = $1`).
+	mockDB.ExpectQuery(`SELECT next_match_id, loser_next_match_id, match_number, player1_id, player2_id, best_of FROM matches WHERE id = $1 FOR UPDATE`).
 		WithArgs(matchID).
-		WillReturnRows(pgxmock.NewRows([]string{"next_match_id", "match_number"}).
-			AddRow(nil, matchNum))
+		WillReturnRows(pgxmock.NewRows(lockedMatchColumns).
+			AddRow(nil, nil, matchNum, &winnerID, &loserID, 1))
 
 	// 2. Update Score (Standard update)
 	updateScoreSQL := `
 		UPDATE matches 
-		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed' 
+		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed', completed_at = NOW()
 		WHERE id = $4`
 	mockDB.ExpectExec(updateScoreSQL).
 		WithArgs("3", "2", winnerID, matchID).
@@ -240,6 +359,7 @@ func TestUpdateMatchResult_FinalMatch(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("match_id")
@@ -260,6 +380,10 @@ func TestGenerateBracket_NotEnoughParticipants(t *testing.T) {
 
 	// 1. Mock External Service returning only 1 participant
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		participants := []Participant{
 			{ID: "p1", Name: "Player 1"},
 		}
@@ -297,12 +421,10 @@ func TestUpdateMatchResult_NotFound(t *testing.T) {
 	matchID := "unknown-id"
 	body := `{"score_a": "1", "score_b": "0", "winner_id": "w"}`
 
-	mockDB.ExpectBegin()
-	// Fail the fetch
-	mockDB.ExpectQuery(`SELECT next_match_id, match_number FROM matches WHERE id = $1`).
+	// Fail the lookup; nothing is locked
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches WHERE id = $1`).
 		WithArgs(matchID).
 		WillReturnError(errors.New("no rows in result set"))
-	mockDB.ExpectRollback()
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -317,6 +439,60 @@ func TestUpdateMatchResult_NotFound(t *testing.T) {
 	assert.NoError(t, mockDB.ExpectationsWereMet())
 }
 
+func TestUpdateMatchResult_Rejected(t *testing.T) {
+	p1, p2 := "p1", "p2"
+	tests := []struct {
+		name     string
+		userID   string
+		bestOf   int
+		winnerID string
+		locks    bool
+		status   int
+		message  string
+	}{
+		{"not staff", "p1", 1, "p1", false, http.StatusForbidden, "Only the organizer or a referee"},
+		{"series", "org-1", 3, "p1", true, http.StatusConflict, "/brackets/matches/m1/games"},
+		{"winner not in match", "org-1", 1, "someone-else", true, http.StatusBadRequest, "winner_id must be one of the match participants"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+			assert.NoError(t, err)
+			defer mockDB.Close()
+
+			tsMock := newTournamentServiceMock("org-1", nil)
+			defer tsMock.Close()
+			h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+			mockDB.ExpectQuery(`SELECT tournament_id FROM matches WHERE id = $1`).
+				WithArgs("m1").
+				WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+			if tt.locks {
+				mockDB.ExpectBegin()
+				mockDB.ExpectQuery(`SELECT next_match_id, loser_next_match_id, match_number, player1_id, player2_id, best_of FROM matches WHERE id = $1 FOR UPDATE`).
+					WithArgs("m1").
+					WillReturnRows(pgxmock.NewRows(lockedMatchColumns).AddRow(nil, nil, 1, &p1, &p2, tt.bestOf))
+				mockDB.ExpectRollback()
+			}
+
+			body := `{"score_a": "1", "score_b": "0", "winner_id": "` + tt.winnerID + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("X-User-Id", tt.userID)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("match_id")
+			c.SetParamValues("m1")
+
+			assert.NoError(t, h.UpdateMatchResult(c))
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.message)
+			assert.NoError(t, mockDB.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestGenerateBracket_WithBye(t *testing.T) {
 	e := echo.New()
 	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
@@ -325,6 +501,10 @@ func TestGenerateBracket_WithBye(t *testing.T) {
 
 	// 1. Mock External Service (3 Participants = 1 Bye)
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		participants := []Participant{
 			{ID: "p1", Name: "Player 1"},
 			{ID: "p2", Name: "Player 2"},
@@ -341,32 +521,35 @@ func TestGenerateBracket_WithBye(t *testing.T) {
 	}
 
 	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`INSERT INTO brackets`).
+		WithArgs("t1", "chess", "individual").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	// LOGIC: 3 Players -> 4 Slots. Round 1 has 2 matches.
-	// Match 1: P1 vs P2 (Standard)
-	// Match 2: P3 vs NULL (Bye) -> Auto Advance P3
+	// LOGIC: 3 Players -> 4 Slots in seed order 1, 4, 2, 3. Round 1 has 2 matches.
+	// Match 1: Seed 1 vs NULL (Bye) -> Auto Advance Seed 1
+	// Match 2: Seed 2 vs Seed 3 (Standard)
 
 	// 1. Insert Final (Round 2)
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), 2, 1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), 2, 1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), ResultNormal).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-final"))
 
-	// 2. Insert Semi 1 (Standard)
+	// 2. Insert Semi 1 (Bye)
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), 1, 1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), 1, 1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), ResultBye).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-1"))
 
-	// 3. Insert Semi 2 (Bye)
+	// 3. Expect Auto-Advancement UPDATE for the Bye match
+	// Logic: Match 1 (Odd) is a bye, so its player takes the Player1 slot of the final
+	mockDB.ExpectExec(`UPDATE matches SET player1_id`).
+		WithArgs(pgxmock.AnyArg(), "match-final").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	// 4. Insert Semi 2 (Standard)
 	mockDB.ExpectQuery(`(?s).*INSERT INTO matches.*`).
-		WithArgs(pgxmock.AnyArg(), 1, 2, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WithArgs(pgxmock.AnyArg(), 1, 2, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), ResultNormal).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("match-semi-2"))
 
-	// 4. Expect Auto-Advancement UPDATE for the Bye match
-	// Logic: If Match 2 (Even) is bye, update Player2 slot of next match
-	mockDB.ExpectExec(`(?s).*UPDATE matches SET.*`).
-		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
-		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-
 	mockDB.ExpectCommit()
 
 	req := httptest.NewRequest(http.MethodPost, "/brackets/generate?tournament_id=t1", nil)
@@ -385,6 +568,10 @@ func TestGenerateBracket_TournamentServiceError(t *testing.T) {
 	
 	// Mock Server that returns 500
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 	}))
 	defer tsMock.Close()
@@ -452,23 +639,30 @@ func TestUpdateMatchResult_UpdateError(t *testing.T) {
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	h := &BracketHandler{DB: mockDB}
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, TournamentServiceURL: tsMock.URL}
 
 	matchID := "match-1"
 	body := `{"score_a": "1", "score_b": "0", "winner_id": "w"}`
 
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches WHERE id = $1`).
+		WithArgs(matchID).
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
 	mockDB.ExpectBegin()
 	
 	// 1. Fetch Success
 	var nextMatchID string = "next-id"
-	mockDB.ExpectQuery(`SELECT next_match_id, match_number FROM matches WHERE id = $1`).
+	winnerID, loserID := "w", "l"
+	mockDB.ExpectQuery(`SELECT next_match_id, loser_next_match_id, match_number, player1_id, player2_id, best_of FROM matches WHERE id = $1 FOR UPDATE`).
 		WithArgs(matchID).
-		WillReturnRows(pgxmock.NewRows([]string{"next_match_id", "match_number"}).AddRow(&nextMatchID, 1))
+		WillReturnRows(pgxmock.NewRows(lockedMatchColumns).AddRow(&nextMatchID, nil, 1, &winnerID, &loserID, 1))
 
 	// 2. Update Failure (Simulate DB error during write)
 	updateScoreSQL := `
 		UPDATE matches 
-		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed' 
+		SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed', completed_at = NOW()
 		WHERE id = $4`
 	mockDB.ExpectExec(updateScoreSQL).
 		WithArgs("1", "0", "w", matchID).
@@ -478,6 +672,7 @@ func TestUpdateMatchResult_UpdateError(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("match_id")
@@ -498,6 +693,10 @@ func TestGenerateBracket_DBTxError(t *testing.T) {
 
 	// Mock External Service Success
 	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
 		participants := []Participant{
 			{ID: "p1", Name: "Player 1"}, {ID: "p2", Name: "Player 2"},
 		}
@@ -523,4 +722,130 @@ func TestGenerateBracket_DBTxError(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	assert.Contains(t, rec.Body.String(), "DB Transaction failed")
-}
\ No newline at end of file
+}
+// recordArg matches any argument and keeps it, so a test can rebuild what the handler wrote.
+type recordArg struct{ dst **string }
+
+func (a recordArg) Match(v interface{}) bool {
+	if p, ok := v.(*string); ok {
+		*a.dst = p
+	}
+	return true
+}
+
+type playedMatch struct {
+	p1, p2, winner *string
+	status         string
+}
+
+func TestGenerateBracket_PlaysToTheEnd(t *testing.T) {
+	tests := []struct {
+		players    int
+		byeMatches map[int]bool // First-round matches decided by a bye, from the seed order 1, 8, 4, 5, 2, 7, 3, 6
+	}{
+		{5, map[int]bool{1: true, 3: true, 4: true}},
+		{6, map[int]bool{1: true, 3: true}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			e := echo.New()
+			mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			assert.NoError(t, err)
+			defer mockDB.Close()
+
+			tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/tournaments/t1" {
+					json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+					return
+				}
+				participants := []Participant{}
+				for i := 1; i <= tt.players; i++ {
+					participants = append(participants, Participant{ID: fmt.Sprintf("p%d", i)})
+				}
+				json.NewEncoder(w).Encode(participants)
+			}))
+			defer tsMock.Close()
+
+			h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+			// 8 slots, 3 rounds, inserted final first. Byes advance right after their match is inserted.
+			matches := map[string]*playedMatch{}
+			var advanced []**string
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`INSERT INTO brackets`).
+				WithArgs("t1", "chess", "individual").
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			for r := 3; r >= 1; r-- {
+				for m := 1; m <= 1<<(3-r); m++ {
+					id := fmt.Sprintf("%d-%d", r, m)
+					pm := &playedMatch{}
+					matches[id] = pm
+					resultType := ResultNormal
+					if r == 1 && tt.byeMatches[m] {
+						resultType = ResultBye
+					}
+					mockDB.ExpectQuery(`(?s)INSERT INTO matches`).
+						WithArgs("t1", r, m, recordArg{&pm.p1}, recordArg{&pm.p2}, pgxmock.AnyArg(), pgxmock.AnyArg(), recordArg{&pm.winner}, resultType).
+						WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(id))
+					if resultType == ResultBye {
+						pm.status = "completed"
+						winner := new(*string)
+						advanced = append(advanced, winner)
+						mockDB.ExpectExec(`UPDATE matches SET player[12]_id`).
+							WithArgs(recordArg{winner}, fmt.Sprintf("2-%d", (m+1)/2)).
+							WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+					}
+				}
+			}
+			mockDB.ExpectCommit()
+
+			req := httptest.NewRequest(http.MethodPost, "/brackets/generate?tournament_id=t1", nil)
+			rec := httptest.NewRecorder()
+			assert.NoError(t, h.GenerateBracket(e.NewContext(req, rec)))
+			require.NoError(t, mockDB.ExpectationsWereMet())
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			// Every first-round match has a player, and the byes went on to round 2
+			for m := 1; m <= 4; m++ {
+				pm := matches[fmt.Sprintf("1-%d", m)]
+				require.NotNil(t, pm.p1, "match %d has no players", m)
+				if tt.byeMatches[m] {
+					assert.Nil(t, pm.p2)
+					assert.Equal(t, pm.p1, pm.winner)
+				}
+			}
+			for _, p := range advanced {
+				require.NotNil(t, *p)
+			}
+
+			// Play it out: byes are already decided, player1 wins every played match
+			seen := map[string]bool{}
+			for r := 1; r <= 3; r++ {
+				for m := 1; m <= 1<<(3-r); m++ {
+					pm := matches[fmt.Sprintf("%d-%d", r, m)]
+					if pm.status != "completed" {
+						require.NotNil(t, pm.p1, "round %d match %d never got its players", r, m)
+						require.NotNil(t, pm.p2, "round %d match %d never got its players", r, m)
+						pm.winner, pm.status = pm.p1, "completed"
+					}
+					for _, p := range []*string{pm.p1, pm.p2} {
+						if r == 1 && p != nil {
+							assert.False(t, seen[*p], "%s seeded twice", *p)
+							seen[*p] = true
+						}
+					}
+					if r < 3 {
+						next := matches[fmt.Sprintf("%d-%d", r+1, (m+1)/2)]
+						if m%2 == 1 {
+							next.p1 = pm.winner
+						} else {
+							next.p2 = pm.winner
+						}
+					}
+				}
+			}
+			assert.Len(t, seen, tt.players)
+			assert.NotNil(t, matches["3-1"].winner, "the final must be decided")
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// How long resolved participant names are reused before asking tournament-service again.
+const participantNameTTL = time.Minute
+
+// NameCache keeps participant names per tournament so bracket reads do not hit tournament-service every time.
+// Expired entries are removed, so it only holds the tournaments read within the last ttl.
+type NameCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]nameCacheEntry
+}
+
+type nameCacheEntry struct {
+	names     map[string]string
+	expiresAt time.Time
+}
+
+func NewNameCache(ttl time.Duration) *NameCache {
+	return &NameCache{ttl: ttl, entries: make(map[string]nameCacheEntry)}
+}
+
+func (nc *NameCache) get(tournamentID string, now time.Time) (map[string]string, bool) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	e, ok := nc.entries[tournamentID]
+	if !ok {
+		return nil, false
+	}
+	if now.After(e.expiresAt) {
+		delete(nc.entries, tournamentID)
+		return nil, false
+	}
+	return e.names, true
+}
+
+func (nc *NameCache) put(tournamentID string, names map[string]string, now time.Time) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	// Puts happen at most once per ttl and tournament, so sweeping here keeps the map small cheaply
+	for id, e := range nc.entries {
+		if now.After(e.expiresAt) {
+			delete(nc.entries, id)
+		}
+	}
+	nc.entries[tournamentID] = nameCacheEntry{names: names, expiresAt: now.Add(nc.ttl)}
+}
+
+// cachedParticipantNames is participantNames behind the handler's NameCache (if configured).
+func (h *BracketHandler) cachedParticipantNames(ctx context.Context, tournamentID, userID, userRoles string) (map[string]string, error) {
+	if h.Names == nil {
+		return h.participantNames(ctx, tournamentID, userID, userRoles)
+	}
+	if names, ok := h.Names.get(tournamentID, time.Now()); ok {
+		return names, nil
+	}
+	names, err := h.participantNames(ctx, tournamentID, userID, userRoles)
+	if err != nil {
+		return nil, err
+	}
+	h.Names.put(tournamentID, names, time.Now())
+	return names, nil
+}
+
+// callerParticipantNames resolves names with the identity of the current request.
+func (h *BracketHandler) callerParticipantNames(c echo.Context, tournamentID string) (map[string]string, error) {
+	return h.cachedParticipantNames(c.Request().Context(), tournamentID,
+		c.Request().Header.Get("X-User-Id"), c.Request().Header.Get("X-User-Roles"))
+}
+
+// applyNames fills in the display names of each match's participants.
+func applyNames(matches []Match, names map[string]string) {
+	lookup := func(id *string) *string {
+		if id == nil {
+			return nil
+		}
+		if name, ok := names[*id]; ok {
+			return &name
+		}
+		return nil
+	}
+	for i := range matches {
+		matches[i].Player1Name = lookup(matches[i].Player1ID)
+		matches[i].Player2Name = lookup(matches[i].Player2ID)
+	}
+}
+
+// BracketRound groups the matches of one round.
+type BracketRound struct {
+	Round   int     `json:"round"`
+	Name    string  `json:"name"` // "Final", "Semi-finals", "Round of 16", ...
+	Matches []Match `json:"matches"`
+}
+
+// groupRounds groups matches by round, first round first, keeping the match order within a round.
+func groupRounds(matches []Match) []BracketRound {
+	rounds := 0
+	for _, m := range matches {
+		if m.Round > rounds {
+			rounds = m.Round
+		}
+	}
+
+	grouped := make([]BracketRound, rounds)
+	for r := range grouped {
+		grouped[r] = BracketRound{Round: r + 1, Name: roundName(r+1, rounds), Matches: []Match{}}
+	}
+	for _, m := range matches {
+		if m.Round >= 1 {
+			grouped[m.Round-1].Matches = append(grouped[m.Round-1].Matches, m)
+		}
+	}
+	return grouped
+}
+
+// MatchNode is a match with the matches that feed into it, for ?view=tree.
+type MatchNode struct {
+	Match
+	Children []*MatchNode `json:"children"` // Feeder matches ordered by match_number; empty in the first round
+}
+
+// buildTree nests matches along next_match_id, returning the final as root.
+// The third-place match is not part of the tree and is returned separately.
+func buildTree(matches []Match) (root, thirdPlace *MatchNode) {
+	nodes := make(map[string]*MatchNode, len(matches))
+	for _, m := range matches {
+		nodes[m.ID] = &MatchNode{Match: m, Children: []*MatchNode{}}
+	}
+
+	// Sorted by round DESC, match_number ASC, so children are appended in match_number order
+	for _, m := range matches {
+		n := nodes[m.ID]
+		switch {
+		case m.ThirdPlace:
+			thirdPlace = n
+		case m.NextMatchID == nil:
+			root = n
+		default:
+			if parent, ok := nodes[*m.NextMatchID]; ok {
+				parent.Children = append(parent.Children, n)
+			}
+		}
+	}
+	return root, thirdPlace
+}
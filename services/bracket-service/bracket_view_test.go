@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTree(t *testing.T) {
+	matches := exportMatches()
+	third := Match{ID: "m-third", Round: 2, MatchNumber: 2, ThirdPlace: true}
+	matches = append(matches[:1], append([]Match{third}, matches[1:]...)...)
+
+	root, thirdPlace := buildTree(matches)
+
+	assert.Equal(t, "m-final", root.ID)
+	assert.Len(t, root.Children, 2)
+	assert.Equal(t, "m1", root.Children[0].ID)
+	assert.Equal(t, "m2", root.Children[1].ID)
+	assert.Empty(t, root.Children[0].Children)
+	assert.Equal(t, "m-third", thirdPlace.ID)
+}
+
+func TestGroupRounds(t *testing.T) {
+	rounds := groupRounds(exportMatches())
+
+	assert.Len(t, rounds, 2)
+	assert.Equal(t, "Semi-finals", rounds[0].Name)
+	assert.Equal(t, []string{"m1", "m2"}, []string{rounds[0].Matches[0].ID, rounds[0].Matches[1].ID})
+	assert.Equal(t, "Final", rounds[1].Name)
+}
+
+func TestNameCache_Expires(t *testing.T) {
+	nc := NewNameCache(time.Minute)
+	now := time.Now()
+	nc.put("t1", map[string]string{"p1": "Alice"}, now)
+
+	names, ok := nc.get("t1", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, "Alice", names["p1"])
+
+	_, ok = nc.get("t1", now.Add(2*time.Minute))
+	assert.False(t, ok)
+	assert.Empty(t, nc.entries, "an expired entry is removed when read")
+}
+
+func TestNameCache_PutRemovesExpiredEntries(t *testing.T) {
+	nc := NewNameCache(time.Minute)
+	now := time.Now()
+	nc.put("t1", map[string]string{"p1": "Alice"}, now)
+	nc.put("t2", map[string]string{"p2": "Bob"}, now.Add(30*time.Second))
+
+	// t1 was never read again after it expired
+	nc.put("t3", map[string]string{"p3": "Carol"}, now.Add(80*time.Second))
+
+	assert.Len(t, nc.entries, 2)
+	assert.NotContains(t, nc.entries, "t1")
+}
+
+func TestGetBracket_TreeViewWithNames(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	lookups := 0
+	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		json.NewEncoder(w).Encode([]Participant{{ID: "p1", Name: "Alice"}, {ID: "p2", Name: "Bob"}, {ID: "p3", Name: "Carol"}})
+	}))
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, TournamentServiceURL: tsMock.URL, Names: NewNameCache(time.Minute)}
+
+	p1, p2, p3, final := "p1", "p2", "p3", "m-final"
+	var none *string
+	var noTime *time.Time
+	columns := []string{
+		"id", "tournament_id", "round", "match_number",
+		"player1_id", "player2_id", "next_match_id",
+		"status", "score_a", "score_b", "winner_id", "result_type", "best_of",
+		"scheduled_at", "deadline_at", "venue",
+		"third_place", "loser_next_match_id",
+	}
+	for i := 0; i < 2; i++ {
+		mockDB.ExpectQuery(`(?s)SELECT.*FROM matches`).
+			WithArgs("t1").
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(final, "t1", 2, 1, &p1, none, none, "scheduled", none, none, none, "normal", 1, noTime, noTime, none, false, none).
+				AddRow("m1", "t1", 1, 1, &p1, &p2, &final, "completed", none, none, &p1, "normal", 1, noTime, noTime, none, false, none).
+				AddRow("m2", "t1", 1, 2, &p3, none, &final, "scheduled", none, none, none, "normal", 1, noTime, noTime, none, false, none))
+		mockDB.ExpectQuery(`(?s).*FROM match_games.*`).
+			WithArgs("t1").
+			WillReturnRows(pgxmock.NewRows([]string{"match_id", "game_number", "map", "score_a", "score_b", "winner_id"}))
+	}
+
+	var body struct {
+		Tree struct {
+			ID          string  `json:"id"`
+			Player1Name *string `json:"player1_name"`
+			Children    []struct {
+				ID          string  `json:"id"`
+				Player2Name *string `json:"player2_name"`
+			} `json:"children"`
+		} `json:"tree"`
+	}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/?view=tree", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("tournamentId")
+		c.SetParamValues("t1")
+
+		_ = h.GetBracket(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	}
+
+	assert.Equal(t, "m-final", body.Tree.ID)
+	assert.Equal(t, "Alice", *body.Tree.Player1Name)
+	assert.Len(t, body.Tree.Children, 2)
+	assert.Equal(t, "Bob", *body.Tree.Children[0].Player2Name)
+	assert.Nil(t, body.Tree.Children[1].Player2Name)
+	// Second read is served from the cache
+	assert.Equal(t, 1, lookups)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
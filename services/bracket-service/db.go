@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/jackc/pgx/v5"
@@ -34,6 +34,8 @@ func ConnectDB() (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Every query gets a span in the trace of the request or event that ran it
+	config.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -44,6 +46,6 @@ func ConnectDB() (*pgxpool.Pool, error) {
 		return nil, err
 	}
 
-	log.Println("Connected to Bracket DB (PostgreSQL)")
+	slog.Info("connected to bracket DB (PostgreSQL)")
 	return pool, nil
 }
\ No newline at end of file
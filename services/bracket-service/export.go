@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Version of the JSON export schema. Bump it on any breaking change to the Export* types;
+// new optional fields do not need a bump.
+const exportSchemaVersion = 1
+
+// Export formats accepted by ?format=.
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportSVG  = "svg"
+)
+
+// BracketExport is the stable, documented JSON export of a bracket (see swagger.yaml).
+type BracketExport struct {
+	SchemaVersion int                 `json:"schema_version"`
+	TournamentID  string              `json:"tournament_id"`
+	Format        string              `json:"format"` // Bracket format, currently always single_elimination
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Participants  []ExportParticipant `json:"participants"`
+	Rounds        []ExportRound       `json:"rounds"`
+}
+
+type ExportParticipant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ExportRound struct {
+	Round   int           `json:"round"`
+	Name    string        `json:"name"` // "Final", "Semi-finals", "Round of 16", ...
+	Matches []ExportMatch `json:"matches"`
+}
+
+type ExportMatch struct {
+	ID          string             `json:"id"`
+	MatchNumber int                `json:"match_number"`
+	ThirdPlace  bool               `json:"third_place"`
+	Player1     *ExportParticipant `json:"player1"` // null until decided
+	Player2     *ExportParticipant `json:"player2"`
+	ScoreA      *string            `json:"score_a"`
+	ScoreB      *string            `json:"score_b"`
+	WinnerID    *string            `json:"winner_id"`
+	Status      string             `json:"status"`
+	ResultType  string             `json:"result_type"`
+	BestOf      int                `json:"best_of"`
+	ScheduledAt *time.Time         `json:"scheduled_at"`
+	NextMatchID *string            `json:"next_match_id"`
+}
+
+// roundName labels a round by how far it is from the final.
+func roundName(round, rounds int) string {
+	switch rounds - round {
+	case 0:
+		return "Final"
+	case 1:
+		return "Semi-finals"
+	case 2:
+		return "Quarter-finals"
+	}
+	return fmt.Sprintf("Round of %d", 1<<(rounds-round+1))
+}
+
+// participantNames resolves participant IDs to display names through tournament-service, on behalf of the caller.
+// Disqualified participants are included so finished matches keep their names.
+func (h *BracketHandler) participantNames(ctx context.Context, tournamentID, userID, userRoles string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, tournamentLookupTimeout)
+	defer cancel()
+
+	var participants []Participant
+	status, err := h.getTournamentJSON(ctx, fmt.Sprintf("/tournaments/%s/participants", tournamentID), userID, userRoles, &participants)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("participants lookup returned %d", status)
+	}
+
+	names := make(map[string]string, len(participants))
+	for _, p := range participants {
+		names[p.ID] = p.Name
+	}
+	return names, nil
+}
+
+// buildExport groups matches into rounds (first round first) and resolves names.
+// Matches must be ordered by round DESC, match_number ASC as returned by loadBracket.
+func buildExport(tournamentID string, matches []Match, names map[string]string, now time.Time) BracketExport {
+	exp := BracketExport{
+		SchemaVersion: exportSchemaVersion,
+		TournamentID:  tournamentID,
+		Format:        "single_elimination",
+		GeneratedAt:   now.UTC(),
+		Participants:  []ExportParticipant{},
+		Rounds:        []ExportRound{},
+	}
+
+	rounds := 0
+	for _, m := range matches {
+		if m.Round > rounds {
+			rounds = m.Round
+		}
+	}
+
+	participant := func(id *string) *ExportParticipant {
+		if id == nil {
+			return nil
+		}
+		return &ExportParticipant{ID: *id, Name: names[*id]}
+	}
+
+	seen := make(map[string]bool)
+	for r := 1; r <= rounds; r++ {
+		round := ExportRound{Round: r, Name: roundName(r, rounds), Matches: []ExportMatch{}}
+		for _, m := range matches {
+			if m.Round != r {
+				continue
+			}
+			round.Matches = append(round.Matches, ExportMatch{
+				ID:          m.ID,
+				MatchNumber: m.MatchNumber,
+				ThirdPlace:  m.ThirdPlace,
+				Player1:     participant(m.Player1ID),
+				Player2:     participant(m.Player2ID),
+				ScoreA:      m.ScoreA,
+				ScoreB:      m.ScoreB,
+				WinnerID:    m.WinnerID,
+				Status:      m.Status,
+				ResultType:  m.ResultType,
+				BestOf:      m.BestOf,
+				ScheduledAt: m.ScheduledAt,
+				NextMatchID: m.NextMatchID,
+			})
+			for _, p := range []*string{m.Player1ID, m.Player2ID} {
+				if p != nil && !seen[*p] {
+					seen[*p] = true
+					exp.Participants = append(exp.Participants, ExportParticipant{ID: *p, Name: names[*p]})
+				}
+			}
+		}
+		exp.Rounds = append(exp.Rounds, round)
+	}
+	return exp
+}
+
+// exportCSV writes one row per match.
+func exportCSV(exp BracketExport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{
+		"round", "round_name", "match_number", "third_place",
+		"player1_id", "player1_name", "player2_id", "player2_name",
+		"score_a", "score_b", "winner_id", "winner_name", "status", "result_type", "best_of", "scheduled_at",
+	})
+
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	names := make(map[string]string, len(exp.Participants))
+	for _, p := range exp.Participants {
+		names[p.ID] = p.Name
+	}
+	for _, r := range exp.Rounds {
+		for _, m := range r.Matches {
+			var p1ID, p1Name, p2ID, p2Name, scheduledAt string
+			if m.Player1 != nil {
+				p1ID, p1Name = m.Player1.ID, m.Player1.Name
+			}
+			if m.Player2 != nil {
+				p2ID, p2Name = m.Player2.ID, m.Player2.Name
+			}
+			if m.ScheduledAt != nil {
+				scheduledAt = m.ScheduledAt.UTC().Format(time.RFC3339)
+			}
+			row := []string{
+				strconv.Itoa(r.Round), r.Name, strconv.Itoa(m.MatchNumber), strconv.FormatBool(m.ThirdPlace),
+				p1ID, p1Name, p2ID, p2Name,
+				deref(m.ScoreA), deref(m.ScoreB), deref(m.WinnerID), names[deref(m.WinnerID)],
+				m.Status, m.ResultType, strconv.Itoa(m.BestOf), scheduledAt,
+			}
+			for i := range row {
+				row[i] = csvCell(row[i])
+			}
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvCell keeps spreadsheets from running a cell as a formula: names and scores are user input, and
+// a leading =, +, -, @ (or tab / carriage return) is quoted with an apostrophe.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// SVG layout, in pixels.
+const (
+	svgBoxWidth  = 200
+	svgBoxHeight = 48
+	svgColGap    = 40
+	svgSlot      = 64 // Vertical space per first-round match
+	svgMargin    = 20
+	svgHeader    = 24 // Round names above each column
+)
+
+// exportSVG draws the bracket tree: one column per round, each match centred between its feeders.
+// The third-place match sits under the final.
+func exportSVG(exp BracketExport) []byte {
+	rounds := len(exp.Rounds)
+	firstRound := 1
+	if rounds > 0 {
+		firstRound = len(exp.Rounds[0].Matches)
+	}
+
+	// Top-left corner of a match box
+	pos := func(round, matchNumber int) (x, y int) {
+		span := svgSlot << (round - 1)
+		x = svgMargin + (round-1)*(svgBoxWidth+svgColGap)
+		y = svgMargin + svgHeader + (matchNumber-1)*span + (span-svgBoxHeight)/2
+		return x, y
+	}
+
+	width := 2*svgMargin + rounds*svgBoxWidth + max(rounds-1, 0)*svgColGap
+	height := 2*svgMargin + svgHeader + firstRound*svgSlot
+	thirdPlaceY := height - svgMargin + svgHeader
+	hasThirdPlace := false
+	for _, r := range exp.Rounds {
+		for _, m := range r.Matches {
+			if m.ThirdPlace {
+				hasThirdPlace = true
+			}
+		}
+	}
+	if hasThirdPlace {
+		height += svgHeader + svgBoxHeight + svgMargin
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	for _, r := range exp.Rounds {
+		x, _ := pos(r.Round, 1)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", x, svgMargin+12, html.EscapeString(r.Name))
+
+		for _, m := range r.Matches {
+			var y int
+			if m.ThirdPlace {
+				y = thirdPlaceY
+				fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">Third place</text>`+"\n", x, y-8)
+			} else {
+				_, y = pos(r.Round, m.MatchNumber)
+				// Connector to the next round: right edge to the left edge of the next match
+				if r.Round < rounds {
+					nx, ny := pos(r.Round+1, (m.MatchNumber+1)/2)
+					midX := x + svgBoxWidth + svgColGap/2
+					fmt.Fprintf(&b, `<path d="M%d %d H%d V%d H%d" fill="none" stroke="#999999"/>`+"\n",
+						x+svgBoxWidth, y+svgBoxHeight/2, midX, ny+svgBoxHeight/2, nx)
+				}
+			}
+			writeSVGMatch(&b, x, y, m)
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
+
+// writeSVGMatch draws one match box with a row per participant; the winner is bold.
+func writeSVGMatch(b *strings.Builder, x, y int, m ExportMatch) {
+	fmt.Fprintf(b, `<g id="match-%s">`+"\n", html.EscapeString(m.ID))
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#f5f5f5" stroke="#333333"/>`+"\n", x, y, svgBoxWidth, svgBoxHeight)
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#cccccc"/>`+"\n", x, y+svgBoxHeight/2, x+svgBoxWidth, y+svgBoxHeight/2)
+
+	rows := []struct {
+		player *ExportParticipant
+		score  *string
+	}{{m.Player1, m.ScoreA}, {m.Player2, m.ScoreB}}
+	for i, row := range rows {
+		name, weight := "TBD", "normal"
+		if row.player != nil {
+			name = row.player.Name
+			if name == "" {
+				name = row.player.ID
+			}
+			if m.WinnerID != nil && *m.WinnerID == row.player.ID {
+				weight = "bold"
+			}
+		} else if m.Status == "completed" {
+			name = "BYE"
+		}
+		textY := y + i*svgBoxHeight/2 + 16
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-weight="%s">%s</text>`+"\n", x+6, textY, weight, html.EscapeString(name))
+		if row.score != nil && *row.score != "" {
+			fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="end" font-weight="%s">%s</text>`+"\n", x+svgBoxWidth-6, textY, weight, html.EscapeString(*row.score))
+		}
+	}
+	b.WriteString("</g>\n")
+}
+
+// ExportBracket renders a bracket as JSON, CSV or SVG (?format=, default json) for use outside T-Hub.
+func (h *BracketHandler) ExportBracket(c echo.Context) error {
+	tournamentID := c.Param("tournamentId")
+	format := c.QueryParam("format")
+	if format == "" {
+		format = ExportJSON
+	}
+	if format != ExportJSON && format != ExportCSV && format != ExportSVG {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be json, csv or svg"})
+	}
+
+	matches, err := h.loadBracket(c.Request().Context(), tournamentID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "bracket load failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bracket"})
+	}
+	if len(matches) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Bracket not found"})
+	}
+
+	names, err := h.callerParticipantNames(c, tournamentID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "participant lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to fetch participants"})
+	}
+
+	exp := buildExport(tournamentID, matches, names, time.Now())
+	filename := fmt.Sprintf("bracket-%s.%s", tournamentID, format)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, filename))
+
+	switch format {
+	case ExportCSV:
+		body, err := exportCSV(exp)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to write CSV"})
+		}
+		return c.Blob(http.StatusOK, "text/csv; charset=utf-8", body)
+	case ExportSVG:
+		return c.Blob(http.StatusOK, "image/svg+xml", exportSVG(exp))
+	}
+	return c.JSON(http.StatusOK, exp)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportMatches is a finished 4 player bracket as returned by loadBracket (final first).
+func exportMatches() []Match {
+	p1, p2, p3, p4 := "p1", "p2", "p3", "p4"
+	final := "m-final"
+	s := func(v string) *string { return &v }
+	return []Match{
+		{ID: final, Round: 2, MatchNumber: 1, Player1ID: &p1, Player2ID: &p3, Status: "completed", ScoreA: s("2"), ScoreB: s("0"), WinnerID: &p1, ResultType: ResultNormal, BestOf: 3},
+		{ID: "m1", Round: 1, MatchNumber: 1, Player1ID: &p1, Player2ID: &p2, NextMatchID: &final, Status: "completed", ScoreA: s("1"), ScoreB: s("0"), WinnerID: &p1, ResultType: ResultNormal, BestOf: 1},
+		{ID: "m2", Round: 1, MatchNumber: 2, Player1ID: &p3, Player2ID: &p4, NextMatchID: &final, Status: "completed", ScoreA: s(""), ScoreB: s(""), WinnerID: &p3, ResultType: ResultNoShow, BestOf: 1},
+	}
+}
+
+var exportNames = map[string]string{"p1": "Alice", "p2": "Bob", "p3": "Carol & Co", "p4": "Dave"}
+
+func TestRoundName(t *testing.T) {
+	assert.Equal(t, "Final", roundName(4, 4))
+	assert.Equal(t, "Semi-finals", roundName(3, 4))
+	assert.Equal(t, "Quarter-finals", roundName(2, 4))
+	assert.Equal(t, "Round of 16", roundName(1, 4))
+}
+
+func TestBuildExport(t *testing.T) {
+	exp := buildExport("t1", exportMatches(), exportNames, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, exportSchemaVersion, exp.SchemaVersion)
+	assert.Len(t, exp.Participants, 4)
+	// First round first
+	assert.Len(t, exp.Rounds, 2)
+	assert.Equal(t, "Semi-finals", exp.Rounds[0].Name)
+	assert.Equal(t, "m1", exp.Rounds[0].Matches[0].ID)
+	assert.Equal(t, "Final", exp.Rounds[1].Name)
+	assert.Equal(t, "Alice", exp.Rounds[1].Matches[0].Player1.Name)
+	assert.Equal(t, "p1", *exp.Rounds[1].Matches[0].WinnerID)
+}
+
+func TestExportCSV(t *testing.T) {
+	body, err := exportCSV(buildExport("t1", exportMatches(), exportNames, time.Now()))
+	assert.NoError(t, err)
+
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 4) // header + 3 matches
+	assert.Equal(t, "round", records[0][0])
+	assert.Equal(t, []string{"1", "Semi-finals", "1", "false", "p1", "Alice", "p2", "Bob", "1", "0", "p1", "Alice", "completed", "normal", "1", ""}, records[1])
+}
+
+func TestExportCSV_EscapesFormulas(t *testing.T) {
+	names := map[string]string{"p1": "=HYPERLINK(\"http://evil\")", "p2": "@SUM(A1)", "p3": "+1", "p4": "-2"}
+	body, err := exportCSV(buildExport("t1", exportMatches(), names, time.Now()))
+	assert.NoError(t, err)
+
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", records[1][5])
+	assert.Equal(t, "'@SUM(A1)", records[1][7])
+	assert.Equal(t, "'+1", records[2][5])
+	assert.Equal(t, "'-2", records[2][7])
+	assert.Equal(t, "1", records[1][8], "plain scores are left alone")
+}
+
+func TestExportSVG_EscapesNames(t *testing.T) {
+	svg := string(exportSVG(buildExport("t1", exportMatches(), exportNames, time.Now())))
+
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `<g id="match-m-final">`)
+	assert.Contains(t, svg, "Carol &amp; Co")
+	assert.NotContains(t, svg, "Carol & Co")
+}
+
+func TestExportBracket_JSON(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tournaments/t1/participants", r.URL.Path)
+		json.NewEncoder(w).Encode([]Participant{{ID: "p1", Name: "Alice"}, {ID: "p2", Name: "Bob", Status: "disqualified"}})
+	}))
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, TournamentServiceURL: tsMock.URL}
+
+	p1, p2, one, zero := "p1", "p2", "1", "0"
+	var none *string
+	var noTime *time.Time
+	mockDB.ExpectQuery(`(?s)SELECT.*FROM matches`).
+		WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "tournament_id", "round", "match_number",
+			"player1_id", "player2_id", "next_match_id",
+			"status", "score_a", "score_b", "winner_id", "result_type", "best_of",
+			"scheduled_at", "deadline_at", "venue",
+			"third_place", "loser_next_match_id",
+		}).AddRow("m1", "t1", 1, 1, &p1, &p2, none, "completed", &one, &zero, &p1, "normal", 1, noTime, noTime, none, false, none))
+	mockDB.ExpectQuery(`(?s).*FROM match_games.*`).
+		WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"match_id", "game_number", "map", "score_a", "score_b", "winner_id"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/?format=json", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId")
+	c.SetParamValues("t1")
+
+	_ = h.ExportBracket(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var exp BracketExport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &exp))
+	assert.Equal(t, "Final", exp.Rounds[0].Name)
+	assert.Equal(t, "Bob", exp.Rounds[0].Matches[0].Player2.Name)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestExportBracket_InvalidFormat(t *testing.T) {
+	e := echo.New()
+	h := &BracketHandler{}
+
+	req := httptest.NewRequest(http.MethodGet, "/?format=pdf", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId")
+	c.SetParamValues("t1")
+
+	_ = h.ExportBracket(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
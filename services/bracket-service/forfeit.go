@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// How a match was decided. Everything but ResultNormal is decided without playing.
+const (
+	ResultNormal        = "normal"
+	ResultBye           = "bye"            // Opponent slot empty (odd field, double forfeit or disqualification upstream)
+	ResultForfeit       = "forfeit"        // The loser conceded or was disqualified
+	ResultNoShow        = "no_show"        // The loser did not show up
+	ResultDoubleForfeit = "double_forfeit" // Neither side showed up; nobody advances
+)
+
+var forfeitResultTypes = map[string]bool{
+	ResultForfeit:       true,
+	ResultNoShow:        true,
+	ResultDoubleForfeit: true,
+}
+
+// completeMatch closes a match that was decided without a score. winnerID is nil for double forfeits.
+func completeMatch(ctx context.Context, tx pgx.Tx, matchID string, winnerID *string, resultType string) error {
+	_, err := tx.Exec(ctx, `UPDATE matches SET winner_id = $1, status = 'completed', result_type = $2, completed_at = NOW() WHERE id = $3`,
+		winnerID, resultType, matchID)
+	return err
+}
+
+// advanceWinner moves the winner of a match into the next one and resolves the next match
+// if its other side can no longer be filled. A nil winner leaves the slot empty for good.
+func advanceWinner(ctx context.Context, tx pgx.Tx, nextMatchID *string, matchNum int, winnerID *string) error {
+	if nextMatchID == nil {
+		return nil
+	}
+
+	if winnerID != nil {
+		// Logic: If MatchNumber is Odd (1,3,5), winner goes to Player1 slot of next match.
+		//        If MatchNumber is Even (2,4,6), winner goes to Player2 slot.
+		updateField := "player1_id"
+		if matchNum%2 == 0 {
+			updateField = "player2_id"
+		}
+
+		query := fmt.Sprintf("UPDATE matches SET %s = $1 WHERE id = $2", updateField)
+		if _, err := tx.Exec(ctx, query, *winnerID, *nextMatchID); err != nil {
+			return err
+		}
+	}
+
+	return resolveWalkover(ctx, tx, *nextMatchID)
+}
+
+// advanceParticipants sends the winner on through next_match_id and the loser through
+// loser_next_match_id (semi-finals feeding the third-place match). Either may be nil.
+func advanceParticipants(ctx context.Context, tx pgx.Tx, nextMatchID, loserNextMatchID *string, matchNum int, winnerID, loserID *string) error {
+	if err := advanceWinner(ctx, tx, nextMatchID, matchNum, winnerID); err != nil {
+		return err
+	}
+	// Same slot rule for losers: the loser of an odd match is player1
+	return advanceWinner(ctx, tx, loserNextMatchID, matchNum, loserID)
+}
+
+// opponentOf returns the other player of a match, or nil if there is none.
+func opponentOf(playerID string, p1, p2 *string) *string {
+	if p1 != nil && *p1 == playerID {
+		return p2
+	}
+	if p2 != nil && *p2 == playerID {
+		return p1
+	}
+	return nil
+}
+
+// resolveWalkover completes a match once both feeder matches are done but a slot is still empty.
+// The remaining player advances with a bye; with no players left the empty slot propagates upward.
+func resolveWalkover(ctx context.Context, tx pgx.Tx, matchID string) error {
+	var p1, p2, nextMatchID, loserNextMatchID *string
+	var status string
+	var matchNum int
+	err := tx.QueryRow(ctx, `SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id = $1 FOR UPDATE`, matchID).
+		Scan(&p1, &p2, &status, &nextMatchID, &loserNextMatchID, &matchNum)
+	if err != nil {
+		return err
+	}
+	if status == "completed" || (p1 != nil && p2 != nil) {
+		return nil
+	}
+
+	pending, err := pendingFeeders(ctx, tx, matchID)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		// Still waiting for an opponent
+		return nil
+	}
+
+	winnerID := p1
+	if winnerID == nil {
+		winnerID = p2
+	}
+	if err := completeMatch(ctx, tx, matchID, winnerID, ResultBye); err != nil {
+		return err
+	}
+	// A bye has no loser, so the loser's slot downstream stays empty
+	return advanceParticipants(ctx, tx, nextMatchID, loserNextMatchID, matchNum, winnerID, nil)
+}
+
+// pendingFeeders counts the unfinished matches whose winner or loser still moves into matchID.
+func pendingFeeders(ctx context.Context, tx pgx.Tx, matchID string) (int, error) {
+	var pending int
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM matches WHERE $1 IN (next_match_id, loser_next_match_id) AND status <> 'completed'`, matchID).Scan(&pending)
+	return pending, err
+}
+
+// --- Forfeits ---
+
+type ForfeitRequest struct {
+	ResultType string `json:"result_type"` // forfeit, no_show (default) or double_forfeit
+	WinnerID   string `json:"winner_id"`   // The participant that showed up; empty for double forfeits
+	Override   bool   `json:"override"`    // Record a no-show before the deadline; organizer or co-organizer only
+}
+
+// ForfeitMatch records a match decided without playing. Organizer, co-organizer or referee only.
+// No-shows and double forfeits need the match deadline to have passed, unless the organizer overrides it.
+// A match nobody can reach any more (no players, nothing left feeding into it) takes a double forfeit
+// at any time, which passes the empty slot on like any other double forfeit.
+func (h *BracketHandler) ForfeitMatch(c echo.Context) error {
+	matchID := c.Param("match_id")
+
+	var req ForfeitRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.ResultType == "" {
+		req.ResultType = ResultNoShow
+	}
+	if !forfeitResultTypes[req.ResultType] {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "result_type must be forfeit, no_show or double_forfeit"})
+	}
+	if req.ResultType == ResultDoubleForfeit && req.WinnerID != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A double forfeit has no winner"})
+	}
+
+	ctx := c.Request().Context()
+
+	// Permissions are checked before the match is locked.
+	// Referees may record forfeits, but only the organizer may override the deadline.
+	tournamentID, err := h.matchTournament(ctx, matchID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+	allowed, err := h.canManageTournament(c, tournamentID, !req.Override)
+	if err != nil {
+		slog.ErrorContext(ctx, "permission lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify permissions"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer or a referee can forfeit matches"})
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var p1, p2, nextMatchID, loserNextMatchID *string
+	var matchNum int
+	var deadline *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT player1_id, player2_id, status, deadline_at, next_match_id, loser_next_match_id, match_number
+		FROM matches WHERE id = $1 FOR UPDATE`, matchID,
+	).Scan(&p1, &p2, &status, &deadline, &nextMatchID, &loserNextMatchID, &matchNum)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+
+	if status == "completed" {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is already completed"})
+	}
+	if p1 == nil || p2 == nil {
+		if p1 != nil || p2 != nil || req.ResultType != ResultDoubleForfeit {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Match is still waiting for its participants"})
+		}
+		pending, err := pendingFeeders(ctx, tx, matchID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check feeder matches"})
+		}
+		if pending > 0 {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Match is still waiting for its participants"})
+		}
+	} else if req.ResultType != ResultForfeit && !req.Override && !isForfeitable(status, deadline, time.Now()) {
+		// Nobody is a no-show before the deadline; a conceded or disqualified player forfeits any time
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is not past its deadline"})
+	}
+
+	var winnerID, loserID *string
+	if req.ResultType != ResultDoubleForfeit {
+		if !isPlayer(req.WinnerID, p1, p2) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "winner_id must be one of the match participants"})
+		}
+		winnerID = &req.WinnerID
+		loserID = opponentOf(req.WinnerID, p1, p2)
+	}
+
+	if err := completeMatch(ctx, tx, matchID, winnerID, req.ResultType); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to forfeit match"})
+	}
+	if err := advanceParticipants(ctx, tx, nextMatchID, loserNextMatchID, matchNum, winnerID, loserID); err != nil {
+		slog.ErrorContext(ctx, "advancing participants failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to advance winner"})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
+	}
+
+	h.publishForfeit(ctx, tournamentID, matchID, winnerID, req.ResultType)
+	h.notifyBracketUpdated(ctx, tournamentID, matchID, "forfeit")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "Match forfeited", "result_type": req.ResultType, "winner_id": winnerID})
+}
+
+func (h *BracketHandler) publishForfeit(ctx context.Context, tournamentID, matchID string, winnerID *string, resultType string) {
+	event, _ := json.Marshal(map[string]interface{}{
+		"event_type": "MatchForfeited",
+		"payload": map[string]interface{}{
+			"tournament_id": tournamentID,
+			"match_id":      matchID,
+			"winner_id":     winnerID,
+			"result_type":   resultType,
+		},
+		"timestamp": time.Now(),
+	})
+	_ = h.RMQ.Publish(ctx, "events.match.forfeited", string(event))
+}
+
+// --- Disqualifications ---
+
+type ParticipantDisqualifiedEvent struct {
+	EventType string `json:"event_type"`
+	Payload   struct {
+		TournamentID  string `json:"tournament_id"`
+		ParticipantID string `json:"participant_id"`
+	} `json:"payload"`
+}
+
+// HandleParticipantDisqualified forfeits every open match of a participant disqualified in tournament-service.
+// A match already against an opponent goes to the opponent; a slot still waiting for an opponent is
+// emptied so the opponent advances with a bye. Redelivered events find no open matches and do nothing.
+func (h *BracketHandler) HandleParticipantDisqualified(ctx context.Context, body []byte) error {
+	var event ParticipantDisqualifiedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	tournamentID := event.Payload.TournamentID
+	participantID := event.Payload.ParticipantID
+	if tournamentID == "" || participantID == "" {
+		return fmt.Errorf("%w: incomplete disqualification event", errMalformedEvent)
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, player1_id, player2_id, next_match_id, loser_next_match_id, match_number
+		FROM matches
+		WHERE tournament_id = $1 AND status <> 'completed' AND (player1_id = $2 OR player2_id = $2)
+		ORDER BY round ASC
+		FOR UPDATE`, tournamentID, participantID)
+	if err != nil {
+		return err
+	}
+
+	type openMatch struct {
+		ID               string
+		P1, P2           *string
+		NextMatchID      *string
+		LoserNextMatchID *string
+		MatchNumber      int
+	}
+	var open []openMatch
+	for rows.Next() {
+		var m openMatch
+		if err := rows.Scan(&m.ID, &m.P1, &m.P2, &m.NextMatchID, &m.LoserNextMatchID, &m.MatchNumber); err != nil {
+			rows.Close()
+			return err
+		}
+		open = append(open, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	forfeited := map[string]*string{} // match -> opponent that won by forfeit
+	for _, m := range open {
+		opponent := m.P2
+		slot := "player1_id"
+		if m.P2 != nil && *m.P2 == participantID {
+			opponent = m.P1
+			slot = "player2_id"
+		}
+
+		if opponent != nil {
+			if err := completeMatch(ctx, tx, m.ID, opponent, ResultForfeit); err != nil {
+				return err
+			}
+			// A disqualified loser does not play for third place
+			if err := advanceParticipants(ctx, tx, m.NextMatchID, m.LoserNextMatchID, m.MatchNumber, opponent, nil); err != nil {
+				return err
+			}
+			forfeited[m.ID] = opponent
+			continue
+		}
+
+		// No opponent yet: free the slot so whoever arrives advances with a bye
+		if _, err := tx.Exec(ctx, fmt.Sprintf("UPDATE matches SET %s = NULL WHERE id = $1", slot), m.ID); err != nil {
+			return err
+		}
+		if err := resolveWalkover(ctx, tx, m.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for matchID, winnerID := range forfeited {
+		h.publishForfeit(ctx, tournamentID, matchID, winnerID, ResultForfeit)
+	}
+	if len(open) > 0 {
+		h.notifyBracketUpdated(ctx, tournamentID, "", "disqualification")
+	}
+	slog.InfoContext(ctx, "participant disqualified", "participant_id", participantID, "tournament_id", tournamentID, "open_matches", len(open))
+	return nil
+}
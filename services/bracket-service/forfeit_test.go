@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var forfeitColumns = []string{"player1_id", "player2_id", "status", "deadline_at", "next_match_id", "loser_next_match_id", "match_number"}
+
+var resolveColumns = []string{"player1_id", "player2_id", "status", "next_match_id", "loser_next_match_id", "match_number"}
+
+func TestForfeitMatch_AdvancesWinner(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p1, p2, next := "p1", "p2", "m-final"
+	var noNext *string
+	deadline := time.Now().Add(-time.Hour)
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m2").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT player1_id, player2_id, status, deadline_at.*FOR UPDATE`).
+		WithArgs("m2").
+		WillReturnRows(pgxmock.NewRows(forfeitColumns).
+			AddRow(&p1, &p2, "scheduled", &deadline, &next, noNext, 2))
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(&p2, ResultNoShow, "m2").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// Even match number feeds player2 of the next match
+	mockDB.ExpectExec(`UPDATE matches SET player2_id`).
+		WithArgs("p2", "m-final").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// The other semi-final is still being played
+	mockDB.ExpectQuery(`SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id`).
+		WithArgs("m-final").
+		WillReturnRows(pgxmock.NewRows(resolveColumns).AddRow(nil, &p2, "scheduled", noNext, noNext, 1))
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM matches WHERE \$1 IN \(next_match_id, loser_next_match_id\)`).
+		WithArgs("m-final").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"winner_id": "p2"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m2")
+
+	_ = h.ForfeitMatch(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestForfeitMatch_DoubleForfeitGivesOpponentBye(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	// Referees may record forfeits
+	tsMock := newTournamentServiceMock("org-1", []map[string]string{{"user_id": "ref-1", "role": "referee"}})
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p1, p2, p3 := "p1", "p2", "p3"
+	final := "m-final"
+	var noWinner, noNext *string
+	deadline := time.Now().Add(-time.Hour)
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT player1_id, player2_id, status, deadline_at.*FOR UPDATE`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(forfeitColumns).
+			AddRow(&p1, &p2, "scheduled", &deadline, &final, noNext, 1))
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(noWinner, ResultDoubleForfeit, "m1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// p3 already won the other semi-final, so the final resolves as a bye
+	mockDB.ExpectQuery(`SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows(resolveColumns).AddRow(nil, &p3, "scheduled", noNext, noNext, 1))
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM matches WHERE \$1 IN \(next_match_id, loser_next_match_id\)`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(&p3, ResultBye, final).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"result_type": "double_forfeit"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "ref-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.ForfeitMatch(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestForfeitMatch_DoubleForfeitClosesEmptyMatch(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p3 := "p3"
+	final := "m-final"
+	var noPlayer, noWinner, noNext *string
+	var noDeadline *time.Time
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	// Nobody ever reached m1 and nothing feeds into it any more, so no deadline is needed
+	mockDB.ExpectQuery(`(?s)SELECT player1_id, player2_id, status, deadline_at.*FOR UPDATE`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(forfeitColumns).
+			AddRow(noPlayer, noPlayer, "scheduled", noDeadline, &final, noNext, 1))
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM matches WHERE \$1 IN \(next_match_id, loser_next_match_id\)`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(noWinner, ResultDoubleForfeit, "m1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// p3 is waiting in the final, which now resolves as a bye
+	mockDB.ExpectQuery(`SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows(resolveColumns).AddRow(nil, &p3, "scheduled", noNext, noNext, 1))
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM matches WHERE \$1 IN \(next_match_id, loser_next_match_id\)`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(&p3, ResultBye, final).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"result_type": "double_forfeit"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.ForfeitMatch(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestForfeitMatch_NoShowBeforeDeadline(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", []map[string]string{{"user_id": "ref-1", "role": "referee"}})
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p1, p2 := "p1", "p2"
+	var noNext *string
+	deadline := time.Now().Add(time.Hour)
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT player1_id, player2_id, status, deadline_at.*FOR UPDATE`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(forfeitColumns).AddRow(&p1, &p2, "scheduled", &deadline, noNext, noNext, 1))
+	mockDB.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"result_type": "no_show", "winner_id": "p1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "ref-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.ForfeitMatch(c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestForfeitMatch_OverrideIsOrganizerOnly(t *testing.T) {
+	e := echo.New()
+
+	tsMock := newTournamentServiceMock("org-1", []map[string]string{{"user_id": "ref-1", "role": "referee"}})
+	defer tsMock.Close()
+
+	p1, p2 := "p1", "p2"
+	var noNext *string
+	deadline := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		userID string
+		want   int
+	}{
+		{"ref-1", http.StatusForbidden},
+		{"org-1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+		assert.NoError(t, err)
+		h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+		mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+			WithArgs("m1").
+			WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+		if tc.want == http.StatusOK {
+			mockDB.ExpectBegin()
+			mockDB.ExpectQuery(`(?s)SELECT player1_id, player2_id, status, deadline_at.*FOR UPDATE`).
+				WithArgs("m1").
+				WillReturnRows(pgxmock.NewRows(forfeitColumns).AddRow(&p1, &p2, "scheduled", &deadline, noNext, noNext, 1))
+			mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+				WithArgs(&p1, ResultNoShow, "m1").
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			mockDB.ExpectCommit()
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"winner_id": "p1", "override": true}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-User-Id", tc.userID)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("match_id")
+		c.SetParamValues("m1")
+
+		_ = h.ForfeitMatch(c)
+
+		assert.Equal(t, tc.want, rec.Code, tc.userID)
+		assert.NoError(t, mockDB.ExpectationsWereMet(), tc.userID)
+		mockDB.Close()
+	}
+}
+
+func TestForfeitMatch_InvalidResultType(t *testing.T) {
+	e := echo.New()
+	h := &BracketHandler{}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"result_type": "bye", "winner_id": "p1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.ForfeitMatch(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleParticipantDisqualified(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+
+	cheater, opponent := "cheater", "p2"
+	semi, final := "m-semi", "m-final"
+	var noPlayer, noNext *string
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT id, player1_id, player2_id, next_match_id, loser_next_match_id, match_number.*FROM matches`).
+		WithArgs("t1", cheater).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "player1_id", "player2_id", "next_match_id", "loser_next_match_id", "match_number"}).
+			AddRow(semi, &cheater, &opponent, &final, noNext, 1))
+	// The opponent wins by forfeit and moves on
+	mockDB.ExpectExec(`UPDATE matches SET winner_id`).
+		WithArgs(&opponent, ResultForfeit, semi).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`UPDATE matches SET player1_id`).
+		WithArgs(opponent, final).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectQuery(`SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number FROM matches WHERE id`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows(resolveColumns).AddRow(&opponent, noPlayer, "scheduled", noNext, noNext, 1))
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM matches WHERE \$1 IN \(next_match_id, loser_next_match_id\)`).
+		WithArgs(final).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+	mockDB.ExpectCommit()
+
+	body := []byte(`{"event_type": "ParticipantDisqualified", "payload": {"tournament_id": "t1", "participant_id": "cheater"}}`)
+	assert.NoError(t, h.HandleParticipantDisqualified(context.Background(), body))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
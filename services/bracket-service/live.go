@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Routing key of the bracket change notifications every replica listens to.
+const BracketUpdatedKey = "events.bracket.updated"
+
+// Comment line sent on idle streams so proxies do not close them.
+const liveHeartbeat = 25 * time.Second
+
+// Pending messages per client; a client that falls further behind misses updates until it catches up.
+const liveClientBuffer = 16
+
+// LiveHub fans bracket updates out to the Server-Sent Events clients connected to this replica.
+type LiveHub struct {
+	mu      sync.Mutex
+	clients map[string]map[chan []byte]struct{} // tournament -> client channels
+}
+
+func NewLiveHub() *LiveHub {
+	return &LiveHub{clients: make(map[string]map[chan []byte]struct{})}
+}
+
+// subscribe registers a client for a tournament. The returned func must be called when the client leaves.
+func (lh *LiveHub) subscribe(tournamentID string) (<-chan []byte, func()) {
+	ch := make(chan []byte, liveClientBuffer)
+
+	lh.mu.Lock()
+	if lh.clients[tournamentID] == nil {
+		lh.clients[tournamentID] = make(map[chan []byte]struct{})
+	}
+	lh.clients[tournamentID][ch] = struct{}{}
+	lh.mu.Unlock()
+
+	return ch, func() {
+		lh.mu.Lock()
+		defer lh.mu.Unlock()
+		delete(lh.clients[tournamentID], ch)
+		if len(lh.clients[tournamentID]) == 0 {
+			delete(lh.clients, tournamentID)
+		}
+	}
+}
+
+func (lh *LiveHub) hasClients(tournamentID string) bool {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	return len(lh.clients[tournamentID]) > 0
+}
+
+// broadcast hands msg to every client of the tournament without blocking on slow ones.
+func (lh *LiveHub) broadcast(tournamentID string, msg []byte) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	for ch := range lh.clients[tournamentID] {
+		select {
+		case ch <- msg:
+		default:
+			slog.Warn("live client too slow, dropping update", "tournament_id", tournamentID)
+		}
+	}
+}
+
+// BracketUpdatedEvent tells every replica that a tournament's bracket changed.
+// Origin is the InstanceID of the publishing replica, which has already pushed the update to its own clients.
+type BracketUpdatedEvent struct {
+	EventType string `json:"event_type"`
+	Payload   struct {
+		TournamentID string `json:"tournament_id"`
+		MatchID      string `json:"match_id,omitempty"`
+		Reason       string `json:"reason"`
+		Origin       string `json:"origin"`
+	} `json:"payload"`
+}
+
+// notifyBracketUpdated pushes a change to this replica's live clients and tells the other replicas about it.
+// matchID is empty for changes spanning a whole round or bracket.
+func (h *BracketHandler) notifyBracketUpdated(ctx context.Context, tournamentID, matchID, reason string) {
+	event, _ := json.Marshal(map[string]interface{}{
+		"event_type": "BracketUpdated",
+		"payload": map[string]interface{}{
+			"tournament_id": tournamentID,
+			"match_id":      matchID,
+			"reason":        reason,
+			"origin":        h.InstanceID,
+		},
+		"timestamp": time.Now(),
+	})
+	_ = h.RMQ.Publish(ctx, BracketUpdatedKey, string(event))
+
+	go h.pushBracket(context.WithoutCancel(ctx), tournamentID, matchID, reason)
+}
+
+// HandleBracketUpdated pushes changes made on other replicas to this replica's live clients.
+func (h *BracketHandler) HandleBracketUpdated(ctx context.Context, body []byte) error {
+	var event BracketUpdatedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	if event.Payload.TournamentID == "" {
+		return fmt.Errorf("%w: bracket update without tournament_id", errMalformedEvent)
+	}
+	if event.Payload.Origin == h.InstanceID {
+		return nil
+	}
+	h.pushBracket(ctx, event.Payload.TournamentID, event.Payload.MatchID, event.Payload.Reason)
+	return nil
+}
+
+// pushBracket sends the current bracket to the tournament's live clients, if there are any.
+func (h *BracketHandler) pushBracket(ctx context.Context, tournamentID, matchID, reason string) {
+	if h.Live == nil || !h.Live.hasClients(tournamentID) {
+		return
+	}
+
+	msg, err := h.bracketMessage(ctx, tournamentID, matchID, reason, "", "")
+	if err != nil {
+		slog.ErrorContext(ctx, "live update failed", "tournament_id", tournamentID, "error", err)
+		return
+	}
+	h.Live.broadcast(tournamentID, msg)
+}
+
+// bracketMessage renders the bracket as one SSE "bracket" event with the same match shape as GetBracket.
+func (h *BracketHandler) bracketMessage(ctx context.Context, tournamentID, matchID, reason, userID, userRoles string) ([]byte, error) {
+	matches, err := h.loadBracket(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	names, err := h.cachedParticipantNames(ctx, tournamentID, userID, userRoles)
+	if err != nil {
+		slog.ErrorContext(ctx, "participant name lookup failed", "error", err)
+	}
+	applyNames(matches, names)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"tournament_id": tournamentID,
+		"match_id":      matchID,
+		"reason":        reason,
+		"matches":       matches,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("event: bracket\ndata: %s\n\n", data)), nil
+}
+
+// StreamBracket is a Server-Sent Events stream of a tournament's bracket. It starts with the current
+// bracket (reason "snapshot") and sends the full bracket again after every change.
+func (h *BracketHandler) StreamBracket(c echo.Context) error {
+	tournamentID := c.Param("tournamentId")
+	if h.Live == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Live updates are not available"})
+	}
+
+	// Subscribe before reading the snapshot so no change falls in between
+	updates, unsubscribe := h.Live.subscribe(tournamentID)
+	defer unsubscribe()
+
+	ctx := c.Request().Context()
+	snapshot, err := h.bracketMessage(ctx, tournamentID, "", "snapshot",
+		c.Request().Header.Get("X-User-Id"), c.Request().Header.Get("X-User-Roles"))
+	if err != nil {
+		slog.ErrorContext(ctx, "bracket load failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bracket"})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // Disable buffering in nginx ingress
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := res.Write(snapshot); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-updates:
+			if _, err := res.Write(msg); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := res.Write([]byte(": ping\n\n")); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
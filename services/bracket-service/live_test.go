@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveHub_BroadcastPerTournament(t *testing.T) {
+	lh := NewLiveHub()
+	t1, leave := lh.subscribe("t1")
+	t2, leave2 := lh.subscribe("t2")
+	defer leave2()
+
+	lh.broadcast("t1", []byte("update"))
+
+	assert.Equal(t, "update", string(<-t1))
+	assert.Empty(t, t2)
+
+	leave()
+	assert.False(t, lh.hasClients("t1"))
+	assert.True(t, lh.hasClients("t2"))
+}
+
+func TestLiveHub_SlowClientDoesNotBlock(t *testing.T) {
+	lh := NewLiveHub()
+	_, leave := lh.subscribe("t1")
+	defer leave()
+
+	for i := 0; i < liveClientBuffer+5; i++ {
+		lh.broadcast("t1", []byte("update"))
+	}
+}
+
+func TestHandleBracketUpdated_IgnoresOwnEvents(t *testing.T) {
+	// No DB: an event from this replica must not trigger a reload
+	h := &BracketHandler{Live: NewLiveHub(), InstanceID: "replica-a"}
+	_, leave := h.Live.subscribe("t1")
+	defer leave()
+
+	body := `{"event_type": "BracketUpdated", "payload": {"tournament_id": "t1", "reason": "result", "origin": "replica-a"}}`
+	assert.NoError(t, h.HandleBracketUpdated(context.Background(), []byte(body)))
+	assert.Error(t, h.HandleBracketUpdated(context.Background(), []byte(`{"payload": {}}`)))
+}
+
+// readEvent reads one SSE event (up to the blank line), skipping heartbeats.
+func readEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamBracket_SnapshotAndUpdates(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Participant{{ID: "p1", Name: "Alice"}, {ID: "p2", Name: "Bob"}})
+	}))
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, TournamentServiceURL: tsMock.URL, Names: NewNameCache(time.Minute), Live: NewLiveHub(), InstanceID: "replica-a"}
+
+	p1, p2 := "p1", "p2"
+	var none *string
+	var noTime *time.Time
+	columns := []string{
+		"id", "tournament_id", "round", "match_number",
+		"player1_id", "player2_id", "next_match_id",
+		"status", "score_a", "score_b", "winner_id", "result_type", "best_of",
+		"scheduled_at", "deadline_at", "venue",
+		"third_place", "loser_next_match_id",
+	}
+	one, zero := "1", "0"
+	mockDB.ExpectQuery(`(?s)SELECT.*FROM matches`).WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows(columns).AddRow("m1", "t1", 1, 1, &p1, &p2, none, "scheduled", none, none, none, "normal", 1, noTime, noTime, none, false, none))
+	mockDB.ExpectQuery(`(?s).*FROM match_games.*`).WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"match_id", "game_number", "map", "score_a", "score_b", "winner_id"}))
+	mockDB.ExpectQuery(`(?s)SELECT.*FROM matches`).WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows(columns).AddRow("m1", "t1", 1, 1, &p1, &p2, none, "completed", &one, &zero, &p1, "normal", 1, noTime, noTime, none, false, none))
+	mockDB.ExpectQuery(`(?s).*FROM match_games.*`).WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"match_id", "game_number", "map", "score_a", "score_b", "winner_id"}))
+
+	e := echo.New()
+	e.GET("/brackets/:tournamentId/stream", h.StreamBracket)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/brackets/t1/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+	event, data := readEvent(t, r)
+	assert.Equal(t, "bracket", event)
+	assert.Contains(t, data, `"reason":"snapshot"`)
+	assert.Contains(t, data, `"player1_name":"Alice"`)
+
+	// A result reported on another replica
+	body := `{"event_type": "BracketUpdated", "payload": {"tournament_id": "t1", "match_id": "m1", "reason": "result", "origin": "replica-b"}}`
+	require.NoError(t, h.HandleBracketUpdated(context.Background(), []byte(body)))
+
+	event, data = readEvent(t, r)
+	assert.Equal(t, "bracket", event)
+	assert.Contains(t, data, `"reason":"result"`)
+	assert.Contains(t, data, `"winner_id":"p1"`)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
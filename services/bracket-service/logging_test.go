@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogging_PassesRequestIDOn(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var forwarded string
+	tournaments := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(echo.HeaderXRequestID)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer tournaments.Close()
+	h := &BracketHandler{TournamentServiceURL: tournaments.URL}
+
+	e := echo.New()
+	e.Use(RequestIDMiddleware(), RequestLogMiddleware(), IdentityMiddleware(nil))
+	e.GET("/brackets/:tournamentId/placements", func(c echo.Context) error {
+		var out map[string]interface{}
+		status, err := h.getTournamentJSON(c.Request().Context(), "/tournaments/"+c.Param("tournamentId"), "user-1", "", &out)
+		if err != nil {
+			return err
+		}
+		return c.NoContent(status)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/brackets/t1/placements", nil)
+	req.Header.Set("X-User-Id", "user-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, id, 32, "a request without an ID gets one")
+	assert.Equal(t, id, forwarded)
+
+	var access map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &access))
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "bracket-service", access["service"])
+	assert.Equal(t, "/brackets/:tournamentId/placements", access["route"])
+	assert.Equal(t, float64(http.StatusNotFound), access["status"])
+	assert.Equal(t, id, access["request_id"])
+	assert.Equal(t, "user-1", access["user_id"])
+	assert.Contains(t, access, "latency_ms")
+}
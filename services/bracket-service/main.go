@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -10,6 +11,16 @@ import (
 )
 
 func main() {
+	// JSON logs; the level comes from LOG_LEVEL
+	InitLogging()
+
+	// 0. Tracing, continuing the traces api-gateway starts
+	shutdownTracing, err := InitTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Tracing Error: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// 1. Database
 	dbPool, err := ConnectDB()
 	if err != nil {
@@ -32,12 +43,26 @@ func main() {
         // Fallback for local dev or hardcoded if prefered for MVP
         tournamentServiceURL = "http://tournament-service.t-hub-dev.svc.cluster.local:8080"
     }
+	// Team rosters, to tell which side a player of a team tournament is on
+	teamServiceURL := os.Getenv("TEAM_SERVICE_URL")
+	if teamServiceURL == "" {
+		teamServiceURL = "http://team-service.t-hub-dev.svc.cluster.local:8080"
+	}
+
+	// Identity of callers, as asserted by api-gateway
+	assertions, err := IdentityVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Identity assertion config error: %v", err)
+	}
 
 	// 4. Echo Setup
 	e := echo.New()
-	e.Use(middleware.Logger())
+	e.Use(TracingMiddleware())
+	e.Use(RequestIDMiddleware())
+	e.Use(RequestLogMiddleware())
 	e.Use(middleware.Recover())
 	e.Use(MetricsMiddleware) // Add metrics middleware
+	e.Use(IdentityMiddleware(assertions))
 
 	// 5. Routes
 	e.GET("/health", func(c echo.Context) error {
@@ -46,11 +71,55 @@ func main() {
 	e.GET("/metrics", MetricsHandler()) // Add metrics endpoint
 
     // Handler Initialization (We will create this next)
-    h := &BracketHandler{DB: dbPool, RMQ: rmq, TournamentServiceURL: tournamentServiceURL}
+    instanceID, _ := os.Hostname() // Pod name in Kubernetes
+    h := &BracketHandler{
+        DB:                   dbPool,
+        RMQ:                  rmq,
+        TournamentServiceURL: tournamentServiceURL,
+        TeamServiceURL:       teamServiceURL,
+        Names:                NewNameCache(participantNameTTL),
+        Live:                 NewLiveHub(),
+        InstanceID:           instanceID,
+    }
     e.POST("/brackets/generate", h.GenerateBracket)
     e.GET("/brackets/:tournamentId", h.GetBracket)
+	e.GET("/brackets/:tournamentId/placements", h.GetPlacements)
+	e.GET("/brackets/:tournamentId/export", h.ExportBracket)
+	e.GET("/brackets/:tournamentId/stream", h.StreamBracket)
 	e.POST("/brackets/matches/:match_id/result", h.UpdateMatchResult)
 
+	// Scheduling
+	e.PUT("/brackets/:tournamentId/rounds/:round/schedule", h.ScheduleRound)
+	e.GET("/brackets/matches/:match_id/reschedule", h.ListRescheduleProposals)
+	e.POST("/brackets/matches/:match_id/reschedule", h.ProposeReschedule)
+	e.POST("/brackets/matches/:match_id/reschedule/:proposal_id/accept", h.AcceptReschedule)
+	e.POST("/brackets/matches/:match_id/reschedule/:proposal_id/reject", h.RejectReschedule)
+	e.POST("/brackets/matches/:match_id/forfeit", h.ForfeitMatch)
+
+	// Best-of-N series
+	e.PUT("/brackets/:tournamentId/rounds/:round/format", h.SetRoundFormat)
+	e.POST("/brackets/matches/:match_id/games", h.RecordGame)
+
+	// Ratings
+	e.GET("/ratings/:game/leaderboard", h.GetLeaderboard)
+	e.GET("/ratings/:game/participants/:participantId", h.GetParticipantRating)
+	e.POST("/ratings/recompute", h.RecomputeRatings)
+
+	// 6. Events from other services
+	if err := rmq.Subscribe("bracket-service.participant_disqualified", "events.tournament.participant_disqualified", h.HandleParticipantDisqualified); err != nil {
+		log.Fatalf("RabbitMQ Subscribe Error: %v", err)
+	}
+	if err := rmq.Subscribe("bracket-service.ratings", "events.match.completed", h.HandleMatchCompleted); err != nil {
+		log.Fatalf("RabbitMQ Subscribe Error: %v", err)
+	}
+	if err := rmq.Subscribe("bracket-service.standings", "events.match.*", h.HandleMatchDecided); err != nil {
+		log.Fatalf("RabbitMQ Subscribe Error: %v", err)
+	}
+	// Every replica hears about every change so its live clients stay current
+	if err := rmq.SubscribeBroadcast(BracketUpdatedKey, h.HandleBracketUpdated); err != nil {
+		log.Fatalf("RabbitMQ Subscribe Error: %v", err)
+	}
+
 	port := ":8080"
 	e.Logger.Fatal(e.Start(port))
 }
\ No newline at end of file
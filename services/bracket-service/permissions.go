@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Staff roles as granted in tournament-service.
+const (
+	RoleCoOrganizer = "co_organizer"
+	RoleReferee     = "referee"
+)
+
+// Upper bound for permission lookups against tournament-service.
+const tournamentLookupTimeout = 5 * time.Second
+
+// canManageTournament asks tournament-service whether the caller may manage the tournament:
+// SuperAdmin, the organizer, or a co-organizer. With allowReferee, referees pass as well.
+// tournament-service stays the single source of truth for ownership and staff roles.
+func (h *BracketHandler) canManageTournament(c echo.Context, tournamentID string, allowReferee bool) (bool, error) {
+	userID := c.Request().Header.Get("X-User-Id")
+	userRoles := c.Request().Header.Get("X-User-Roles")
+	if userID == "" {
+		return false, nil
+	}
+
+	// 1. Platform admins
+	if hasRole(userRoles, "SuperAdmin") {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), tournamentLookupTimeout)
+	defer cancel()
+
+	// 2. Organizer. Private tournaments answer 403 to anyone else, which just means "not the organizer".
+	var t struct {
+		OrganizerID string `json:"organizer_id"`
+	}
+	status, err := h.getTournamentJSON(ctx, fmt.Sprintf("/tournaments/%s", tournamentID), userID, userRoles, &t)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusOK && t.OrganizerID == userID {
+		return true, nil
+	}
+
+	// 3. Per-tournament staff
+	var staff []struct {
+		UserID string `json:"user_id"`
+		Role   string `json:"role"`
+	}
+	status, err = h.getTournamentJSON(ctx, fmt.Sprintf("/tournaments/%s/staff", tournamentID), userID, userRoles, &staff)
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("staff lookup returned %d", status)
+	}
+	for _, s := range staff {
+		if s.UserID != userID {
+			continue
+		}
+		if s.Role == RoleCoOrganizer || (allowReferee && s.Role == RoleReferee) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// matchTournament returns the tournament a match belongs to. It is read without a lock, so the
+// permission check against tournament-service happens before a transaction locks the match.
+func (h *BracketHandler) matchTournament(ctx context.Context, matchID string) (string, error) {
+	var tournamentID string
+	err := h.DB.QueryRow(ctx, `SELECT tournament_id FROM matches WHERE id = $1`, matchID).Scan(&tournamentID)
+	return tournamentID, err
+}
+
+// getTournamentJSON GETs a tournament-service path on behalf of the caller and decodes 200 responses into out.
+func (h *BracketHandler) getTournamentJSON(ctx context.Context, path, userID, userRoles string, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.TournamentServiceURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-User-Id", userID)
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(echo.HeaderXRequestID, id)
+	}
+	if userRoles != "" {
+		req.Header.Set("X-User-Roles", userRoles)
+	}
+	// tournament-service only believes the identity headers with the gateway's assertion
+	if assertion := assertionFromContext(ctx); assertion != "" {
+		req.Header.Set(HeaderIdentityAssertion, assertion)
+	}
+
+	resp, err := tracedClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, err
+		}
+	}
+	return resp.StatusCode, nil
+}
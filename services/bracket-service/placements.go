@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Routing key of the final standings, published once the deciding matches of a bracket are done.
+const BracketCompletedKey = "events.bracket.completed"
+
+// Placement is the final position of a participant. Participants knocked out in the same
+// round share a placement range, e.g. both quarter-final losers of an 8 player bracket are 5th-8th.
+// participant_id/placement match the standings accepted by tournament-service payouts.
+type Placement struct {
+	ParticipantID string `json:"participant_id"`
+	Placement     int    `json:"placement"`    // Best place of the range
+	PlacementTo   int    `json:"placement_to"` // Worst place of the range (same as placement when untied)
+}
+
+// placementNode is the part of a match needed to rank participants.
+type placementNode struct {
+	Round      int
+	P1, P2     *string
+	WinnerID   *string
+	Status     string
+	ThirdPlace bool
+}
+
+// ComputePlacements ranks the participants of a single-elimination bracket from its match tree.
+// Losers of round r (counting the final as the last round) place 2^(rounds-r)+1 to 2^(rounds-r+1).
+// A finished third-place match splits 3rd and 4th. complete is false while deciding matches are open;
+// participants still in the running are left out until then.
+func ComputePlacements(matches []placementNode) (placements []Placement, complete bool) {
+	rounds := 0
+	for _, m := range matches {
+		if m.Round > rounds {
+			rounds = m.Round
+		}
+	}
+
+	byID := make(map[string]*Placement)
+	place := func(id string, from, to int) {
+		byID[id] = &Placement{ParticipantID: id, Placement: from, PlacementTo: to}
+	}
+
+	complete = rounds > 0
+	var thirdPlace *placementNode
+	for i := range matches {
+		m := &matches[i]
+		if m.ThirdPlace {
+			thirdPlace = m
+			continue
+		}
+		if m.Status != "completed" {
+			if m.Round == rounds {
+				complete = false
+			}
+			continue
+		}
+
+		from := 1<<(rounds-m.Round) + 1
+		to := 1 << (rounds - m.Round + 1)
+		for _, p := range []*string{m.P1, m.P2} {
+			if p != nil && (m.WinnerID == nil || *p != *m.WinnerID) {
+				place(*p, from, to)
+			}
+		}
+		if m.Round == rounds && m.WinnerID != nil {
+			place(*m.WinnerID, 1, 1)
+		}
+	}
+
+	if thirdPlace != nil {
+		if thirdPlace.Status != "completed" {
+			complete = false
+		} else if thirdPlace.WinnerID != nil {
+			place(*thirdPlace.WinnerID, 3, 3)
+			if loser := opponentOf(*thirdPlace.WinnerID, thirdPlace.P1, thirdPlace.P2); loser != nil {
+				place(*loser, 4, 4)
+			}
+		}
+	}
+
+	placements = make([]Placement, 0, len(byID))
+	for _, p := range byID {
+		placements = append(placements, *p)
+	}
+	sort.Slice(placements, func(i, j int) bool {
+		if placements[i].Placement != placements[j].Placement {
+			return placements[i].Placement < placements[j].Placement
+		}
+		return placements[i].ParticipantID < placements[j].ParticipantID
+	})
+	return placements, complete
+}
+
+// loadPlacementNodes reads the matches of a tournament needed by ComputePlacements.
+func (h *BracketHandler) loadPlacementNodes(ctx context.Context, tournamentID string) ([]placementNode, error) {
+	rows, err := h.DB.Query(ctx, `
+		SELECT round, player1_id, player2_id, winner_id, status, third_place
+		FROM matches
+		WHERE tournament_id = $1`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []placementNode
+	for rows.Next() {
+		var m placementNode
+		if err := rows.Scan(&m.Round, &m.P1, &m.P2, &m.WinnerID, &m.Status, &m.ThirdPlace); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GetPlacements returns the final (or so far known) placements of a tournament.
+func (h *BracketHandler) GetPlacements(c echo.Context) error {
+	tournamentID := c.Param("tournamentId")
+
+	matches, err := h.loadPlacementNodes(c.Request().Context(), tournamentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bracket"})
+	}
+	if len(matches) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Bracket not found"})
+	}
+
+	placements, complete := ComputePlacements(matches)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tournament_id": tournamentID,
+		"complete":      complete,
+		"placements":    placements,
+	})
+}
+
+type MatchDecidedEvent struct {
+	EventType string `json:"event_type"`
+	Payload   struct {
+		TournamentID string `json:"tournament_id"`
+	} `json:"payload"`
+}
+
+// HandleMatchDecided publishes the final standings when a completed or forfeited match finishes the bracket.
+// A corrected final result publishes the standings again; consumers replace what they stored before.
+func (h *BracketHandler) HandleMatchDecided(ctx context.Context, body []byte) error {
+	var event MatchDecidedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	if event.EventType != "MatchCompleted" && event.EventType != "MatchForfeited" {
+		return nil
+	}
+	tournamentID := event.Payload.TournamentID
+	if tournamentID == "" {
+		return fmt.Errorf("%w: match event without tournament_id", errMalformedEvent)
+	}
+
+	matches, err := h.loadPlacementNodes(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	placements, complete := ComputePlacements(matches)
+	if !complete {
+		return nil
+	}
+
+	// Brackets generated before game and participant type were recorded publish them empty
+	var game, participantType string
+	err = h.DB.QueryRow(ctx, `SELECT game, participant_type FROM brackets WHERE tournament_id = $1`, tournamentID).
+		Scan(&game, &participantType)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	completed, _ := json.Marshal(map[string]interface{}{
+		"event_type": "BracketCompleted",
+		"payload": map[string]interface{}{
+			"tournament_id":    tournamentID,
+			"game":             game,
+			"participant_type": participantType,
+			"placements":       placements,
+		},
+		"timestamp": time.Now(),
+	})
+	return h.RMQ.Publish(ctx, BracketCompletedKey, string(completed))
+}
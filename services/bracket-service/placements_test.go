@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func sp(s string) *string { return &s }
+
+func TestComputePlacements_EightPlayers(t *testing.T) {
+	matches := []placementNode{
+		// Quarter-finals
+		{Round: 1, P1: sp("a"), P2: sp("b"), WinnerID: sp("a"), Status: "completed"},
+		{Round: 1, P1: sp("c"), P2: sp("d"), WinnerID: sp("c"), Status: "completed"},
+		{Round: 1, P1: sp("e"), P2: sp("f"), WinnerID: sp("e"), Status: "completed"},
+		{Round: 1, P1: sp("g"), P2: sp("h"), WinnerID: sp("g"), Status: "completed"},
+		// Semi-finals
+		{Round: 2, P1: sp("a"), P2: sp("c"), WinnerID: sp("a"), Status: "completed"},
+		{Round: 2, P1: sp("e"), P2: sp("g"), WinnerID: sp("g"), Status: "completed"},
+		// Final
+		{Round: 3, P1: sp("a"), P2: sp("g"), WinnerID: sp("g"), Status: "completed"},
+	}
+
+	placements, complete := ComputePlacements(matches)
+
+	assert.True(t, complete)
+	assert.Equal(t, []Placement{
+		{"g", 1, 1}, {"a", 2, 2},
+		{"c", 3, 4}, {"e", 3, 4},
+		{"b", 5, 8}, {"d", 5, 8}, {"f", 5, 8}, {"h", 5, 8},
+	}, placements)
+}
+
+func TestComputePlacements_ThirdPlaceMatch(t *testing.T) {
+	matches := []placementNode{
+		{Round: 1, P1: sp("a"), P2: sp("b"), WinnerID: sp("a"), Status: "completed"},
+		{Round: 1, P1: sp("c"), P2: sp("d"), WinnerID: sp("d"), Status: "completed"},
+		{Round: 2, P1: sp("a"), P2: sp("d"), WinnerID: sp("a"), Status: "completed"},
+		{Round: 2, P1: sp("b"), P2: sp("c"), Status: "scheduled", ThirdPlace: true},
+	}
+
+	// Third-place match still open
+	placements, complete := ComputePlacements(matches)
+	assert.False(t, complete)
+	assert.Equal(t, Placement{"b", 3, 4}, placements[2])
+
+	matches[3].Status = "completed"
+	matches[3].WinnerID = sp("c")
+	placements, complete = ComputePlacements(matches)
+	assert.True(t, complete)
+	assert.Equal(t, []Placement{{"a", 1, 1}, {"d", 2, 2}, {"c", 3, 3}, {"b", 4, 4}}, placements)
+}
+
+func TestComputePlacements_DoubleForfeit(t *testing.T) {
+	matches := []placementNode{
+		{Round: 1, P1: sp("a"), P2: sp("b"), Status: "completed"},
+		{Round: 1, P1: sp("c"), P2: sp("d"), WinnerID: sp("c"), Status: "completed"},
+		{Round: 2, P2: sp("c"), WinnerID: sp("c"), Status: "completed"},
+	}
+
+	placements, complete := ComputePlacements(matches)
+
+	assert.True(t, complete)
+	// Both no-shows share the semi-final range
+	assert.Equal(t, []Placement{{"c", 1, 1}, {"a", 3, 4}, {"b", 3, 4}, {"d", 3, 4}}, placements)
+}
+
+func TestGetPlacements_NotFound(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB}
+
+	mockDB.ExpectQuery(`(?s)SELECT round, player1_id.*FROM matches`).
+		WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"round", "player1_id", "player2_id", "winner_id", "status", "third_place"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId")
+	c.SetParamValues("t1")
+
+	_ = h.GetPlacements(c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// recordingRabbitMQ keeps published events for inspection.
+type recordingRabbitMQ struct {
+	keys   []string
+	bodies []string
+}
+
+func (r *recordingRabbitMQ) Publish(ctx context.Context, key, body string) error {
+	r.keys = append(r.keys, key)
+	r.bodies = append(r.bodies, body)
+	return nil
+}
+
+func placementRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"round", "player1_id", "player2_id", "winner_id", "status", "third_place"})
+}
+
+func TestHandleMatchDecided_PublishesFinalStandings(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	rmq := &recordingRabbitMQ{}
+	h := &BracketHandler{DB: mockDB, RMQ: rmq}
+
+	mockDB.ExpectQuery(`(?s)SELECT round, player1_id.*FROM matches`).
+		WithArgs("t1").
+		WillReturnRows(placementRows().
+			AddRow(1, sp("a"), sp("b"), sp("a"), "completed", false).
+			AddRow(1, sp("c"), sp("d"), sp("d"), "completed", false).
+			AddRow(2, sp("a"), sp("d"), sp("d"), "completed", false))
+	mockDB.ExpectQuery(`SELECT game, participant_type FROM brackets`).
+		WithArgs("t1").
+		WillReturnRows(pgxmock.NewRows([]string{"game", "participant_type"}).AddRow("chess", "individual"))
+
+	body := `{"event_type": "MatchCompleted", "payload": {"tournament_id": "t1", "match_id": "final"}}`
+	assert.NoError(t, h.HandleMatchDecided(context.Background(), []byte(body)))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+
+	assert.Equal(t, []string{BracketCompletedKey}, rmq.keys)
+	var event struct {
+		Payload struct {
+			Game       string      `json:"game"`
+			Placements []Placement `json:"placements"`
+		} `json:"payload"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(rmq.bodies[0]), &event))
+	assert.Equal(t, "chess", event.Payload.Game)
+	assert.Equal(t, []Placement{{"d", 1, 1}, {"a", 2, 2}, {"b", 3, 4}, {"c", 3, 4}}, event.Payload.Placements)
+}
+
+func TestHandleMatchDecided_BracketStillRunning(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	rmq := &recordingRabbitMQ{}
+	h := &BracketHandler{DB: mockDB, RMQ: rmq}
+
+	mockDB.ExpectQuery(`(?s)SELECT round, player1_id.*FROM matches`).
+		WithArgs("t1").
+		WillReturnRows(placementRows().
+			AddRow(1, sp("a"), sp("b"), sp("a"), "completed", false).
+			AddRow(1, sp("c"), sp("d"), (*string)(nil), "scheduled", false).
+			AddRow(2, sp("a"), (*string)(nil), (*string)(nil), "scheduled", false))
+
+	body := `{"event_type": "MatchForfeited", "payload": {"tournament_id": "t1", "match_id": "m1"}}`
+	assert.NoError(t, h.HandleMatchDecided(context.Background(), []byte(body)))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	assert.Empty(t, rmq.keys)
+
+	// Scheduling events share the routing key pattern but are ignored
+	body = `{"event_type": "RoundScheduled", "payload": {"tournament_id": "t1"}}`
+	assert.NoError(t, h.HandleMatchDecided(context.Background(), []byte(body)))
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
 )
 
 const (
@@ -15,8 +17,16 @@ const (
 	ExchangeType = "topic"
 )
 
+// requeueDelay is how long a consumer waits before handing a failed message back to the queue,
+// so an outage of the database or another service does not turn into a busy loop.
+var requeueDelay = time.Second
+
+// errMalformedEvent marks events a handler cannot parse. They would fail the same way on every
+// delivery, so they are dropped instead of requeued.
+var errMalformedEvent = errors.New("malformed event")
+
 type EventPublisher interface {
-	Publish(routingKey string, body string) error
+	Publish(ctx context.Context, routingKey string, body string) error
 }
 
 type Service struct {
@@ -45,16 +55,104 @@ func ConnectRabbitMQ() (*Service, error) {
 		return nil, err
 	}
 
-	log.Println("Connected to RabbitMQ")
+	slog.Info("connected to RabbitMQ")
 	return &Service{Conn: conn, Channel: ch}, nil
 }
 
-func (s *Service) Publish(routingKey string, body string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Publish sends a message to the exchange. The trace of ctx travels along in the message headers;
+// ctx being canceled does not stop the publish.
+func (s *Service) Publish(ctx context.Context, routingKey string, body string) error {
+	headers := amqp.Table{}
+	ctx, span := startPublishSpan(ctx, routingKey, headers)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
-	return s.Channel.PublishWithContext(ctx, ExchangeName, routingKey, false, false, amqp.Publishing{
+	err := s.Channel.PublishWithContext(ctx, ExchangeName, routingKey, false, false, amqp.Publishing{
 		ContentType: "application/json",
+		Headers:     headers,
 		Body:        []byte(body),
 	})
-}
\ No newline at end of file
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// handleDelivery runs handler for msg in the trace it was published in.
+// Messages are acked when handler succeeds. Malformed events are logged and dropped; any other failure
+// is requeued after requeueDelay so the event is retried once whatever it depends on is back.
+func handleDelivery(msg amqp.Delivery, handler func(ctx context.Context, body []byte) error) {
+	ctx, span := startConsumeSpan(msg)
+	defer span.End()
+
+	if err := handler(ctx, msg.Body); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, errMalformedEvent) {
+			slog.ErrorContext(ctx, "dropping malformed event", "routing_key", msg.RoutingKey, "error", err)
+			_ = msg.Nack(false, false)
+			return
+		}
+		slog.ErrorContext(ctx, "failed to handle event, requeueing", "routing_key", msg.RoutingKey, "error", err)
+		time.Sleep(requeueDelay)
+		_ = msg.Nack(false, true)
+		return
+	}
+	_ = msg.Ack(false)
+}
+
+// Subscribe binds a durable queue to routingKey and hands every message to handler in the background.
+func (s *Service) Subscribe(queue string, routingKey string, handler func(ctx context.Context, body []byte) error) error {
+	q, err := s.Channel.QueueDeclare(queue, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Channel.QueueBind(q.Name, routingKey, ExchangeName, false, nil); err != nil {
+		return err
+	}
+
+	msgs, err := s.Channel.Consume(q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for msg := range msgs {
+			handleDelivery(msg, handler)
+		}
+		slog.Warn("consumer stopped", "queue", queue)
+	}()
+
+	return nil
+}
+
+// SubscribeBroadcast is like Subscribe, but with a private queue per replica so every replica
+// receives every message. The queue is deleted when the connection closes.
+func (s *Service) SubscribeBroadcast(routingKey string, handler func(ctx context.Context, body []byte) error) error {
+	q, err := s.Channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Channel.QueueBind(q.Name, routingKey, ExchangeName, false, nil); err != nil {
+		return err
+	}
+
+	msgs, err := s.Channel.Consume(q.Name, "", false, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for msg := range msgs {
+			handleDelivery(msg, handler)
+		}
+		slog.Warn("broadcast consumer stopped", "routing_key", routingKey)
+	}()
+
+	return nil
+}
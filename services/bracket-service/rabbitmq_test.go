@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingAcknowledger remembers how a delivery was settled.
+type recordingAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestHandleDelivery_Settlement(t *testing.T) {
+	requeueDelay = 0
+	t.Cleanup(func() { requeueDelay = time.Second })
+
+	tests := []struct {
+		name                    string
+		err                     error
+		acked, nacked, requeued bool
+	}{
+		{name: "handled", err: nil, acked: true},
+		{name: "malformed event is dropped", err: fmt.Errorf("%w: unexpected end of JSON input", errMalformedEvent), nacked: true},
+		{name: "other failures are requeued", err: errors.New("connection refused"), nacked: true, requeued: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &recordingAcknowledger{}
+			handleDelivery(amqp.Delivery{Acknowledger: ack, RoutingKey: "events.match.completed"},
+				func(ctx context.Context, body []byte) error { return tt.err })
+
+			assert.Equal(t, tt.acked, ack.acked)
+			assert.Equal(t, tt.nacked, ack.nacked)
+			assert.Equal(t, tt.requeued, ack.requeued)
+		})
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Elo parameters. New participants start at InitialRating and move faster while provisional.
+const (
+	InitialRating    = 1500.0
+	provisionalGames = 30 // Matches before a rating counts as established
+	kProvisional     = 40.0
+	kEstablished     = 20.0
+)
+
+// Participant types as used by tournament-service.
+const (
+	ParticipantIndividual = "individual"
+	ParticipantTeam       = "team"
+)
+
+// Rating is a participant's Elo rating in one game. Users and teams are rated separately.
+type Rating struct {
+	Rank            int     `json:"rank,omitempty"` // Only set on leaderboards
+	ParticipantID   string  `json:"participant_id"`
+	ParticipantType string  `json:"participant_type"`
+	Game            string  `json:"game"`
+	Rating          float64 `json:"rating"`
+	GamesPlayed     int     `json:"games_played"`
+	Wins            int     `json:"wins"`
+	Losses          int     `json:"losses"`
+	Provisional     bool    `json:"provisional"`
+}
+
+// RatingChange is one entry of a participant's rating history.
+type RatingChange struct {
+	MatchID      string    `json:"match_id"`
+	TournamentID string    `json:"tournament_id"`
+	OpponentID   string    `json:"opponent_id"`
+	Won          bool      `json:"won"`
+	RatingBefore float64   `json:"rating_before"`
+	RatingAfter  float64   `json:"rating_after"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ratedMatch is a completed match that counts towards ratings.
+type ratedMatch struct {
+	MatchID         string
+	TournamentID    string
+	Game            string
+	ParticipantType string
+	WinnerID        string
+	LoserID         string
+}
+
+// isRated tells whether a result counts towards ratings. Only matches that were actually
+// played do; byes, forfeits and no-shows say nothing about strength.
+func isRated(resultType string) bool {
+	return resultType == ResultNormal
+}
+
+func kFactor(gamesPlayed int) float64 {
+	if gamesPlayed < provisionalGames {
+		return kProvisional
+	}
+	return kEstablished
+}
+
+// expectedScore is the probability of a win against opponent under the Elo model.
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// rateMatch applies one result to both ratings and returns the changes, winner first.
+// Each side uses its own K-factor, so provisional ratings settle quickly without
+// swinging established ones as much.
+func rateMatch(m ratedMatch, winner, loser *Rating, at time.Time) (RatingChange, RatingChange) {
+	expected := expectedScore(winner.Rating, loser.Rating)
+	gain := kFactor(winner.GamesPlayed) * (1 - expected)
+	loss := kFactor(loser.GamesPlayed) * (1 - expected)
+
+	wc := RatingChange{MatchID: m.MatchID, TournamentID: m.TournamentID, OpponentID: loser.ParticipantID, Won: true, RatingBefore: winner.Rating, CreatedAt: at}
+	lc := RatingChange{MatchID: m.MatchID, TournamentID: m.TournamentID, OpponentID: winner.ParticipantID, Won: false, RatingBefore: loser.Rating, CreatedAt: at}
+
+	winner.Rating = math.Round((winner.Rating+gain)*100) / 100
+	loser.Rating = math.Round((loser.Rating-loss)*100) / 100
+	winner.GamesPlayed++
+	winner.Wins++
+	loser.GamesPlayed++
+	loser.Losses++
+	winner.Provisional = winner.GamesPlayed < provisionalGames
+	loser.Provisional = loser.GamesPlayed < provisionalGames
+
+	wc.RatingAfter = winner.Rating
+	lc.RatingAfter = loser.Rating
+	return wc, lc
+}
+
+// replayRatings rates matches in order from scratch. It returns the final ratings and,
+// per match, the winner's and loser's change.
+func replayRatings(matches []ratedMatch, completedAt []time.Time) (map[string]*Rating, [][2]RatingChange) {
+	book := make(map[string]*Rating)
+	get := func(game, participantType, id string) *Rating {
+		key := game + "\x00" + participantType + "\x00" + id
+		r, ok := book[key]
+		if !ok {
+			r = &Rating{ParticipantID: id, ParticipantType: participantType, Game: game, Rating: InitialRating, Provisional: true}
+			book[key] = r
+		}
+		return r
+	}
+
+	changes := make([][2]RatingChange, len(matches))
+	for i, m := range matches {
+		winner := get(m.Game, m.ParticipantType, m.WinnerID)
+		loser := get(m.Game, m.ParticipantType, m.LoserID)
+		changes[i][0], changes[i][1] = rateMatch(m, winner, loser, completedAt[i])
+	}
+	return book, changes
+}
+
+// --- Storage ---
+
+// lockRating returns a participant's rating for update, creating it at InitialRating on first use.
+func lockRating(ctx context.Context, tx pgx.Tx, game, participantType, participantID string) (*Rating, error) {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO ratings (game, participant_type, participant_id, rating)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game, participant_type, participant_id) DO NOTHING`,
+		game, participantType, participantID, InitialRating)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rating{ParticipantID: participantID, ParticipantType: participantType, Game: game}
+	err = tx.QueryRow(ctx, `
+		SELECT rating, games_played, wins, losses FROM ratings
+		WHERE game = $1 AND participant_type = $2 AND participant_id = $3
+		FOR UPDATE`, game, participantType, participantID,
+	).Scan(&r.Rating, &r.GamesPlayed, &r.Wins, &r.Losses)
+	return r, err
+}
+
+func saveRating(ctx context.Context, tx pgx.Tx, r *Rating) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE ratings SET rating = $1, games_played = $2, wins = $3, losses = $4, updated_at = NOW()
+		WHERE game = $5 AND participant_type = $6 AND participant_id = $7`,
+		r.Rating, r.GamesPlayed, r.Wins, r.Losses, r.Game, r.ParticipantType, r.ParticipantID)
+	return err
+}
+
+func insertRatingChange(ctx context.Context, tx pgx.Tx, m ratedMatch, participantID string, c RatingChange) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO rating_history (match_id, tournament_id, game, participant_type, participant_id, opponent_id, won, rating_before, rating_after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		m.MatchID, m.TournamentID, m.Game, m.ParticipantType, participantID, c.OpponentID, c.Won, c.RatingBefore, c.RatingAfter, c.CreatedAt)
+	return err
+}
+
+// applyRatedMatch rates one match inside tx. A match that already has history is skipped,
+// so redelivered events do not count twice. Ratings are locked in a fixed order to avoid deadlocks.
+func applyRatedMatch(ctx context.Context, tx pgx.Tx, m ratedMatch, at time.Time) error {
+	var rated bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM rating_history WHERE match_id = $1)`, m.MatchID).Scan(&rated); err != nil {
+		return err
+	}
+	if rated {
+		return nil
+	}
+
+	first, second := m.WinnerID, m.LoserID
+	if second < first {
+		first, second = second, first
+	}
+	a, err := lockRating(ctx, tx, m.Game, m.ParticipantType, first)
+	if err != nil {
+		return err
+	}
+	b, err := lockRating(ctx, tx, m.Game, m.ParticipantType, second)
+	if err != nil {
+		return err
+	}
+	winner, loser := a, b
+	if winner.ParticipantID != m.WinnerID {
+		winner, loser = b, a
+	}
+
+	wc, lc := rateMatch(m, winner, loser, at)
+	if err := saveRating(ctx, tx, winner); err != nil {
+		return err
+	}
+	if err := saveRating(ctx, tx, loser); err != nil {
+		return err
+	}
+	if err := insertRatingChange(ctx, tx, m, winner.ParticipantID, wc); err != nil {
+		return err
+	}
+	return insertRatingChange(ctx, tx, m, loser.ParticipantID, lc)
+}
+
+// ratingsByID returns the current ratings of the given participants; unrated ones are missing.
+func (h *BracketHandler) ratingsByID(ctx context.Context, game, participantType string, ids []string) (map[string]float64, error) {
+	rows, err := h.DB.Query(ctx, `
+		SELECT participant_id, rating FROM ratings
+		WHERE game = $1 AND participant_type = $2 AND participant_id = ANY($3)`,
+		game, participantType, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[string]float64, len(ids))
+	for rows.Next() {
+		var id string
+		var rating float64
+		if err := rows.Scan(&id, &rating); err != nil {
+			return nil, err
+		}
+		ratings[id] = rating
+	}
+	return ratings, rows.Err()
+}
+
+// --- Events ---
+
+// publishMatchCompleted announces a match that was played to a result.
+// score_a belongs to player1_id and score_b to player2_id.
+func (h *BracketHandler) publishMatchCompleted(ctx context.Context, tournamentID, matchID, winnerID string, p1, p2 *string, scoreA, scoreB string) {
+	event, _ := json.Marshal(map[string]interface{}{
+		"event_type": "MatchCompleted",
+		"payload": map[string]interface{}{
+			"tournament_id": tournamentID,
+			"match_id":      matchID,
+			"winner_id":     winnerID,
+			"loser_id":      opponentOf(winnerID, p1, p2),
+			"player1_id":    p1,
+			"player2_id":    p2,
+			"score_a":       scoreA,
+			"score_b":       scoreB,
+			"result_type":   ResultNormal,
+		},
+		"timestamp": time.Now(),
+	})
+	_ = h.RMQ.Publish(ctx, "events.match.completed", string(event))
+}
+
+type MatchCompletedEvent struct {
+	EventType string `json:"event_type"`
+	Payload   struct {
+		MatchID string `json:"match_id"`
+	} `json:"payload"`
+}
+
+// HandleMatchCompleted updates the ratings of both participants of a completed match.
+// The match is read back from the database so the rating always reflects the stored result.
+func (h *BracketHandler) HandleMatchCompleted(ctx context.Context, body []byte) error {
+	var event MatchCompletedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	if event.Payload.MatchID == "" {
+		return fmt.Errorf("%w: match completed event without match_id", errMalformedEvent)
+	}
+
+	var m ratedMatch
+	var winnerID, p1, p2 *string
+	var status, resultType string
+	err := h.DB.QueryRow(ctx, `
+		SELECT m.tournament_id, m.winner_id, m.player1_id, m.player2_id, m.status, m.result_type, b.game, b.participant_type
+		FROM matches m
+		JOIN brackets b ON b.tournament_id = m.tournament_id
+		WHERE m.id = $1`, event.Payload.MatchID,
+	).Scan(&m.TournamentID, &winnerID, &p1, &p2, &status, &resultType, &m.Game, &m.ParticipantType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Brackets generated before ratings existed have no game on record
+		slog.InfoContext(ctx, "match has no rated bracket, skipping", "match_id", event.Payload.MatchID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	loserID := (*string)(nil)
+	if winnerID != nil {
+		loserID = opponentOf(*winnerID, p1, p2)
+	}
+	if status != "completed" || !isRated(resultType) || winnerID == nil || loserID == nil {
+		return nil
+	}
+	m.MatchID = event.Payload.MatchID
+	m.WinnerID, m.LoserID = *winnerID, *loserID
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := applyRatedMatch(ctx, tx, m, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// --- Handlers ---
+
+// gameParam reads the :game path parameter, which may contain escaped spaces or punctuation.
+func gameParam(c echo.Context) string {
+	game, err := url.PathUnescape(c.Param("game"))
+	if err != nil {
+		return c.Param("game")
+	}
+	return game
+}
+
+func participantTypeParam(c echo.Context) (string, bool) {
+	participantType := c.QueryParam("participant_type")
+	if participantType == "" {
+		return ParticipantIndividual, true
+	}
+	return participantType, participantType == ParticipantIndividual || participantType == ParticipantTeam
+}
+
+// GetLeaderboard lists the ratings of a game, best first.
+// Query: participant_type (individual|team, default individual), limit (default 50, max 200), offset.
+func (h *BracketHandler) GetLeaderboard(c echo.Context) error {
+	game := gameParam(c)
+	participantType, ok := participantTypeParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "participant_type must be individual or team"})
+	}
+
+	limit, offset := 50, 0
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 200"})
+		}
+		limit = n
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "offset must not be negative"})
+		}
+		offset = n
+	}
+
+	rows, err := h.DB.Query(c.Request().Context(), `
+		SELECT participant_id, rating, games_played, wins, losses
+		FROM ratings
+		WHERE game = $1 AND participant_type = $2
+		ORDER BY rating DESC, games_played DESC, participant_id
+		LIMIT $3 OFFSET $4`, game, participantType, limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch leaderboard"})
+	}
+	defer rows.Close()
+
+	leaderboard := []Rating{}
+	for rows.Next() {
+		r := Rating{Game: game, ParticipantType: participantType, Rank: offset + len(leaderboard) + 1}
+		if err := rows.Scan(&r.ParticipantID, &r.Rating, &r.GamesPlayed, &r.Wins, &r.Losses); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read leaderboard"})
+		}
+		r.Provisional = r.GamesPlayed < provisionalGames
+		leaderboard = append(leaderboard, r)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"game":             game,
+		"participant_type": participantType,
+		"ratings":          leaderboard,
+	})
+}
+
+// GetParticipantRating returns a participant's rating in a game with the most recent history.
+func (h *BracketHandler) GetParticipantRating(c echo.Context) error {
+	game := gameParam(c)
+	participantID := c.Param("participantId")
+	ctx := c.Request().Context()
+
+	r := Rating{ParticipantID: participantID, Game: game}
+	err := h.DB.QueryRow(ctx, `
+		SELECT participant_type, rating, games_played, wins, losses FROM ratings
+		WHERE game = $1 AND participant_id = $2`, game, participantID,
+	).Scan(&r.ParticipantType, &r.Rating, &r.GamesPlayed, &r.Wins, &r.Losses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Participant has no rating in this game"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch rating"})
+	}
+	r.Provisional = r.GamesPlayed < provisionalGames
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT match_id, tournament_id, opponent_id, won, rating_before, rating_after, created_at
+		FROM rating_history
+		WHERE game = $1 AND participant_id = $2
+		ORDER BY created_at DESC
+		LIMIT 50`, game, participantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch rating history"})
+	}
+	defer rows.Close()
+
+	history := []RatingChange{}
+	for rows.Next() {
+		var ch RatingChange
+		if err := rows.Scan(&ch.MatchID, &ch.TournamentID, &ch.OpponentID, &ch.Won, &ch.RatingBefore, &ch.RatingAfter, &ch.CreatedAt); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read rating history"})
+		}
+		history = append(history, ch)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rating":  r,
+		"history": history,
+	})
+}
+
+// RecomputeRatings rebuilds ratings and history from scratch by replaying every rated match in
+// completion order with the same rules as live updates, optionally for a single game (?game=). SuperAdmin only.
+func (h *BracketHandler) RecomputeRatings(c echo.Context) error {
+	if !hasRole(c.Request().Header.Get("X-User-Roles"), "SuperAdmin") {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only admins can recompute ratings"})
+	}
+	game := c.QueryParam("game")
+
+	ctx := c.Request().Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Drop what is there; NULL game means all games
+	if _, err := tx.Exec(ctx, `DELETE FROM rating_history WHERE $1::text IS NULL OR game = $1`, nullIfEmpty(game)); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset rating history"})
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM ratings WHERE $1::text IS NULL OR game = $1`, nullIfEmpty(game)); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset ratings"})
+	}
+
+	// 2. Replay the match history. Matches completed before completed_at was recorded come first.
+	rows, err := tx.Query(ctx, `
+		SELECT m.id, m.tournament_id, b.game, b.participant_type, m.winner_id, m.player1_id, m.player2_id, COALESCE(m.completed_at, b.created_at)
+		FROM matches m
+		JOIN brackets b ON b.tournament_id = m.tournament_id
+		WHERE m.status = 'completed' AND m.result_type = $1 AND m.winner_id IS NOT NULL
+		  AND ($2::text IS NULL OR b.game = $2)
+		ORDER BY m.completed_at NULLS FIRST, b.created_at, m.round, m.match_number`, ResultNormal, nullIfEmpty(game))
+	if err != nil {
+		slog.ErrorContext(ctx, "rating replay failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read match history"})
+	}
+	var history []ratedMatch
+	var completedAt []time.Time
+	for rows.Next() {
+		var m ratedMatch
+		var at time.Time
+		var p1, p2 *string
+		if err := rows.Scan(&m.MatchID, &m.TournamentID, &m.Game, &m.ParticipantType, &m.WinnerID, &p1, &p2, &at); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read match history"})
+		}
+		loserID := opponentOf(m.WinnerID, p1, p2)
+		if loserID == nil {
+			continue
+		}
+		m.LoserID = *loserID
+		history = append(history, m)
+		completedAt = append(completedAt, at)
+	}
+	rows.Close()
+
+	// 3. Replay in memory, then write the result
+	book, changes := replayRatings(history, completedAt)
+	for _, r := range book {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO ratings (game, participant_type, participant_id, rating, games_played, wins, losses)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			r.Game, r.ParticipantType, r.ParticipantID, r.Rating, r.GamesPlayed, r.Wins, r.Losses)
+		if err != nil {
+			slog.ErrorContext(ctx, "rating insert failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to recompute ratings"})
+		}
+	}
+	for i, m := range history {
+		if err := insertRatingChange(ctx, tx, m, m.WinnerID, changes[i][0]); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to recompute ratings"})
+		}
+		if err := insertRatingChange(ctx, tx, m, m.LoserID, changes[i][1]); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to recompute ratings"})
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "Ratings recomputed", "matches": len(history)})
+}
+
+func hasRole(userRoles, role string) bool {
+	for _, r := range strings.Split(userRoles, ",") {
+		if strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
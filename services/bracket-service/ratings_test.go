@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateMatch(t *testing.T) {
+	m := ratedMatch{MatchID: "m1", WinnerID: "a", LoserID: "b"}
+
+	// Equal newcomers move by half the provisional K-factor
+	a := &Rating{ParticipantID: "a", Rating: InitialRating}
+	b := &Rating{ParticipantID: "b", Rating: InitialRating}
+	wc, lc := rateMatch(m, a, b, time.Now())
+	assert.Equal(t, 1520.0, a.Rating)
+	assert.Equal(t, 1480.0, b.Rating)
+	assert.Equal(t, 1500.0, wc.RatingBefore)
+	assert.Equal(t, "b", wc.OpponentID)
+	assert.False(t, lc.Won)
+	assert.Equal(t, 1, a.Wins)
+	assert.Equal(t, 1, b.Losses)
+
+	// An upset against an established favourite
+	fav := &Rating{ParticipantID: "fav", Rating: 1900, GamesPlayed: 100}
+	dog := &Rating{ParticipantID: "dog", Rating: 1500, GamesPlayed: 100}
+	rateMatch(m, dog, fav, time.Now())
+	assert.InDelta(t, 1518.18, dog.Rating, 0.01)
+	assert.InDelta(t, 1881.82, fav.Rating, 0.01)
+	assert.False(t, dog.Provisional)
+}
+
+func TestReplayRatings_MatchesLiveUpdates(t *testing.T) {
+	now := time.Now()
+	matches := []ratedMatch{
+		{MatchID: "m1", Game: "chess", ParticipantType: ParticipantIndividual, WinnerID: "a", LoserID: "b"},
+		{MatchID: "m2", Game: "chess", ParticipantType: ParticipantIndividual, WinnerID: "a", LoserID: "c"},
+		{MatchID: "m3", Game: "go", ParticipantType: ParticipantIndividual, WinnerID: "b", LoserID: "a"},
+	}
+	book, changes := replayRatings(matches, []time.Time{now, now, now})
+
+	// Live path: the same results applied one at a time
+	a := &Rating{ParticipantID: "a", Rating: InitialRating}
+	b := &Rating{ParticipantID: "b", Rating: InitialRating}
+	c := &Rating{ParticipantID: "c", Rating: InitialRating}
+	rateMatch(matches[0], a, b, now)
+	rateMatch(matches[1], a, c, now)
+
+	assert.Len(t, book, 5) // a, b, c in chess; a, b in go
+	assert.Equal(t, a.Rating, book["chess\x00individual\x00a"].Rating)
+	assert.Equal(t, c.Rating, book["chess\x00individual\x00c"].Rating)
+	assert.Equal(t, 2, book["chess\x00individual\x00a"].Wins)
+	// Games are rated independently
+	assert.Equal(t, 1520.0, book["go\x00individual\x00b"].Rating)
+	assert.Equal(t, a.Rating, changes[1][0].RatingAfter)
+}
+
+func TestSeedOrder(t *testing.T) {
+	assert.Equal(t, []int{1, 2}, seedOrder(2))
+	assert.Equal(t, []int{1, 4, 2, 3}, seedOrder(4))
+	assert.Equal(t, []int{1, 8, 4, 5, 2, 7, 3, 6}, seedOrder(8))
+}
+
+func TestHandleMatchCompleted(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB}
+
+	a, b := "a", "b"
+	mockDB.ExpectQuery(`(?s)SELECT m.tournament_id, m.winner_id.*JOIN brackets b`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id", "winner_id", "player1_id", "player2_id", "status", "result_type", "game", "participant_type"}).
+			AddRow("t1", &b, &a, &b, "completed", ResultNormal, "chess", ParticipantIndividual))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM rating_history`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+	// Locked in ID order: a (loser) first
+	for _, id := range []string{"a", "b"} {
+		mockDB.ExpectExec(`(?s)INSERT INTO ratings.*ON CONFLICT`).
+			WithArgs("chess", ParticipantIndividual, id, InitialRating).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectQuery(`(?s)SELECT rating, games_played, wins, losses FROM ratings.*FOR UPDATE`).
+			WithArgs("chess", ParticipantIndividual, id).
+			WillReturnRows(pgxmock.NewRows([]string{"rating", "games_played", "wins", "losses"}).AddRow(InitialRating, 0, 0, 0))
+	}
+	mockDB.ExpectExec(`UPDATE ratings SET rating`).
+		WithArgs(1520.0, 1, 1, 0, "chess", ParticipantIndividual, "b").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`UPDATE ratings SET rating`).
+		WithArgs(1480.0, 1, 0, 1, "chess", ParticipantIndividual, "a").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`INSERT INTO rating_history`).
+		WithArgs("m1", "t1", "chess", ParticipantIndividual, "b", "a", true, 1500.0, 1520.0, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO rating_history`).
+		WithArgs("m1", "t1", "chess", ParticipantIndividual, "a", "b", false, 1500.0, 1480.0, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+
+	body := `{"event_type": "MatchCompleted", "payload": {"tournament_id": "t1", "match_id": "m1"}}`
+	assert.NoError(t, h.HandleMatchCompleted(context.Background(), []byte(body)))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestHandleMatchCompleted_ForfeitsAreNotRated(t *testing.T) {
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB}
+
+	a, b := "a", "b"
+	mockDB.ExpectQuery(`(?s)SELECT m.tournament_id, m.winner_id.*JOIN brackets b`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id", "winner_id", "player1_id", "player2_id", "status", "result_type", "game", "participant_type"}).
+			AddRow("t1", &a, &a, &b, "completed", ResultNoShow, "chess", ParticipantIndividual))
+
+	body := `{"event_type": "MatchCompleted", "payload": {"match_id": "m1"}}`
+	assert.NoError(t, h.HandleMatchCompleted(context.Background(), []byte(body)))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB}
+
+	mockDB.ExpectQuery(`(?s)SELECT participant_id, rating.*FROM ratings.*ORDER BY rating DESC`).
+		WithArgs("League of Legends", ParticipantTeam, 2, 10).
+		WillReturnRows(pgxmock.NewRows([]string{"participant_id", "rating", "games_played", "wins", "losses"}).
+			AddRow("team-a", 1712.5, 40, 30, 10).
+			AddRow("team-b", 1650.0, 12, 8, 4))
+
+	req := httptest.NewRequest(http.MethodGet, "/?participant_type=team&limit=2&offset=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("game")
+	c.SetParamValues("League%20of%20Legends")
+
+	_ = h.GetLeaderboard(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Ratings []Rating `json:"ratings"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 11, body.Ratings[0].Rank)
+	assert.False(t, body.Ratings[0].Provisional)
+	assert.Equal(t, 12, body.Ratings[1].Rank)
+	assert.True(t, body.Ratings[1].Provisional)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRecomputeRatings_AdminOnly(t *testing.T) {
+	e := echo.New()
+	h := &BracketHandler{}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", "u1")
+	req.Header.Set("X-User-Roles", "User")
+	rec := httptest.NewRecorder()
+
+	_ = h.RecomputeRatings(e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestGenerateBracket_RatingSeeding(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1" {
+			json.NewEncoder(w).Encode(map[string]string{"id": "t1", "game": "chess", "participant_type": "individual"})
+			return
+		}
+		json.NewEncoder(w).Encode([]Participant{{ID: "weak"}, {ID: "best"}, {ID: "new"}})
+	}))
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	mockDB.ExpectQuery(`(?s)SELECT participant_id, rating FROM ratings`).
+		WithArgs("chess", "individual", pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"participant_id", "rating"}).
+			AddRow("weak", 1400.0).AddRow("best", 1800.0))
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`INSERT INTO brackets`).
+		WithArgs("t1", "chess", "individual").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectQuery(`(?s)INSERT INTO matches`).
+		WithArgs("t1", 2, 1, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "scheduled", pgxmock.AnyArg(), ResultNormal).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("m-final"))
+	// Seed 1 (best) gets the bye, seed 2 (new, unrated at 1500) meets seed 3 (weak)
+	best, newcomer, weak := "best", "new", "weak"
+	var nobody *string
+	mockDB.ExpectQuery(`(?s)INSERT INTO matches`).
+		WithArgs("t1", 1, 1, &best, nobody, pgxmock.AnyArg(), "completed", &best, ResultBye).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("m1"))
+	mockDB.ExpectExec(`UPDATE matches SET player1_id`).
+		WithArgs(&best, "m-final").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectQuery(`(?s)INSERT INTO matches`).
+		WithArgs("t1", 1, 2, &newcomer, &weak, pgxmock.AnyArg(), "scheduled", nobody, ResultNormal).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("m2"))
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/brackets/generate?tournament_id=t1&seeding=rating", nil)
+	rec := httptest.NewRecorder()
+
+	_ = h.GenerateBracket(e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
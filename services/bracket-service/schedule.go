@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Reschedule proposal states
+const (
+	ProposalPending    = "pending"
+	ProposalAccepted   = "accepted"
+	ProposalRejected   = "rejected"
+	ProposalSuperseded = "superseded"
+)
+
+type RescheduleProposal struct {
+	ID          string     `json:"id"`
+	MatchID     string     `json:"match_id"`
+	ProposedBy  string     `json:"proposed_by"`
+	ProposedFor string     `json:"proposed_for"` // Participant (user or team) the proposer plays for
+	ProposedAt  time.Time  `json:"proposed_at"`
+	Message     string     `json:"message"`
+	Status      string     `json:"status"`
+	RespondedBy *string    `json:"responded_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at"`
+}
+
+// isForfeitable reports whether a match missed its deadline without a result.
+func isForfeitable(status string, deadline *time.Time, now time.Time) bool {
+	return status != "completed" && deadline != nil && now.After(*deadline)
+}
+
+// isPlayer reports whether id is one of the two participants of a match: a user, or a team in team tournaments.
+func isPlayer(id string, p1, p2 *string) bool {
+	return id != "" && ((p1 != nil && *p1 == id) || (p2 != nil && *p2 == id))
+}
+
+// --- Bulk Scheduling ---
+
+type ScheduleRoundRequest struct {
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	SlotMinutes int        `json:"slot_minutes"` // Optional: match N starts at scheduled_at + (N-1) * slot
+	DeadlineAt  *time.Time `json:"deadline_at"`
+	Venue       *string    `json:"venue"`
+}
+
+// ScheduleRound sets the time slots of every unfinished match in a round. Organizer only.
+func (h *BracketHandler) ScheduleRound(c echo.Context) error {
+	tournamentID := c.Param("tournamentId")
+	round, err := strconv.Atoi(c.Param("round"))
+	if err != nil || round < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid round"})
+	}
+
+	var req ScheduleRoundRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.ScheduledAt.IsZero() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "scheduled_at is required"})
+	}
+	if req.SlotMinutes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "slot_minutes cannot be negative"})
+	}
+	if req.DeadlineAt != nil && !req.DeadlineAt.After(req.ScheduledAt) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "deadline_at must be after scheduled_at"})
+	}
+
+	allowed, err := h.canManageTournament(c, tournamentID, false)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "permission lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify permissions"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer can schedule matches"})
+	}
+
+	tag, err := h.DB.Exec(c.Request().Context(), `
+		UPDATE matches
+		SET scheduled_at = $1 + (match_number - 1) * make_interval(mins => $2),
+		    deadline_at = COALESCE($3, deadline_at),
+		    venue = COALESCE($4, venue)
+		WHERE tournament_id = $5 AND round = $6 AND status <> 'completed'`,
+		req.ScheduledAt, req.SlotMinutes, req.DeadlineAt, req.Venue, tournamentID, round,
+	)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "schedule update failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to schedule round"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "No open matches in this round"})
+	}
+
+	event, _ := json.Marshal(map[string]interface{}{
+		"event_type": "RoundScheduled",
+		"payload": map[string]interface{}{
+			"tournament_id": tournamentID,
+			"round":         round,
+			"scheduled_at":  req.ScheduledAt,
+			"slot_minutes":  req.SlotMinutes,
+			"deadline_at":   req.DeadlineAt,
+		},
+		"timestamp": time.Now(),
+	})
+	_ = h.RMQ.Publish(c.Request().Context(), "events.match.scheduled", string(event))
+	h.notifyBracketUpdated(c.Request().Context(), tournamentID, "", "schedule")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "Round scheduled", "matches": tag.RowsAffected()})
+}
+
+// --- Reschedule Proposals ---
+
+func (h *BracketHandler) ListRescheduleProposals(c echo.Context) error {
+	matchID := c.Param("match_id")
+
+	rows, err := h.DB.Query(c.Request().Context(), `
+		SELECT id, match_id, proposed_by, proposed_for, proposed_at, COALESCE(message, ''), status, responded_by, created_at, responded_at
+		FROM reschedule_proposals
+		WHERE match_id = $1
+		ORDER BY created_at DESC`, matchID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch proposals"})
+	}
+	defer rows.Close()
+
+	proposals := []RescheduleProposal{}
+	for rows.Next() {
+		var p RescheduleProposal
+		if err := rows.Scan(&p.ID, &p.MatchID, &p.ProposedBy, &p.ProposedFor, &p.ProposedAt, &p.Message, &p.Status, &p.RespondedBy, &p.CreatedAt, &p.RespondedAt); err != nil {
+			slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read proposals"})
+		}
+		proposals = append(proposals, p)
+	}
+
+	return c.JSON(http.StatusOK, proposals)
+}
+
+type RescheduleRequest struct {
+	ProposedAt time.Time `json:"proposed_at"`
+	Message    string    `json:"message"`
+}
+
+// ProposeReschedule lets one of the two participants suggest a new time. In team tournaments any
+// member of either team may propose. A new proposal replaces any still pending one for the same match.
+func (h *BracketHandler) ProposeReschedule(c echo.Context) error {
+	matchID := c.Param("match_id")
+	userID := c.Request().Header.Get("X-User-Id")
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+	}
+
+	var req RescheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if !req.ProposedAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "proposed_at must be in the future"})
+	}
+
+	ctx := c.Request().Context()
+
+	// Work out which side the caller plays for before anything is locked
+	p1, p2, participantType, err := h.matchSides(ctx, matchID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+	side, err := h.sideOf(ctx, userID, participantType, p1, p2)
+	if err != nil {
+		slog.ErrorContext(ctx, "team membership lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify team membership"})
+	}
+	if side == "" {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the match participants can propose a new time"})
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var deadline *time.Time
+	err = tx.QueryRow(ctx, `SELECT player1_id, player2_id, status, deadline_at FROM matches WHERE id = $1 FOR UPDATE`, matchID).
+		Scan(&p1, &p2, &status, &deadline)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+
+	if !isPlayer(side, p1, p2) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the match participants can propose a new time"})
+	}
+	if status == "completed" {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is already completed"})
+	}
+	if deadline != nil && req.ProposedAt.After(*deadline) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "proposed_at is past the match deadline"})
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE reschedule_proposals SET status = $1 WHERE match_id = $2 AND status = $3`,
+		ProposalSuperseded, matchID, ProposalPending)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update proposals"})
+	}
+
+	proposal := RescheduleProposal{MatchID: matchID, ProposedBy: userID, ProposedFor: side, ProposedAt: req.ProposedAt, Message: req.Message, Status: ProposalPending}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO reschedule_proposals (match_id, proposed_by, proposed_for, proposed_at, message, status)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		matchID, userID, side, req.ProposedAt, req.Message, ProposalPending,
+	).Scan(&proposal.ID, &proposal.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save proposal"})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
+	}
+
+	return c.JSON(http.StatusCreated, proposal)
+}
+
+// AcceptReschedule moves the match to the proposed time. Only the opposing side of the proposer may accept.
+func (h *BracketHandler) AcceptReschedule(c echo.Context) error {
+	return h.respondToReschedule(c, true)
+}
+
+func (h *BracketHandler) RejectReschedule(c echo.Context) error {
+	return h.respondToReschedule(c, false)
+}
+
+func (h *BracketHandler) respondToReschedule(c echo.Context, accept bool) error {
+	matchID := c.Param("match_id")
+	proposalID := c.Param("proposal_id")
+	userID := c.Request().Header.Get("X-User-Id")
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+	}
+
+	ctx := c.Request().Context()
+
+	// Work out which side the caller plays for before anything is locked
+	p1, p2, participantType, err := h.matchSides(ctx, matchID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Proposal not found"})
+	}
+	side, err := h.sideOf(ctx, userID, participantType, p1, p2)
+	if err != nil {
+		slog.ErrorContext(ctx, "team membership lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify team membership"})
+	}
+	if side == "" {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the opponent can respond to this proposal"})
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
+	}
+	defer tx.Rollback(ctx)
+
+	var proposedFor, proposalStatus, matchStatus, tournamentID string
+	var proposedAt time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT r.proposed_for, r.proposed_at, r.status, m.player1_id, m.player2_id, m.status, m.tournament_id
+		FROM reschedule_proposals r
+		JOIN matches m ON m.id = r.match_id
+		WHERE r.id = $1 AND r.match_id = $2
+		FOR UPDATE`, proposalID, matchID,
+	).Scan(&proposedFor, &proposedAt, &proposalStatus, &p1, &p2, &matchStatus, &tournamentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Proposal not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch proposal"})
+	}
+
+	if !isPlayer(side, p1, p2) || side == proposedFor {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the opponent can respond to this proposal"})
+	}
+	if proposalStatus != ProposalPending {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Proposal is no longer pending"})
+	}
+	if matchStatus == "completed" {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is already completed"})
+	}
+
+	newStatus := ProposalRejected
+	if accept {
+		newStatus = ProposalAccepted
+		if _, err := tx.Exec(ctx, `UPDATE matches SET scheduled_at = $1 WHERE id = $2`, proposedAt, matchID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reschedule match"})
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE reschedule_proposals SET status = $1, responded_by = $2, responded_at = NOW() WHERE id = $3`,
+		newStatus, userID, proposalID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update proposal"})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
+	}
+
+	if accept {
+		event, _ := json.Marshal(map[string]interface{}{
+			"event_type": "MatchRescheduled",
+			"payload": map[string]interface{}{
+				"match_id":     matchID,
+				"scheduled_at": proposedAt,
+			},
+			"timestamp": time.Now(),
+		})
+		_ = h.RMQ.Publish(ctx, "events.match.rescheduled", string(event))
+		h.notifyBracketUpdated(ctx, tournamentID, matchID, "reschedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Proposal " + newStatus, "status": newStatus})
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTournamentServiceMock answers the permission lookups made by canManageTournament.
+func newTournamentServiceMock(organizerID string, staff []map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tournaments/t1/staff" {
+			json.NewEncoder(w).Encode(staff)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "t1", "organizer_id": organizerID})
+	}))
+}
+
+var matchSidesColumns = []string{"player1_id", "player2_id", "participant_type"}
+
+// newTeamServiceMock serves the rosters of the given teams.
+func newTeamServiceMock(rosters map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for teamID, userIDs := range rosters {
+			if r.URL.Path != "/teams/"+teamID+"/members" {
+				continue
+			}
+			members := []map[string]string{}
+			for _, id := range userIDs {
+				members = append(members, map[string]string{"user_id": id, "role": "member"})
+			}
+			json.NewEncoder(w).Encode(members)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func TestIsForfeitable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.True(t, isForfeitable("scheduled", &past, now))
+	assert.False(t, isForfeitable("completed", &past, now))
+	assert.False(t, isForfeitable("scheduled", &future, now))
+	assert.False(t, isForfeitable("scheduled", nil, now))
+}
+
+func TestScheduleRound_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	start := time.Date(2030, 1, 1, 18, 0, 0, 0, time.UTC)
+	var noDeadline *time.Time
+	var noVenue *string
+	mockDB.ExpectExec(`(?s)UPDATE matches.*SET scheduled_at`).
+		WithArgs(start, 30, noDeadline, noVenue, "t1", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 4))
+
+	body := `{"scheduled_at": "2030-01-01T18:00:00Z", "slot_minutes": 30}`
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId", "round")
+	c.SetParamValues("t1", "1")
+
+	_ = h.ScheduleRound(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestScheduleRound_KeepsDeadlineWhenOmitted(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	// Moving a round's start time must not clear the deadline it already has
+	start := time.Date(2030, 1, 2, 18, 0, 0, 0, time.UTC)
+	var noDeadline *time.Time
+	venue := "Hall B"
+	mockDB.ExpectExec(`(?s)UPDATE matches.*deadline_at = COALESCE\(\$3, deadline_at\)`).
+		WithArgs(start, 0, noDeadline, &venue, "t1", 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	body := `{"scheduled_at": "2030-01-02T18:00:00Z", "venue": "Hall B"}`
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId", "round")
+	c.SetParamValues("t1", "2")
+
+	_ = h.ScheduleRound(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestScheduleRound_Forbidden(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	// Referees may not schedule
+	tsMock := newTournamentServiceMock("org-1", []map[string]string{{"user_id": "ref-1", "role": "referee"}})
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	body := `{"scheduled_at": "2030-01-01T18:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "ref-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId", "round")
+	c.SetParamValues("t1", "1")
+
+	_ = h.ScheduleRound(c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestProposeReschedule_PastDeadline(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+
+	p1, p2 := "p1", "p2"
+	deadline := time.Now().Add(24 * time.Hour)
+
+	mockDB.ExpectQuery(`(?s)SELECT m.player1_id, m.player2_id.*FROM matches m`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(matchSidesColumns).AddRow(&p1, &p2, "individual"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`SELECT player1_id, player2_id, status, deadline_at FROM matches`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"player1_id", "player2_id", "status", "deadline_at"}).
+			AddRow(&p1, &p2, "scheduled", &deadline))
+	mockDB.ExpectRollback()
+
+	proposed := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"proposed_at": "`+proposed+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "p1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.ProposeReschedule(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "deadline")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAcceptReschedule_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+
+	p1, p2 := "p1", "p2"
+	proposedAt := time.Now().Add(2 * time.Hour)
+
+	mockDB.ExpectQuery(`(?s)SELECT m.player1_id, m.player2_id.*FROM matches m`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(matchSidesColumns).AddRow(&p1, &p2, "individual"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT r.proposed_for.*FROM reschedule_proposals r`).
+		WithArgs("prop-1", "m1").
+		WillReturnRows(pgxmock.NewRows([]string{"proposed_for", "proposed_at", "status", "player1_id", "player2_id", "status", "tournament_id"}).
+			AddRow("p1", proposedAt, "pending", &p1, &p2, "scheduled", "t1"))
+	mockDB.ExpectExec(`UPDATE matches SET scheduled_at`).
+		WithArgs(proposedAt, "m1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`UPDATE reschedule_proposals SET status`).
+		WithArgs(ProposalAccepted, "p2", "prop-1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", "p2")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id", "proposal_id")
+	c.SetParamValues("m1", "prop-1")
+
+	_ = h.AcceptReschedule(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAcceptReschedule_ProposerCannotAccept(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}}
+
+	p1, p2 := "p1", "p2"
+
+	mockDB.ExpectQuery(`(?s)SELECT m.player1_id, m.player2_id.*FROM matches m`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows(matchSidesColumns).AddRow(&p1, &p2, "individual"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT r.proposed_for.*FROM reschedule_proposals r`).
+		WithArgs("prop-1", "m1").
+		WillReturnRows(pgxmock.NewRows([]string{"proposed_for", "proposed_at", "status", "player1_id", "player2_id", "status", "tournament_id"}).
+			AddRow("p1", time.Now().Add(time.Hour), "pending", &p1, &p2, "scheduled", "t1"))
+	mockDB.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", "p1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id", "proposal_id")
+	c.SetParamValues("m1", "prop-1")
+
+	_ = h.AcceptReschedule(c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAcceptReschedule_TeamMembers(t *testing.T) {
+	teamA, teamB := "team-a", "team-b"
+	tests := []struct {
+		name   string
+		userID string
+		locks  bool
+		status int
+	}{
+		{"member of the opposing team", "u2", true, http.StatusOK},
+		{"teammate of the proposer", "u3", true, http.StatusForbidden},
+		{"member of neither team", "u9", false, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			assert.NoError(t, err)
+			defer mockDB.Close()
+
+			teams := newTeamServiceMock(map[string][]string{teamA: {"u1", "u3"}, teamB: {"u2"}})
+			defer teams.Close()
+
+			h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TeamServiceURL: teams.URL}
+
+			proposedAt := time.Now().Add(2 * time.Hour)
+			mockDB.ExpectQuery(`(?s)SELECT m.player1_id, m.player2_id.*FROM matches m`).
+				WithArgs("m1").
+				WillReturnRows(pgxmock.NewRows(matchSidesColumns).AddRow(&teamA, &teamB, "team"))
+			if tt.locks {
+				mockDB.ExpectBegin()
+				mockDB.ExpectQuery(`(?s)SELECT r.proposed_for.*FROM reschedule_proposals r`).
+					WithArgs("prop-1", "m1").
+					WillReturnRows(pgxmock.NewRows([]string{"proposed_for", "proposed_at", "status", "player1_id", "player2_id", "status", "tournament_id"}).
+						AddRow(teamA, proposedAt, "pending", &teamA, &teamB, "scheduled", "t1"))
+			}
+			if tt.status == http.StatusOK {
+				mockDB.ExpectExec(`UPDATE matches SET scheduled_at`).
+					WithArgs(proposedAt, "m1").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mockDB.ExpectExec(`UPDATE reschedule_proposals SET status`).
+					WithArgs(ProposalAccepted, tt.userID, "prop-1").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mockDB.ExpectCommit()
+			} else if tt.locks {
+				mockDB.ExpectRollback()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("X-User-Id", tt.userID)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("match_id", "proposal_id")
+			c.SetParamValues("m1", "prop-1")
+
+			_ = h.AcceptReschedule(c)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.NoError(t, mockDB.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Longest series a round can be configured with.
+const maxBestOf = 9
+
+// Game is a single game (map) of a best-of-N series.
+type Game struct {
+	GameNumber int     `json:"game_number"`
+	Map        string  `json:"map"`
+	ScoreA     int     `json:"score_a"` // Player 1
+	ScoreB     int     `json:"score_b"` // Player 2
+	WinnerID   *string `json:"winner_id"`
+}
+
+// winsNeeded is the number of games required to take a best-of-N series.
+func winsNeeded(bestOf int) int {
+	return bestOf/2 + 1
+}
+
+// seriesWinner tallies the games of a series. winner is nil until one side has enough wins.
+func seriesWinner(bestOf int, player1, player2 string, games []Game) (winner *string, winsA, winsB int) {
+	for _, g := range games {
+		if g.WinnerID == nil {
+			continue
+		}
+		switch *g.WinnerID {
+		case player1:
+			winsA++
+		case player2:
+			winsB++
+		}
+	}
+
+	needed := winsNeeded(bestOf)
+	if winsA >= needed {
+		winner = &player1
+	} else if winsB >= needed {
+		winner = &player2
+	}
+	return winner, winsA, winsB
+}
+
+// loadGames returns the recorded games of every match in a tournament, keyed by match ID.
+func (h *BracketHandler) loadGames(ctx context.Context, tournamentID string) (map[string][]Game, error) {
+	rows, err := h.DB.Query(ctx, `
+		SELECT g.match_id, g.game_number, COALESCE(g.map, ''), g.score_a, g.score_b, g.winner_id
+		FROM match_games g
+		JOIN matches m ON m.id = g.match_id
+		WHERE m.tournament_id = $1
+		ORDER BY g.match_id, g.game_number`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := make(map[string][]Game)
+	for rows.Next() {
+		var matchID string
+		var g Game
+		if err := rows.Scan(&matchID, &g.GameNumber, &g.Map, &g.ScoreA, &g.ScoreB, &g.WinnerID); err != nil {
+			return nil, err
+		}
+		games[matchID] = append(games[matchID], g)
+	}
+	return games, rows.Err()
+}
+
+// --- Round Format ---
+
+type RoundFormatRequest struct {
+	BestOf int `json:"best_of"`
+}
+
+// SetRoundFormat makes every unfinished match of a round a best-of-N series. Organizer only.
+func (h *BracketHandler) SetRoundFormat(c echo.Context) error {
+	tournamentID := c.Param("tournamentId")
+	round, err := strconv.Atoi(c.Param("round"))
+	if err != nil || round < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid round"})
+	}
+
+	var req RoundFormatRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.BestOf < 1 || req.BestOf > maxBestOf || req.BestOf%2 == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("best_of must be an odd number between 1 and %d", maxBestOf)})
+	}
+
+	allowed, err := h.canManageTournament(c, tournamentID, false)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "permission lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify permissions"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer can change the match format"})
+	}
+
+	// Series that already have games keep their format
+	tag, err := h.DB.Exec(c.Request().Context(), `
+		UPDATE matches SET best_of = $1
+		WHERE tournament_id = $2 AND round = $3 AND status <> 'completed'
+		  AND NOT EXISTS (SELECT 1 FROM match_games g WHERE g.match_id = matches.id)`,
+		req.BestOf, tournamentID, round,
+	)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "format update failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update round format"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "No unstarted matches in this round"})
+	}
+
+	h.notifyBracketUpdated(c.Request().Context(), tournamentID, "", "format")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"message": "Round format updated", "best_of": req.BestOf, "matches": tag.RowsAffected()})
+}
+
+// --- Game Results ---
+
+type GameRequest struct {
+	GameNumber int    `json:"game_number"`
+	Map        string `json:"map"`
+	ScoreA     int    `json:"score_a"`
+	ScoreB     int    `json:"score_b"`
+}
+
+// RecordGame stores (or corrects) one game of a series. Once a side has won enough games
+// the series is completed and the winner advances. Organizer, co-organizer or referee only.
+func (h *BracketHandler) RecordGame(c echo.Context) error {
+	matchID := c.Param("match_id")
+
+	var req GameRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.ScoreA < 0 || req.ScoreB < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Scores cannot be negative"})
+	}
+	if req.ScoreA == req.ScoreB {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A game cannot end in a draw"})
+	}
+
+	ctx := c.Request().Context()
+
+	// 1. Check permissions before anything is locked
+	tournamentID, err := h.matchTournament(ctx, matchID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+	allowed, err := h.canManageTournament(c, tournamentID, true)
+	if err != nil {
+		slog.ErrorContext(ctx, "permission lookup failed", "error", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to verify permissions"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer or a referee can report games"})
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "DB Error"})
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the match
+	var status string
+	var p1, p2, nextMatchID, loserNextMatchID *string
+	var matchNum, bestOf int
+	err = tx.QueryRow(ctx, `
+		SELECT player1_id, player2_id, status, next_match_id, loser_next_match_id, match_number, best_of
+		FROM matches WHERE id = $1 FOR UPDATE`, matchID,
+	).Scan(&p1, &p2, &status, &nextMatchID, &loserNextMatchID, &matchNum, &bestOf)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+
+	// 2. Validate against the series
+	if status == "completed" {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Series is already decided"})
+	}
+	if p1 == nil || p2 == nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is still waiting for its participants"})
+	}
+	if req.GameNumber < 1 || req.GameNumber > bestOf {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("game_number must be between 1 and %d", bestOf)})
+	}
+
+	gameWinner := *p1
+	if req.ScoreB > req.ScoreA {
+		gameWinner = *p2
+	}
+
+	// 3. Upsert the game
+	_, err = tx.Exec(ctx, `
+		INSERT INTO match_games (match_id, game_number, map, score_a, score_b, winner_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (match_id, game_number) DO UPDATE SET map = $3, score_a = $4, score_b = $5, winner_id = $6`,
+		matchID, req.GameNumber, req.Map, req.ScoreA, req.ScoreB, gameWinner,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "game insert failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save game"})
+	}
+
+	// 4. Re-tally the series
+	rows, err := tx.Query(ctx, `SELECT game_number, winner_id FROM match_games WHERE match_id = $1 ORDER BY game_number`, matchID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read games"})
+	}
+	var games []Game
+	for rows.Next() {
+		var g Game
+		if err := rows.Scan(&g.GameNumber, &g.WinnerID); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read games"})
+		}
+		games = append(games, g)
+	}
+	rows.Close()
+
+	winner, winsA, winsB := seriesWinner(bestOf, *p1, *p2, games)
+	scoreA, scoreB := strconv.Itoa(winsA), strconv.Itoa(winsB)
+
+	// 5. Series score is the number of games won; complete and advance once decided
+	if winner != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE matches SET score_a = $1, score_b = $2, winner_id = $3, status = 'completed', result_type = $4, completed_at = NOW()
+			WHERE id = $5`, scoreA, scoreB, *winner, ResultNormal, matchID)
+		if err == nil {
+			err = advanceParticipants(ctx, tx, nextMatchID, loserNextMatchID, matchNum, winner, opponentOf(*winner, p1, p2))
+		}
+	} else {
+		_, err = tx.Exec(ctx, `UPDATE matches SET score_a = $1, score_b = $2, status = 'in_progress' WHERE id = $3`, scoreA, scoreB, matchID)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "series update failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update series"})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Commit failed"})
+	}
+
+	if winner != nil {
+		h.publishMatchCompleted(ctx, tournamentID, matchID, *winner, p1, p2, scoreA, scoreB)
+	}
+	h.notifyBracketUpdated(ctx, tournamentID, matchID, "game")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Game recorded",
+		"score_a":   scoreA,
+		"score_b":   scoreB,
+		"completed": winner != nil,
+		"winner_id": winner,
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesWinner(t *testing.T) {
+	a, b := "a", "b"
+
+	// Bo3 at 1-1: undecided
+	winner, winsA, winsB := seriesWinner(3, a, b, []Game{{WinnerID: &a}, {WinnerID: &b}})
+	assert.Nil(t, winner)
+	assert.Equal(t, 1, winsA)
+	assert.Equal(t, 1, winsB)
+
+	// Bo3 at 2-1
+	winner, _, _ = seriesWinner(3, a, b, []Game{{WinnerID: &a}, {WinnerID: &b}, {WinnerID: &b}})
+	assert.Equal(t, "b", *winner)
+
+	// Bo5 needs three wins
+	winner, _, _ = seriesWinner(5, a, b, []Game{{WinnerID: &a}, {WinnerID: &a}})
+	assert.Nil(t, winner)
+
+	// Bo1
+	winner, _, _ = seriesWinner(1, a, b, []Game{{WinnerID: &a}})
+	assert.Equal(t, "a", *winner)
+}
+
+func TestSetRoundFormat_RejectsEvenBestOf(t *testing.T) {
+	e := echo.New()
+	h := &BracketHandler{}
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"best_of": 4}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("tournamentId", "round")
+	c.SetParamValues("t1", "1")
+
+	_ = h.SetRoundFormat(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRecordGame_DecidesSeries(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p1, p2 := "p1", "p2"
+	var noNext *string
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m-final").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT player1_id.*best_of.*FOR UPDATE`).
+		WithArgs("m-final").
+		WillReturnRows(pgxmock.NewRows([]string{"player1_id", "player2_id", "status", "next_match_id", "loser_next_match_id", "match_number", "best_of"}).
+			AddRow(&p1, &p2, "in_progress", noNext, noNext, 1, 3))
+	mockDB.ExpectExec(`INSERT INTO match_games`).
+		WithArgs("m-final", 3, "Inferno", 16, 9, "p1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectQuery(`SELECT game_number, winner_id FROM match_games`).
+		WithArgs("m-final").
+		WillReturnRows(pgxmock.NewRows([]string{"game_number", "winner_id"}).
+			AddRow(1, &p1).AddRow(2, &p2).AddRow(3, &p1))
+	// 2-1 completes the final; there is no next match to advance to
+	mockDB.ExpectExec(`(?s)UPDATE matches SET score_a = \$1, score_b = \$2, winner_id = \$3, status = 'completed', result_type = \$4, completed_at = NOW\(\)`).
+		WithArgs("2", "1", "p1", ResultNormal, "m-final").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+
+	body := `{"game_number": 3, "map": "Inferno", "score_a": 16, "score_b": 9}`
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m-final")
+
+	_ = h.RecordGame(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"completed":true`)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRecordGame_GameNumberOutOfRange(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tsMock := newTournamentServiceMock("org-1", nil)
+	defer tsMock.Close()
+
+	h := &BracketHandler{DB: mockDB, RMQ: &MockRabbitMQ{}, TournamentServiceURL: tsMock.URL}
+
+	p1, p2, next := "p1", "p2", "m-final"
+
+	mockDB.ExpectQuery(`SELECT tournament_id FROM matches`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id"}).AddRow("t1"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`(?s)SELECT player1_id.*best_of.*FOR UPDATE`).
+		WithArgs("m1").
+		WillReturnRows(pgxmock.NewRows([]string{"player1_id", "player2_id", "status", "next_match_id", "loser_next_match_id", "match_number", "best_of"}).
+			AddRow(&p1, &p2, "scheduled", &next, nil, 1, 1))
+	mockDB.ExpectRollback()
+
+	body := `{"game_number": 2, "score_a": 1, "score_b": 0}`
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "org-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("match_id")
+	c.SetParamValues("m1")
+
+	_ = h.RecordGame(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
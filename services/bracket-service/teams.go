@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Upper bound for membership lookups against team-service.
+const teamLookupTimeout = 5 * time.Second
+
+// matchSides reads the players of a match and the participant type of its bracket without a lock,
+// so team-service can be asked about membership before a transaction locks the match.
+func (h *BracketHandler) matchSides(ctx context.Context, matchID string) (p1, p2 *string, participantType string, err error) {
+	err = h.DB.QueryRow(ctx, `
+		SELECT m.player1_id, m.player2_id, COALESCE(b.participant_type, 'individual')
+		FROM matches m
+		LEFT JOIN brackets b ON b.tournament_id = m.tournament_id
+		WHERE m.id = $1`, matchID,
+	).Scan(&p1, &p2, &participantType)
+	return p1, p2, participantType, err
+}
+
+// sideOf returns the participant a user plays for in a match: the user themselves in individual
+// tournaments, or the team they are a member of in team tournaments. Empty when they play for neither side.
+func (h *BracketHandler) sideOf(ctx context.Context, userID, participantType string, p1, p2 *string) (string, error) {
+	if userID == "" {
+		return "", nil
+	}
+	if participantType != ParticipantTeam {
+		if isPlayer(userID, p1, p2) {
+			return userID, nil
+		}
+		return "", nil
+	}
+
+	for _, teamID := range []*string{p1, p2} {
+		if teamID == nil {
+			continue
+		}
+		members, err := h.teamMembers(ctx, *teamID)
+		if err != nil {
+			return "", err
+		}
+		for _, m := range members {
+			if m == userID {
+				return *teamID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// teamMembers lists the user IDs of a team's members from team-service.
+func (h *BracketHandler) teamMembers(ctx context.Context, teamID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, teamLookupTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/teams/%s/members", h.TeamServiceURL, teamID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(echo.HeaderXRequestID, id)
+	}
+
+	resp, err := tracedClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("team members lookup returned %d", resp.StatusCode)
+	}
+
+	var members []struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	return ids, nil
+}
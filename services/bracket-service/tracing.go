@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "bracket-service"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(serviceName)
+}
+
+// tracedClient is used for calls to other services, so they continue the request's trace.
+var tracedClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// InitTracing installs the global tracer provider and W3C trace context propagation. Spans are
+// exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)
+// is set; everything else about the exporter comes from the standard OTEL_* variables.
+// The returned function flushes the remaining spans.
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	)
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	installTracing(tp)
+	return tp.Shutdown, nil
+}
+
+func installTracing(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// TracingMiddleware continues the trace of the incoming traceparent (set by api-gateway) for every
+// request but health checks and metric scrapes.
+func TracingMiddleware() echo.MiddlewareFunc {
+	return otelecho.Middleware(serviceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		return c.Path() == "/health" || c.Path() == "/metrics"
+	}))
+}
+
+// queryTracer records a span for every query run through the pool.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := queryOperation(data.SQL)
+	ctx, _ = tracer().Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(data.SQL),
+	))
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryOperation is the keyword a statement starts with, e.g. SELECT.
+func queryOperation(sql string) string {
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "QUERY"
+}
+
+// amqpHeaders carries trace context in the headers of AMQP messages.
+type amqpHeaders amqp.Table
+
+func (h amqpHeaders) Get(key string) string {
+	v, _ := h[key].(string)
+	return v
+}
+
+func (h amqpHeaders) Set(key, value string) {
+	h[key] = value
+}
+
+func (h amqpHeaders) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startPublishSpan starts the span of publishing an event and injects it into headers, so the
+// consumers' spans join the trace.
+func startPublishSpan(ctx context.Context, routingKey string, headers amqp.Table) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, "publish "+routingKey, trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		semconv.MessagingSystemRabbitMQ,
+		semconv.MessagingOperationTypeSend,
+		semconv.MessagingDestinationName(ExchangeName),
+		semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+	))
+	otel.GetTextMapPropagator().Inject(ctx, amqpHeaders(headers))
+	return ctx, span
+}
+
+// startConsumeSpan starts the span of handling a message, continuing the trace it was published in.
+func startConsumeSpan(msg amqp.Delivery) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), amqpHeaders(msg.Headers))
+	return tracer().Start(ctx, "process "+msg.RoutingKey, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		semconv.MessagingSystemRabbitMQ,
+		semconv.MessagingOperationTypeProcess,
+		semconv.MessagingDestinationName(msg.Exchange),
+		semconv.MessagingRabbitMQDestinationRoutingKey(msg.RoutingKey),
+	))
+}
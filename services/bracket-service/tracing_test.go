@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracing records spans in memory for the duration of the test.
+func newTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	installTracing(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestHandleDelivery_ContinuesPublisherTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	// As published by tournament-service
+	headers := amqp.Table{}
+	_, publish := startPublishSpan(context.Background(), "events.tournament.participant_disqualified", headers)
+	publish.End()
+
+	var handled trace.SpanContext
+	handleDelivery(amqp.Delivery{Headers: headers, Exchange: ExchangeName, RoutingKey: "events.tournament.participant_disqualified"},
+		func(ctx context.Context, body []byte) error {
+			handled = trace.SpanContextFromContext(ctx)
+			return fmt.Errorf("%w: incomplete disqualification event", errMalformedEvent)
+		})
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	consume := spans[1]
+	assert.Equal(t, "process events.tournament.participant_disqualified", consume.Name)
+	assert.Equal(t, trace.SpanKindConsumer, consume.SpanKind)
+	assert.Equal(t, publish.SpanContext().TraceID(), consume.SpanContext.TraceID())
+	assert.Equal(t, publish.SpanContext().SpanID(), consume.Parent.SpanID())
+	assert.Equal(t, consume.SpanContext.SpanID(), handled.SpanID(), "the handler runs in the consume span")
+	assert.Equal(t, codes.Error, consume.Status.Code)
+}
+
+func TestGetTournamentJSON_InjectsTraceparent(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	var traceparent string
+	tournaments := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{}`))
+	}))
+	defer tournaments.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	h := &BracketHandler{TournamentServiceURL: tournaments.URL}
+	var out map[string]interface{}
+	_, err := h.getTournamentJSON(ctx, "/tournaments/t1", "u1", "", &out)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	client := spans[0]
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), client.Parent.SpanID())
+	assert.Equal(t, "00-"+client.SpanContext.TraceID().String()+"-"+client.SpanContext.SpanID().String()+"-01", traceparent)
+}
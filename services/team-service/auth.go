@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -16,38 +21,166 @@ const (
 	ctxUsername ctxKey = "username"
 )
 
+// Identity headers set by api-gateway after it verified the caller's token
+const (
+	headerUserID        = "X-User-Id"
+	headerUserName      = "X-User-Name"
+	headerUserEmail     = "X-User-Email"
+	headerGatewaySecret = "X-Gateway-Secret"
+)
+
 type Claims struct {
 	Sub               string `json:"sub"`
 	Email             string `json:"email"`
 	PreferredUsername string `json:"preferred_username"`
+	AuthorizedParty   string `json:"azp"`
 	jwt.RegisteredClaims
 }
 
-// DEV MODE: does NOT verify signature. Swap later.
-func ExtractUser(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+// Verifier checks access tokens issued by Keycloak: the signature against the issuer's JWKS,
+// expiry, issuer and, when Audience is set, that the token was issued for us.
+// user-service/auth.go has the same Verifier; keep the two in step.
+type Verifier struct {
+	Issuer   string
+	Audience string // Matched against "aud" or, as Keycloak puts the client there, "azp"
+	Keys     *KeySet
+}
+
+// Verify parses and validates a raw token.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	claims := &Claims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Sub == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	if v.Audience != "" && !slices.Contains(claims.Audience, v.Audience) && claims.AuthorizedParty != v.Audience {
+		return nil, fmt.Errorf("token not issued for %q", v.Audience)
+	}
+	return claims, nil
+}
+
+// Auth identifies the caller. A bearer token is verified when a Verifier is configured.
+// Without a token, a signed identity assertion from api-gateway is accepted when Assertions is set.
+// Otherwise the gateway's identity headers are trusted only if the request proves it came through
+// the gateway: the shared GatewaySecret header, or a client certificate verified by our TLS config
+// whose common name is in GatewayNames.
+type Auth struct {
+	Verifier      *Verifier
+	Assertions    *AssertionVerifier
+	GatewaySecret string
+	GatewayNames  []string
+}
+
+// NewAuthFromEnv reads the Keycloak realm (KEYCLOAK_URL / KEYCLOAK_REALM, or JWT_ISSUER,
+// JWKS_URL and JWT_AUDIENCE) and the gateway fallback (GATEWAY_ASSERTION_PUBLIC_KEY,
+// GATEWAY_SHARED_SECRET, and GATEWAY_CLIENT_NAMES for mTLS). At least one must be configured.
+func NewAuthFromEnv() (*Auth, error) {
+	assertions, err := NewAssertionVerifierFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	a := &Auth{Assertions: assertions, GatewaySecret: os.Getenv("GATEWAY_SHARED_SECRET")}
+
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" && os.Getenv("KEYCLOAK_URL") != "" {
+		realm := os.Getenv("KEYCLOAK_REALM")
+		if realm == "" {
+			realm = "t-hub"
+		}
+		issuer = strings.TrimSuffix(os.Getenv("KEYCLOAK_URL"), "/") + "/realms/" + realm
+	}
+	if issuer != "" {
+		jwksURL := os.Getenv("JWKS_URL")
+		if jwksURL == "" {
+			jwksURL = issuer + "/protocol/openid-connect/certs"
 		}
+		a.Verifier = &Verifier{Issuer: issuer, Audience: os.Getenv("JWT_AUDIENCE"), Keys: NewKeySet(jwksURL)}
+	}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, _, err := jwt.NewParser().ParseUnverified(tokenStr, &Claims{})
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+	if names := os.Getenv("GATEWAY_CLIENT_NAMES"); names != "" {
+		for _, n := range strings.Split(names, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				a.GatewayNames = append(a.GatewayNames, n)
+			}
 		}
+	} else if os.Getenv("TLS_CLIENT_CA_FILE") != "" {
+		a.GatewayNames = []string{"api-gateway"}
+	}
+
+	if a.Verifier == nil && a.Assertions == nil && a.GatewaySecret == "" && len(a.GatewayNames) == 0 {
+		return nil, fmt.Errorf("set KEYCLOAK_URL or JWT_ISSUER to verify tokens, or GATEWAY_ASSERTION_PUBLIC_KEY / GATEWAY_SHARED_SECRET / TLS_CLIENT_CA_FILE to trust api-gateway")
+	}
+	return a, nil
+}
+
+// fromGateway reports whether the request provably came through api-gateway.
+func (a *Auth) fromGateway(r *http.Request) bool {
+	if a.GatewaySecret != "" {
+		got := r.Header.Get(headerGatewaySecret)
+		if got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(a.GatewaySecret)) == 1 {
+			return true
+		}
+	}
+	// Only chains our TLS config verified against TLS_CLIENT_CA_FILE count
+	if r.TLS != nil && len(a.GatewayNames) > 0 {
+		for _, chain := range r.TLS.VerifiedChains {
+			if len(chain) > 0 && slices.Contains(a.GatewayNames, chain[0].Subject.CommonName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *Auth) ExtractUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var userID, email, username string
+
+		scheme, token, hasToken := strings.Cut(r.Header.Get("Authorization"), " ")
+		hasToken = hasToken && strings.EqualFold(scheme, "Bearer") && token != ""
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok || claims.Sub == "" {
+		switch {
+		case hasToken && a.Verifier != nil:
+			claims, err := a.Verifier.Verify(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			userID, email, username = claims.Sub, claims.Email, claims.PreferredUsername
+		case a.Assertions != nil && r.Header.Get(HeaderIdentityAssertion) != "":
+			claims, err := a.Assertions.Verify(r.Header.Get(HeaderIdentityAssertion))
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			userID, email, username = claims.Subject, claims.Email, claims.Name
+		case a.fromGateway(r) && r.Header.Get(headerUserID) != "":
+			userID = r.Header.Get(headerUserID)
+			email = r.Header.Get(headerUserEmail)
+			username = r.Header.Get(headerUserName)
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), ctxUserID, claims.Sub)
-		ctx = context.WithValue(ctx, ctxEmail, claims.Email)
-		ctx = context.WithValue(ctx, ctxUsername, claims.PreferredUsername)
+		setLogUser(r.Context(), userID)
+		ctx := context.WithValue(r.Context(), ctxUserID, userID)
+		ctx = context.WithValue(ctx, ctxEmail, email)
+		ctx = context.WithValue(ctx, ctxUsername, username)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
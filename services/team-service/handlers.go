@@ -27,7 +27,7 @@ func (h Handler) Ready(w http.ResponseWriter, r *http.Request) {
 
 func (h Handler) TeamsCount(w http.ResponseWriter, r *http.Request) {
 	var count int64
-	err := h.DB.QueryRow(`SELECT COUNT(*) FROM teams;`).Scan(&count)
+	err := h.DB.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM teams;`).Scan(&count)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
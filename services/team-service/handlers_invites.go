@@ -38,7 +38,7 @@ func (h Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
 
 	// Simple auth rule for now: only captain can invite
 	var exists bool
-	if err := h.DB.QueryRow(`
+	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT EXISTS(
 			SELECT 1 FROM teams WHERE id = $1::uuid AND captain_id = $2::uuid
 		)`, teamID, userID).Scan(&exists); err != nil || !exists {
@@ -47,7 +47,7 @@ func (h Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var inviteID string
-	err := h.DB.QueryRow(`
+	err := h.DB.QueryRowContext(r.Context(), `
 		INSERT INTO invites (team_id, inviter_id, invitee_email, status, expires_at)
 		VALUES ($1::uuid, $2::uuid, $3, 'pending', $4)
 		RETURNING id::text`,
@@ -77,7 +77,7 @@ func (h Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
 
 	// captain only (for now)
 	var exists bool
-	if err := h.DB.QueryRow(`
+	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT EXISTS(
 			SELECT 1 FROM teams WHERE id = $1::uuid AND captain_id = $2::uuid
 		)`, teamID, userID).Scan(&exists); err != nil || !exists {
@@ -85,7 +85,7 @@ func (h Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT id::text, team_id::text, inviter_id::text, invitee_email, status, expires_at
 		FROM invites
 		WHERE team_id = $1::uuid and status = 'pending'
@@ -126,7 +126,7 @@ func (h Handler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Simple rule: inviter can delete their own invite
-	res, err := h.DB.Exec(`
+	res, err := h.DB.ExecContext(r.Context(), `
 		DELETE FROM invites
 		WHERE id = $1::uuid AND (inviter_id = $2::uuid or lower(invitee_email) = lower($3))`,
 		inviteID, userID, email)
@@ -165,7 +165,7 @@ func (h Handler) MyInvites(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. Query invites matching this email + join teams for display info
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT 
 			i.id::text, 
 			i.team_id::text, 
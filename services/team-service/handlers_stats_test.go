@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+func TestHandleMatchCompleted_RecordsTeams(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 12, 20, 19, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO team_match_results`).
+		WithArgs("m1", "team-1", "t1", "team-2", true, "2", "1", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The opponent may be a user; the insert then matches no teams row
+	mock.ExpectExec(`INSERT INTO team_match_results`).
+		WithArgs("m1", "team-2", "t1", "team-1", false, "1", "2", at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	h := Handler{DB: db}
+	body := `{"event_type": "MatchCompleted", "payload": {"tournament_id": "t1", "match_id": "m1", "winner_id": "team-1",
+		"player1_id": "team-1", "player2_id": "team-2", "score_a": "2", "score_b": "1"}, "timestamp": "2025-12-20T19:00:00Z"}`
+	if err := h.HandleMatchCompleted(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("HandleMatchCompleted: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestHandleBracketCompleted_IgnoresIndividualTournaments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := Handler{DB: db}
+	body := `{"payload": {"tournament_id": "t1", "participant_type": "individual", "placements": [{"participant_id": "u1", "placement": 1, "placement_to": 1}]}}`
+	if err := h.HandleBracketCompleted(context.Background(), []byte(body)); err != nil {
+		t.Fatalf("HandleBracketCompleted: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestGetTeamStats_OK(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM teams`).
+		WithArgs("team-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT\s+\(SELECT COUNT`).
+		WithArgs("team-1").
+		WillReturnRows(sqlmock.NewRows([]string{"tournaments", "played", "won", "titles"}).AddRow(2, 5, 4, 1))
+	mock.ExpectQuery(`FROM team_placements`).
+		WithArgs("team-1", statsPlacementLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"tournament_id", "game", "placement", "placement_to", "completed_at"}).
+			AddRow("t1", "League of Legends", 1, 1, now))
+	mock.ExpectQuery(`FROM team_match_results`).
+		WithArgs("team-1", statsResultLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"match_id", "tournament_id", "opponent_id", "won", "score_for", "score_against", "played_at"}).
+			AddRow("m1", "t1", "team-2", true, "2", "1", now))
+
+	h := Handler{DB: db}
+	req := httptest.NewRequest(http.MethodGet, "/teams/team-1/stats", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "team-1"})
+	rr := httptest.NewRecorder()
+
+	h.GetTeamStats(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var out TeamStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if out.MatchesLost != 1 || out.Titles != 1 || len(out.Placements) != 1 || len(out.RecentResults) != 1 {
+		t.Fatalf("unexpected out: %#v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestGetTeamStats_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM teams`).
+		WithArgs("team-x").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	h := Handler{DB: db}
+	req := httptest.NewRequest(http.MethodGet, "/teams/team-x/stats", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "team-x"})
+	rr := httptest.NewRecorder()
+
+	h.GetTeamStats(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
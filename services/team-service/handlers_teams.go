@@ -15,7 +15,7 @@ type createTeamRequest struct {
 }
 
 func (h Handler) ListTeams(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT id::text, name, tag, captain_id::text, logo_url, created_at
 		FROM teams
 		ORDER BY created_at DESC`)
@@ -46,7 +46,7 @@ func (h Handler) MyCaptainTeams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT id::text, name, tag, captain_id::text, logo_url, created_at
 		FROM teams
 		WHERE captain_id = $1::uuid
@@ -78,7 +78,7 @@ func (h Handler) MyMemberTeams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT t.id::text, t.name, t.tag, t.captain_id::text, t.logo_url, t.created_at
 		FROM team_members m
 		JOIN teams t ON t.id = m.team_id
@@ -121,7 +121,7 @@ func (h Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
@@ -129,7 +129,7 @@ func (h Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback()
 
 	var teamID string
-	err = tx.QueryRow(`
+	err = tx.QueryRowContext(r.Context(), `
 		INSERT INTO teams (name, tag, captain_id, logo_url)
 		VALUES ($1, $2, $3::uuid, $4)
 		RETURNING id::text`,
@@ -141,7 +141,7 @@ func (h Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Ensure captain is also a team member
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(r.Context(), `
 		INSERT INTO team_members (team_id, user_id, role)
 		VALUES ($1::uuid, $2::uuid, 'captain')
 		ON CONFLICT DO NOTHING`, teamID, userID)
@@ -173,7 +173,7 @@ func (h Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Start a transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
@@ -182,7 +182,7 @@ func (h Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 
 	// 1. Check if user is captain (Locking the row to prevent race conditions)
 	var captainID string
-	err = tx.QueryRow(`SELECT captain_id FROM teams WHERE id = $1::uuid FOR UPDATE`, teamID).Scan(&captainID)
+	err = tx.QueryRowContext(r.Context(), `SELECT captain_id FROM teams WHERE id = $1::uuid FOR UPDATE`, teamID).Scan(&captainID)
 	if err != nil {
 		// Team not found
 		w.WriteHeader(http.StatusNotFound)
@@ -195,21 +195,21 @@ func (h Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. Delete all Invites for this team
-	_, err = tx.Exec(`DELETE FROM invites WHERE team_id = $1::uuid`, teamID)
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM invites WHERE team_id = $1::uuid`, teamID)
 	if err != nil {
 		http.Error(w, "failed to delete invites", http.StatusInternalServerError)
 		return
 	}
 
 	// 3. Delete all Members for this team
-	_, err = tx.Exec(`DELETE FROM team_members WHERE team_id = $1::uuid`, teamID)
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM team_members WHERE team_id = $1::uuid`, teamID)
 	if err != nil {
 		http.Error(w, "failed to delete members", http.StatusInternalServerError)
 		return
 	}
 
 	// 4. Finally, Delete the Team
-	_, err = tx.Exec(`DELETE FROM teams WHERE id = $1::uuid`, teamID)
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM teams WHERE id = $1::uuid`, teamID)
 	if err != nil {
 		http.Error(w, "failed to delete team", http.StatusInternalServerError)
 		return
@@ -239,7 +239,7 @@ func (h Handler) LeaveTeam(w http.ResponseWriter, r *http.Request) {
 
 	// Don’t allow captain to "leave" (avoid orphaned teams)
 	var isCaptain bool
-	if err := h.DB.QueryRow(`
+	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT EXISTS(
 			SELECT 1 FROM teams WHERE id = $1::uuid AND captain_id = $2::uuid
 		)`, teamID, userID).Scan(&isCaptain); err != nil {
@@ -252,7 +252,7 @@ func (h Handler) LeaveTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Remove membership
-	res, err := h.DB.Exec(`
+	res, err := h.DB.ExecContext(r.Context(), `
 		DELETE FROM team_members
 		WHERE team_id = $1::uuid AND user_id = $2::uuid
 	`, teamID, userID)
@@ -287,7 +287,7 @@ func (h Handler) CaptainRemoveMember(w http.ResponseWriter, r *http.Request) {
 
 	// Verify caller is captain
 	var isCaptain bool
-	if err := h.DB.QueryRow(`
+	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT EXISTS(
 			SELECT 1 FROM teams WHERE id = $1::uuid AND captain_id = $2::uuid
 		)`, teamID, captainID).Scan(&isCaptain); err != nil {
@@ -305,7 +305,7 @@ func (h Handler) CaptainRemoveMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.DB.Exec(`
+	res, err := h.DB.ExecContext(r.Context(), `
 		DELETE FROM team_members
 		WHERE team_id = $1::uuid AND user_id = $2::uuid
 	`, teamID, memberID)
@@ -337,7 +337,7 @@ func (h Handler) IsCaptainOfTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var isCaptain bool
-	err := h.DB.QueryRow(`
+	err := h.DB.QueryRowContext(r.Context(), `
 		SELECT EXISTS(
 			SELECT 1
 			FROM teams
@@ -27,7 +27,7 @@ func (h Handler) ListTeamMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.DB.Query(`
+	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT user_id::text, role, joined_at
 		FROM team_members
 		WHERE team_id = $1::uuid
@@ -92,7 +92,7 @@ func (h Handler) AcceptInviteAndJoinTeam(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
@@ -102,7 +102,7 @@ func (h Handler) AcceptInviteAndJoinTeam(w http.ResponseWriter, r *http.Request)
 	// Verify invite belongs to this team and is meant for this email and is pending and not expired
 	var status string
 	var expiresAt *time.Time
-	err = tx.QueryRow(`
+	err = tx.QueryRowContext(r.Context(), `
 		SELECT status, expires_at
 		FROM invites
 		WHERE id = $1::uuid
@@ -124,7 +124,7 @@ func (h Handler) AcceptInviteAndJoinTeam(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Add member (idempotent)
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(r.Context(), `
 		INSERT INTO team_members (team_id, user_id, role)
 		VALUES ($1::uuid, $2::uuid, 'member')
 		ON CONFLICT (team_id, user_id) DO NOTHING
@@ -135,7 +135,7 @@ func (h Handler) AcceptInviteAndJoinTeam(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Mark invite accepted
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(r.Context(), `
 		UPDATE invites
 		SET status = 'accepted'
 		WHERE id = $1::uuid
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long fetched keys are trusted, and how often an unknown kid may trigger a refetch
+const (
+	jwksCacheTTL        = time.Hour
+	jwksMinRefreshDelay = 30 * time.Second
+)
+
+// KeySet fetches the signing keys of an issuer from its JWKS endpoint and caches them by kid.
+// Keys are refetched once the cache is older than jwksCacheTTL, or early when a token names an
+// unknown kid (the issuer rotated its keys), at most once per jwksMinRefreshDelay. Only one fetch
+// runs at a time and the lock is not held during it, so other requests keep using cached keys.
+// user-service/jwks.go and team-service/jwks.go are the same file; change them together.
+type KeySet struct {
+	URL    string
+	Client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetching    chan struct{}    // Closed when the running fetch is done; nil when none runs
+	now         func() time.Time // For tests
+}
+
+func NewKeySet(url string) *KeySet {
+	return &KeySet{URL: url, Client: &http.Client{Timeout: 5 * time.Second}, now: time.Now}
+}
+
+// Key returns the public key with the given kid.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := s.now()
+	key, known := s.keys[kid]
+	if known && now.Sub(s.fetchedAt) <= jwksCacheTTL {
+		s.mu.Unlock()
+		return key, nil
+	}
+
+	var err error
+	switch {
+	case s.fetching != nil && known:
+		// Someone is already refreshing; the cached key is still good meanwhile
+	case s.fetching != nil:
+		// The refresh in flight may bring the kid
+		wait := s.fetching
+		s.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.mu.Lock()
+	case now.Sub(s.attemptedAt) > jwksMinRefreshDelay:
+		// Fetch at most once per jwksMinRefreshDelay, so forged kids or an unreachable issuer
+		// do not turn every request into a JWKS call
+		s.attemptedAt = now
+		done := make(chan struct{})
+		s.fetching = done
+		s.mu.Unlock()
+
+		// Detached from the caller, whose cancellation should not fail the requests waiting on it
+		var keys map[string]crypto.PublicKey
+		keys, err = s.fetch(context.WithoutCancel(ctx))
+
+		s.mu.Lock()
+		// On error keep using the cached keys until the issuer is back
+		if err == nil {
+			s.keys, s.fetchedAt = keys, now
+		}
+		s.fetching = nil
+		close(done)
+	}
+	key, known = s.keys[kid]
+	s.mu.Unlock()
+
+	if !known {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		// Keycloak also publishes its encryption key
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue // Skip key types we cannot use rather than failing the whole set
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("ec point not on curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
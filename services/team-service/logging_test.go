@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRequestLogger_LogsRequestAndUser(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	r := mux.NewRouter()
+	r.Use(requestLogger)
+	r.HandleFunc("/teams/{id}", func(w http.ResponseWriter, r *http.Request) {
+		setLogUser(r.Context(), "user-1") // As ExtractUser does
+		slog.InfoContext(r.Context(), "invite sent", "email", "jane@example.com")
+		w.WriteHeader(http.StatusCreated)
+	}).Methods(http.MethodPost)
+
+	req := httptest.NewRequest(http.MethodPost, "/teams/t1", nil)
+	req.Header.Set("X-Request-Id", "gw-req-1")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Request-Id"); got != "gw-req-1" {
+		t.Fatalf("expected request id to be echoed, got %q", got)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %d: %s", len(lines), buf.String())
+	}
+	var handler, access map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &handler); err != nil {
+		t.Fatalf("handler record is not JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+		t.Fatalf("access record is not JSON: %v", err)
+	}
+
+	if handler["email"] != "[redacted]" {
+		t.Fatalf("expected email to be redacted, got %v", handler["email"])
+	}
+	want := map[string]interface{}{
+		"msg":        "request",
+		"service":    "team-service",
+		"route":      "/teams/{id}",
+		"status":     float64(http.StatusCreated),
+		"request_id": "gw-req-1",
+		"user_id":    "user-1",
+	}
+	for k, v := range want {
+		if access[k] != v {
+			t.Fatalf("expected %s=%v, got %v", k, v, access[k])
+		}
+	}
+	if _, ok := access["latency_ms"]; !ok {
+		t.Fatalf("expected latency_ms in %v", access)
+	}
+}
+
+func TestRequestLogger_HealthOnlyAtDebug(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	r := mux.NewRouter()
+	r.Use(requestLogger)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected no records at info level, got %s", buf.String())
+	}
+}
@@ -1,21 +1,51 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	// Trigger CI/CD (same idea as your user service)
-	fmt.Println("debug build")
+	InitLogging()
+
+	shutdownTracing, err := InitTracing(context.Background())
+	if err != nil {
+		log.Fatalf("tracing error: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	db := InitDB()
 	h := Handler{DB: db}
 
+	auth, err := NewAuthFromEnv()
+	if err != nil {
+		log.Fatalf("auth config error: %v", err)
+	}
+
+	bus, err := ConnectRabbitMQ()
+	if err != nil {
+		log.Fatalf("rabbitmq error: %v", err)
+	}
+	defer bus.Conn.Close()
+
+	// stats read model
+	if err := bus.Subscribe("team-service.match_completed", "events.match.completed", h.HandleMatchCompleted); err != nil {
+		log.Fatalf("rabbitmq subscribe error: %v", err)
+	}
+	if err := bus.Subscribe("team-service.bracket_completed", "events.bracket.completed", h.HandleBracketCompleted); err != nil {
+		log.Fatalf("rabbitmq subscribe error: %v", err)
+	}
+
 	r := mux.NewRouter()
+	r.Use(tracingMiddleware)
+	r.Use(requestLogger)
 	r.Use(metricsMiddleware)
 
 	// public endpoints
@@ -26,10 +56,11 @@ func main() {
 	// public list for front page
 	r.HandleFunc("/teams", h.ListTeams).Methods("GET")
 	r.HandleFunc("/teams/{id}/members", h.ListTeamMembers).Methods("GET")
+	r.HandleFunc("/teams/{id}/stats", h.GetTeamStats).Methods("GET")
 
 	// auth subrouter
 	authed := r.PathPrefix("").Subrouter()
-	authed.Use(ExtractUser)
+	authed.Use(auth.ExtractUser)
 	authed.HandleFunc("/teams/me/teams", h.MyMemberTeams).Methods("GET")
 	authed.HandleFunc("/teams/me/teams/captain", h.MyCaptainTeams).Methods("GET")
 
@@ -49,8 +80,41 @@ func main() {
 	// metrics endpoint
 	r.Handle("/metrics", metricsHandler()).Methods("GET")
 
+	srv, err := newServer(":8080", r)
+	if err != nil {
+		log.Fatalf("tls config error: %v", err)
+	}
 	log.Println("Team Service running on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"))
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// newServer serves TLS when TLS_CERT_FILE and TLS_KEY_FILE are set. With TLS_CLIENT_CA_FILE,
+// client certificates signed by that CA are verified, which is how api-gateway can prove itself
+// over mTLS; requests without one are still accepted and must authenticate with a token.
+func newServer(addr string, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	if os.Getenv("TLS_CERT_FILE") == "" || os.Getenv("TLS_KEY_FILE") == "" {
+		return srv, nil
+	}
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile := os.Getenv("TLS_CLIENT_CA_FILE"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return srv, nil
+}
@@ -19,3 +19,34 @@ type Invite struct {
 	Status       string     `json:"status"`
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
 }
+
+// TeamStats is the competitive record of a team, built from bracket-service events.
+type TeamStats struct {
+	TeamID            string        `json:"team_id"`
+	TournamentsPlayed int           `json:"tournaments_played"`
+	MatchesPlayed     int           `json:"matches_played"`
+	MatchesWon        int           `json:"matches_won"`
+	MatchesLost       int           `json:"matches_lost"`
+	Titles            int           `json:"titles"`
+	Placements        []Placement   `json:"placements"`     // Most recent first
+	RecentResults     []MatchResult `json:"recent_results"` // Most recent first
+}
+
+// Placement is the final position in a finished tournament; tied places span placement..placement_to.
+type Placement struct {
+	TournamentID string    `json:"tournament_id"`
+	Game         string    `json:"game"`
+	Placement    int       `json:"placement"`
+	PlacementTo  int       `json:"placement_to"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+type MatchResult struct {
+	MatchID      string    `json:"match_id"`
+	TournamentID string    `json:"tournament_id"`
+	OpponentID   *string   `json:"opponent_id"`
+	Won          bool      `json:"won"`
+	ScoreFor     *string   `json:"score_for"`
+	ScoreAgainst *string   `json:"score_against"`
+	PlayedAt     time.Time `json:"played_at"`
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	ExchangeName = "t-hub.events"
+	ExchangeType = "topic"
+)
+
+// requeueDelay is how long a consumer waits before handing a failed message back to the queue,
+// so a database outage does not turn into a busy loop.
+var requeueDelay = time.Second
+
+// errMalformedEvent marks events a handler cannot parse. They would fail the same way on every
+// delivery, so they are dropped instead of requeued.
+var errMalformedEvent = errors.New("malformed event")
+
+type EventBus struct {
+	Conn    *amqp.Connection
+	Channel *amqp.Channel
+}
+
+func ConnectRabbitMQ() (*EventBus, error) {
+	user := os.Getenv("RABBITMQ_DEFAULT_USER")
+	pass := os.Getenv("RABBITMQ_DEFAULT_PASS")
+	host := os.Getenv("RABBITMQ_HOST")
+
+	if user == "" || pass == "" || host == "" {
+		return nil, fmt.Errorf("RABBITMQ_DEFAULT_USER, RABBITMQ_DEFAULT_PASS, and RABBITMQ_HOST env vars must be set")
+	}
+
+	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:5672/", user, pass, host))
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.ExchangeDeclare(ExchangeName, ExchangeType, true, false, false, false, nil); err != nil {
+		return nil, err
+	}
+
+	log.Println("Connected to RabbitMQ")
+	return &EventBus{Conn: conn, Channel: ch}, nil
+}
+
+// Subscribe binds a durable queue to routingKey and hands every message to handler in the background.
+func (b *EventBus) Subscribe(queue string, routingKey string, handler func(ctx context.Context, body []byte) error) error {
+	q, err := b.Channel.QueueDeclare(queue, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Channel.QueueBind(q.Name, routingKey, ExchangeName, false, nil); err != nil {
+		return err
+	}
+
+	msgs, err := b.Channel.Consume(q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for msg := range msgs {
+			handleDelivery(msg, handler)
+		}
+		log.Printf("consumer for %s stopped", queue)
+	}()
+
+	return nil
+}
+
+// handleDelivery runs handler for msg in the trace it was published in.
+// Messages are acked when handler succeeds. Malformed events are logged and dropped; any other failure
+// is requeued after requeueDelay so the stats catch up once the database is back.
+func handleDelivery(msg amqp.Delivery, handler func(ctx context.Context, body []byte) error) {
+	ctx, span := startConsumeSpan(msg)
+	defer span.End()
+
+	if err := handler(ctx, msg.Body); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, errMalformedEvent) {
+			slog.ErrorContext(ctx, "dropping malformed event", "routing_key", msg.RoutingKey, "error", err)
+			_ = msg.Nack(false, false)
+			return
+		}
+		slog.ErrorContext(ctx, "failed to handle event, requeueing", "routing_key", msg.RoutingKey, "error", err)
+		time.Sleep(requeueDelay)
+		_ = msg.Nack(false, true)
+		return
+	}
+	_ = msg.Ack(false)
+}
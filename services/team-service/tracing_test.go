@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	testTraceID     = "0af7651916cd43dd8448eb211c80319c"
+	testParentID    = "b7ad6b7169203331"
+)
+
+// newTestTracing records spans in memory for the duration of the test.
+func newTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	installTracing(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestTracingMiddleware_ContinuesGatewayTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	r := mux.NewRouter()
+	r.Use(tracingMiddleware)
+	r.HandleFunc("/health", Handler{}.Health)
+	r.HandleFunc("/teams/{id}/members", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/teams/t1/members", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1 (health checks are not traced)", len(spans))
+	}
+	if spans[0].Name != "GET /teams/{id}/members" {
+		t.Fatalf("span name = %q", spans[0].Name)
+	}
+	if got := spans[0].SpanContext.TraceID().String(); got != testTraceID {
+		t.Fatalf("trace id = %s, want the gateway's %s", got, testTraceID)
+	}
+	if got := spans[0].Parent.SpanID().String(); got != testParentID {
+		t.Fatalf("parent = %s, want %s", got, testParentID)
+	}
+}
+
+func TestHandleDelivery_ContinuesPublisherTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	var handled trace.SpanContext
+	msg := amqp.Delivery{Headers: amqp.Table{"traceparent": testTraceparent}, RoutingKey: "events.bracket.completed"}
+	handleDelivery(msg, func(ctx context.Context, body []byte) error {
+		handled = trace.SpanContextFromContext(ctx)
+		return nil
+	})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "process events.bracket.completed" || spans[0].SpanKind != trace.SpanKindConsumer {
+		t.Fatalf("span = %q (%v)", spans[0].Name, spans[0].SpanKind)
+	}
+	if got := spans[0].Parent.SpanID().String(); got != testParentID {
+		t.Fatalf("parent = %s, want the publisher's %s", got, testParentID)
+	}
+	if handled.SpanID() != spans[0].SpanContext.SpanID() {
+		t.Fatalf("handler did not run in the consume span")
+	}
+}
+
+// recordingAcknowledger remembers how a delivery was settled.
+type recordingAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestHandleDelivery_RequeuesFailuresButDropsMalformedEvents(t *testing.T) {
+	requeueDelay = 0
+	t.Cleanup(func() { requeueDelay = time.Second })
+
+	h := Handler{}
+	malformed := &recordingAcknowledger{}
+	handleDelivery(amqp.Delivery{Acknowledger: malformed, Body: []byte(`{"payload":`)}, h.HandleBracketCompleted)
+	if !malformed.nacked || malformed.requeued {
+		t.Fatalf("malformed event: nacked=%v requeued=%v, want dropped", malformed.nacked, malformed.requeued)
+	}
+
+	failed := &recordingAcknowledger{}
+	handleDelivery(amqp.Delivery{Acknowledger: failed}, func(ctx context.Context, body []byte) error {
+		return errors.New("connection refused")
+	})
+	if !failed.nacked || !failed.requeued {
+		t.Fatalf("failed event: nacked=%v requeued=%v, want requeued", failed.nacked, failed.requeued)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mintAssertion signs claims the way api-gateway does.
+func mintAssertion(t *testing.T, key ed25519.PrivateKey, claims AssertionClaims) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+}
+
+func gatewayClaims() AssertionClaims {
+	now := time.Now()
+	return AssertionClaims{
+		Issuer: assertionIssuer, Audience: assertionAudience,
+		Subject: "user-123", Name: "tester", Email: "t@example.com", Roles: "SuperAdmin,user",
+		IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(),
+	}
+}
+
+// runIdentity passes req through IdentityMiddleware and returns the status and the headers the handler saw.
+func runIdentity(v *AssertionVerifier, req *http.Request) (int, http.Header) {
+	e := echo.New()
+	var seen http.Header
+	e.Use(IdentityMiddleware(v))
+	e.GET("/", func(c echo.Context) error {
+		seen = c.Request().Header.Clone()
+		return c.NoContent(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code, seen
+}
+
+func TestIdentityMiddleware_ValidAssertion(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	v := &AssertionVerifier{Keys: []ed25519.PublicKey{pub}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderIdentityAssertion, mintAssertion(t, priv, gatewayClaims()))
+	req.Header.Set("X-User-Id", "someone-else")
+
+	code, h := runIdentity(v, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "user-123", h.Get("X-User-Id"))
+	assert.Equal(t, "tester", h.Get("X-User-Name"))
+	assert.Equal(t, "t@example.com", h.Get("X-User-Email"))
+	assert.Equal(t, "SuperAdmin,user", h.Get("X-User-Roles"))
+}
+
+func TestIdentityMiddleware_NoAssertionIsAnonymous(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	v := &AssertionVerifier{Keys: []ed25519.PublicKey{pub}}
+
+	// A pod calling us directly and claiming to be an admin
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User-Id", "admin-id")
+	req.Header.Set("X-User-Roles", "SuperAdmin")
+
+	code, h := runIdentity(v, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, h.Get("X-User-Id"))
+	assert.Empty(t, h.Get("X-User-Roles"))
+}
+
+func TestIdentityMiddleware_RejectsInvalidAssertions(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	v := &AssertionVerifier{Keys: []ed25519.PublicKey{pub}}
+
+	expired := gatewayClaims()
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	wrongAudience := gatewayClaims()
+	wrongAudience.Audience = "somewhere-else"
+	wrongIssuer := gatewayClaims()
+	wrongIssuer.Issuer = "bracket-service"
+	future := gatewayClaims()
+	future.IssuedAt = time.Now().Add(time.Hour).Unix()
+	noSubject := gatewayClaims()
+	noSubject.Subject = ""
+	valid := mintAssertion(t, priv, gatewayClaims())
+
+	cases := map[string]string{
+		"garbage":        "not.an.assertion",
+		"other key":      mintAssertion(t, otherKey, gatewayClaims()),
+		"expired":        mintAssertion(t, priv, expired),
+		"wrong audience": mintAssertion(t, priv, wrongAudience),
+		"wrong issuer":   mintAssertion(t, priv, wrongIssuer),
+		"future":         mintAssertion(t, priv, future),
+		"no subject":     mintAssertion(t, priv, noSubject),
+		"tampered":       valid[:len(valid)-4] + "AAAA",
+	}
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderIdentityAssertion, raw)
+			code, _ := runIdentity(v, req)
+			assert.Equal(t, http.StatusUnauthorized, code)
+		})
+	}
+}
+
+func TestIdentityMiddleware_DisabledWithoutKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User-Id", "user-123")
+
+	code, h := runIdentity(nil, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "user-123", h.Get("X-User-Id"))
+}
+
+func TestNewAssertionVerifierFromEnv_KeyRotation(t *testing.T) {
+	t.Setenv("GATEWAY_ASSERTION_PUBLIC_KEY", "")
+	v, err := NewAssertionVerifierFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	oldPub, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	newPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	var pemData []byte
+	for _, k := range []ed25519.PublicKey{oldPub, newPub} {
+		der, err := x509.MarshalPKIXPublicKey(k)
+		require.NoError(t, err)
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	t.Setenv("GATEWAY_ASSERTION_PUBLIC_KEY", string(pemData))
+
+	v, err = NewAssertionVerifierFromEnv()
+	require.NoError(t, err)
+	assert.Len(t, v.Keys, 2)
+	_, err = v.Verify(mintAssertion(t, oldPriv, gatewayClaims()))
+	assert.NoError(t, err, "assertions signed with the old key stay valid during rotation")
+
+	t.Setenv("GATEWAY_ASSERTION_PUBLIC_KEY", "not a key")
+	_, err = NewAssertionVerifierFromEnv()
+	assert.Error(t, err)
+}
+
+func TestIdentityVerifierFromEnv_RequiresKeyOutsideDev(t *testing.T) {
+	t.Setenv("GATEWAY_ASSERTION_PUBLIC_KEY", "")
+	t.Setenv("TRUST_IDENTITY_HEADERS", "")
+	_, err := IdentityVerifierFromEnv()
+	assert.Error(t, err, "forged X-User-* headers must not be trusted by default")
+
+	t.Setenv("TRUST_IDENTITY_HEADERS", "true")
+	v, err := IdentityVerifierFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/jackc/pgx/v5"
@@ -18,6 +18,11 @@ type DBClient interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
+// RowQuerier is satisfied by both the pool and a pgx.Tx, for helpers used inside and outside transactions.
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func ConnectDB() (*pgxpool.Pool, error) {
 	// 1. Get DB credentials from Env
 	dbUser := os.Getenv("DB_USER")
@@ -38,6 +43,8 @@ func ConnectDB() (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
+	// Every query gets a span in the trace of the request that ran it
+	config.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -49,6 +56,6 @@ func ConnectDB() (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
-	log.Println("Connected to PostgreSQL")
+	slog.Info("connected to PostgreSQL")
 	return pool, nil
 }
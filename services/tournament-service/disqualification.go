@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Registration status of a participant removed from the tournament.
+const RegistrationDisqualified = "disqualified"
+
+type DisqualifyRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DisqualifyParticipantHandler removes a participant from a running tournament.
+// The ParticipantDisqualified event lets bracket-service forfeit their remaining matches.
+func DisqualifyParticipantHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		participantID := c.Param("participantId")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req DisqualifyRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if len(req.Reason) > 500 {
+			var errs ValidationErrors
+			errs.Add("reason", "Reason must be at most 500 characters")
+			return validationFailed(c, errs)
+		}
+
+		ctx := c.Request().Context()
+		var t Tournament
+		query := `SELECT id, organizer_id, status FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(ctx, query, tournamentID).Scan(&t.ID, &t.OrganizerID, &t.Status); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(ctx, db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+		if t.Status == "completed" || t.Status == "cancelled" {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Tournament is already finished"})
+		}
+
+		var status string
+		err := db.QueryRow(ctx, `SELECT status FROM registrations WHERE tournament_id = $1 AND participant_id = $2`,
+			tournamentID, participantID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Participant is not registered"})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch registration"})
+		}
+		if status == RegistrationDisqualified {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Participant is already disqualified"})
+		}
+
+		updateQuery := `
+			UPDATE registrations
+			SET status = $1, disqualified_reason = $2, disqualified_at = NOW()
+			WHERE tournament_id = $3 AND participant_id = $4
+		`
+		if _, err := db.Exec(ctx, updateQuery, RegistrationDisqualified, req.Reason, tournamentID, participantID); err != nil {
+			slog.ErrorContext(ctx, "database update failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to disqualify participant"})
+		}
+
+		event := Event{
+			EventType: "ParticipantDisqualified",
+			Payload: map[string]string{
+				"tournament_id":   tournamentID,
+				"participant_id":  participantID,
+				"reason":          req.Reason,
+				"disqualified_by": userID,
+			},
+			Timestamp: time.Now(),
+		}
+		eventBytes, _ := json.Marshal(event)
+		if err := rmq.Publish(c.Request().Context(), "events.tournament.participant_disqualified", string(eventBytes)); err != nil {
+			// The registration is already updated; bracket-service will be out of sync until replayed
+			slog.ErrorContext(ctx, "failed to publish event", "error", err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"message": "Participant disqualified", "participant_id": participantID})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisqualifyParticipantHandler_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	rmq := &MockRabbitMQ{}
+
+	tournamentID := "tourn-live"
+	organizerID := "user-admin"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
+			AddRow(tournamentID, organizerID, "ongoing"))
+	mockDB.ExpectQuery("SELECT status FROM registrations").
+		WithArgs(tournamentID, "cheater").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("approved"))
+	mockDB.ExpectExec("UPDATE registrations").
+		WithArgs(RegistrationDisqualified, "Cheating", tournamentID, "cheater").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"reason": "Cheating"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "participantId")
+	c.SetParamValues(tournamentID, "cheater")
+
+	_ = DisqualifyParticipantHandler(mockDB, rmq)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "events.tournament.participant_disqualified", rmq.LastKey)
+	assert.Contains(t, rmq.LastBody, `"participant_id":"cheater"`)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDisqualifyParticipantHandler_AlreadyDisqualified(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	rmq := &MockRabbitMQ{}
+
+	tournamentID := "tourn-live"
+	organizerID := "user-admin"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
+			AddRow(tournamentID, organizerID, "ongoing"))
+	mockDB.ExpectQuery("SELECT status FROM registrations").
+		WithArgs(tournamentID, "cheater").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(RegistrationDisqualified))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "participantId")
+	c.SetParamValues(tournamentID, "cheater")
+
+	_ = DisqualifyParticipantHandler(mockDB, rmq)(c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Empty(t, rmq.LastKey)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InitLogging makes slog, and the log package that then writes through it, emit JSON records
+// tagged with the service name. LOG_LEVEL sets the minimum level: debug, info (default), warn or error.
+func InitLogging() {
+	level := slog.LevelInfo
+	var err error
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		err = level.UnmarshalText([]byte(v))
+	}
+	slog.SetDefault(newLogger(os.Stdout, level))
+	if err != nil {
+		slog.Warn("invalid LOG_LEVEL, logging at info", "error", err)
+	}
+}
+
+func newLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr})
+	return slog.New(contextHandler{h}).With("service", serviceName)
+}
+
+// logFields are the request's details added to everything logged with its context.
+type logFields struct {
+	requestID string
+
+	mu     sync.Mutex
+	userID string // Once the caller is authenticated
+}
+
+type logFieldsKey struct{}
+
+func withLogFields(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, &logFields{requestID: requestID})
+}
+
+// requestID is the ID of the request ctx belongs to, to pass on to other services.
+func requestID(ctx context.Context) string {
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		return f.requestID
+	}
+	return ""
+}
+
+// setLogUser records who made the request, for the rest of its records.
+func setLogUser(ctx context.Context, userID string) {
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		f.mu.Lock()
+		f.userID = userID
+		f.mu.Unlock()
+	}
+}
+
+// contextHandler adds the request ID, user ID and trace ID of the context to every record.
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		r.AddAttrs(slog.String("request_id", f.requestID))
+		f.mu.Lock()
+		if f.userID != "" {
+			r.AddAttrs(slog.String("user_id", f.userID))
+		}
+		f.mu.Unlock()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Bearer credentials and anything shaped like a JWT
+	tokenPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+|eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// Attributes whose value is never logged.
+var sensitiveKeys = map[string]bool{
+	"email": true, "password": true, "token": true, "access_token": true, "refresh_token": true,
+	"authorization": true, "assertion": true,
+}
+
+// redactAttr keeps emails and tokens out of the logs, in attributes and in messages alike.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[redacted]")
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(redact(err.Error()))
+		}
+	}
+	return a
+}
+
+func redact(s string) string {
+	s = tokenPattern.ReplaceAllString(s, "[token]")
+	return emailPattern.ReplaceAllString(s, "[email]")
+}
+
+// RequestIDMiddleware tags everything logged for a request with its ID: the X-Request-Id set by
+// api-gateway, or a new one for requests that did not come through it.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: newRequestID,
+		RequestIDHandler: func(c echo.Context, id string) {
+			if !validRequestID(id) {
+				id = newRequestID()
+				c.Response().Header().Set(echo.HeaderXRequestID, id)
+			}
+			req := c.Request()
+			req.Header.Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(withLogFields(req.Context(), id)))
+		},
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogMiddleware logs every request once it is done: route, status and latency, plus the
+// request and user IDs. Health checks and metric scrapes are only logged at debug level.
+func RequestLogMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		HandleError:  true,
+		LogMethod:    true,
+		LogRoutePath: true,
+		LogURIPath:   true,
+		LogStatus:    true,
+		LogLatency:   true,
+		LogRemoteIP:  true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			level := slog.LevelInfo
+			switch {
+			case v.Status >= 500:
+				level = slog.LevelError
+			case v.RoutePath == "/health" || v.RoutePath == "/metrics":
+				level = slog.LevelDebug
+			}
+			slog.LogAttrs(c.Request().Context(), level, "request",
+				slog.String("method", v.Method),
+				slog.String("route", v.RoutePath),
+				slog.String("path", v.URIPath),
+				slog.Int("status", v.Status),
+				slog.Float64("latency_ms", float64(v.Latency.Microseconds())/1000),
+				slog.String("remote_ip", v.RemoteIP),
+			)
+			return nil
+		},
+	})
+}
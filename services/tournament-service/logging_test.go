@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs sends slog's records to a buffer for the duration of the test.
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestRequestLogging(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	e := echo.New()
+	e.Use(RequestIDMiddleware(), RequestLogMiddleware(), IdentityMiddleware(nil))
+	e.GET("/health", HealthCheckHandler)
+	e.POST("/tournaments/:id/register", func(c echo.Context) error {
+		slog.ErrorContext(c.Request().Context(), "registration failed", "email", "jane@example.com")
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to register"})
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	req := httptest.NewRequest(http.MethodPost, "/tournaments/t1/register", nil)
+	req.Header.Set(echo.HeaderXRequestID, "gw-req-1") // Set by api-gateway
+	req.Header.Set("X-User-Id", "user-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "gw-req-1", rec.Header().Get(echo.HeaderXRequestID))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, "health checks are logged at debug level only")
+	var handler, access map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &handler))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+
+	assert.Equal(t, "registration failed", handler["msg"])
+	assert.Equal(t, "gw-req-1", handler["request_id"])
+	assert.Equal(t, "user-1", handler["user_id"])
+	assert.Equal(t, "[redacted]", handler["email"])
+
+	assert.Equal(t, "ERROR", access["level"])
+	assert.Equal(t, "tournament-service", access["service"])
+	assert.Equal(t, "/tournaments/:id/register", access["route"])
+	assert.Equal(t, float64(http.StatusInternalServerError), access["status"])
+	assert.Equal(t, "gw-req-1", access["request_id"])
+	assert.Equal(t, "user-1", access["user_id"])
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"log"
+	"os"
 )
 
 func main() {
+	// JSON logs; the level comes from LOG_LEVEL
+	InitLogging()
+
+	// Tracing, continuing the traces api-gateway starts
+	shutdownTracing, err := InitTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Could not init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Connect to Dependencies
 	// Database
 	dbPool, err := ConnectDB()
@@ -23,13 +35,29 @@ func main() {
 	defer rmq.Conn.Close()
 	defer rmq.Channel.Close()
 
+	// Identity of callers, as asserted by api-gateway
+	assertions, err := IdentityVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Identity assertion config error: %v", err)
+	}
+
+	// Final standings for payouts come from the bracket
+	bracketServiceURL := os.Getenv("BRACKET_SERVICE_URL")
+	if bracketServiceURL == "" {
+		bracketServiceURL = "http://bracket-service.t-hub-dev.svc.cluster.local:8080"
+	}
+	brackets := &BracketClient{BaseURL: bracketServiceURL}
+
 	// Setup Echo
 	e := echo.New()
 
-	// Middleware (Logging, Recover)
-	e.Use(middleware.Logger())
+	// Middleware (Tracing, Logging, Recover)
+	e.Use(TracingMiddleware())
+	e.Use(RequestIDMiddleware())
+	e.Use(RequestLogMiddleware())
 	e.Use(middleware.Recover())
 	e.Use(MetricsMiddleware)
+	e.Use(IdentityMiddleware(assertions))
 
 	// Routes
 	e.GET("/health", HealthCheckHandler)
@@ -40,11 +68,31 @@ func main() {
 
 	e.POST("/tournaments/:id/register", RegisterTournamentHandler(dbPool))
 	e.GET("/tournaments/:id/participants", GetParticipantsHandler(dbPool))
+	e.POST("/tournaments/:id/check-in", CheckInHandler(dbPool))
+	e.POST("/tournaments/:id/participants/:participantId/disqualify", DisqualifyParticipantHandler(dbPool, rmq))
+
+	// Entry fees & prize payouts (marked manually by the organizer)
+	e.GET("/tournaments/:id/payments", GetFeePaymentsHandler(dbPool))
+	e.PATCH("/tournaments/:id/participants/:participantId/payment", MarkFeePaidHandler(dbPool))
+	e.POST("/tournaments/:id/payouts", FinalizePayoutsHandler(dbPool, brackets))
+	e.GET("/tournaments/:id/payouts", GetPayoutsHandler(dbPool))
+	e.PATCH("/tournaments/:id/payouts/:participantId", MarkPayoutPaidHandler(dbPool))
+
+	// Rules, announcements & staff roles
+	e.GET("/tournaments/:id/rules", GetRulesHandler(dbPool))
+	e.GET("/tournaments/:id/rules/versions", GetRuleVersionsHandler(dbPool))
+	e.PUT("/tournaments/:id/rules", PublishRulesHandler(dbPool, rmq))
+	e.GET("/tournaments/:id/announcements", GetAnnouncementsHandler(dbPool))
+	e.POST("/tournaments/:id/announcements", PostAnnouncementHandler(dbPool, rmq))
+	e.GET("/tournaments/:id/staff", GetStaffHandler(dbPool))
+	e.PUT("/tournaments/:id/staff/:userId", GrantStaffRoleHandler(dbPool, rmq))
+	e.DELETE("/tournaments/:id/staff/:userId", RevokeStaffRoleHandler(dbPool, rmq))
 	
 	// Updaters
 	e.PATCH("/tournaments/:id/status", UpdateTournamentStatusHandler(dbPool, rmq))
 	e.GET("/tournaments/:id", GetTournamentHandler(dbPool))
 	e.PUT("/tournaments/:id", UpdateTournamentDetailsHandler(dbPool, rmq))
+	e.PATCH("/tournaments/:id", UpdateTournamentDetailsHandler(dbPool, rmq))
 
 
 	// Start Server
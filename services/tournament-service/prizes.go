@@ -0,0 +1,560 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// --- Data Models ---
+
+// PrizeShare is the percentage of the prize pool paid to one final placement.
+type PrizeShare struct {
+	Placement  int     `json:"placement"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Standing is the final placement of a participant. Tied participants share a placement
+// (e.g. both losing semi-finalists are 3rd when there is no third-place match).
+type Standing struct {
+	ParticipantID string `json:"participant_id"`
+	Placement     int    `json:"placement"`
+}
+
+type Payout struct {
+	TournamentID  string     `json:"tournament_id"`
+	ParticipantID string     `json:"participant_id"`
+	Placement     int        `json:"placement"`
+	AmountCents   int64      `json:"amount_cents"`
+	Currency      string     `json:"currency"`
+	Status        string     `json:"status"` // pending, paid
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
+
+const defaultCurrency = "EUR"
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Allowed rounding slack when checking that the distribution adds up to 100%.
+const percentageEpsilon = 0.001
+
+// --- Validation ---
+
+// validatePrizeFields checks the money related fields of a tournament as they will be stored.
+func validatePrizeFields(errs *ValidationErrors, entryFee, prizePool int64, currency string, dist []PrizeShare, maxParticipants int) {
+	if entryFee < 0 {
+		errs.Add("entry_fee_cents", "Entry fee cannot be negative")
+	}
+	if prizePool < 0 {
+		errs.Add("prize_pool_cents", "Prize pool cannot be negative")
+	}
+	if !currencyPattern.MatchString(currency) {
+		errs.Add("currency", "Currency must be a 3 letter ISO 4217 code")
+	}
+
+	if len(dist) == 0 {
+		if prizePool > 0 {
+			errs.Add("prize_distribution", "Prize distribution is required when a prize pool is set")
+		}
+		return
+	}
+
+	seen := make(map[int]bool)
+	total := 0.0
+	for _, s := range dist {
+		if s.Placement < 1 || (maxParticipants > 0 && s.Placement > maxParticipants) {
+			errs.Add("prize_distribution", "Placements must be between 1 and max participants")
+			return
+		}
+		if seen[s.Placement] {
+			errs.Add("prize_distribution", "Each placement can only appear once")
+			return
+		}
+		if s.Percentage <= 0 {
+			errs.Add("prize_distribution", "Percentages must be greater than 0")
+			return
+		}
+		seen[s.Placement] = true
+		total += s.Percentage
+	}
+	if math.Abs(total-100) > percentageEpsilon {
+		errs.Add("prize_distribution", "Prize distribution must sum to 100%")
+	}
+}
+
+// --- Payout Calculation ---
+
+// ComputePayouts splits the prize pool over the final standings.
+// Participants tied on a placement share the percentages of all places they cover,
+// e.g. two players tied for 3rd split the 3rd and 4th place shares equally.
+// Leftover cents from rounding go to the best placed participants first so the
+// payouts always add up to the distributed amount exactly.
+func ComputePayouts(prizePool int64, dist []PrizeShare, standings []Standing) []Payout {
+	pct := make(map[int]float64, len(dist))
+	for _, s := range dist {
+		pct[s.Placement] = s.Percentage
+	}
+
+	sorted := make([]Standing, len(standings))
+	copy(sorted, standings)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Placement < sorted[j].Placement })
+
+	payouts := make([]Payout, 0, len(sorted))
+	var exact []float64
+	distributedPct := 0.0
+
+	for i := 0; i < len(sorted); {
+		// Find the group of participants tied on this placement
+		j := i
+		for j < len(sorted) && sorted[j].Placement == sorted[i].Placement {
+			j++
+		}
+		tied := j - i
+
+		groupPct := 0.0
+		for place := sorted[i].Placement; place < sorted[i].Placement+tied; place++ {
+			groupPct += pct[place]
+		}
+		distributedPct += groupPct
+
+		share := float64(prizePool) * groupPct / 100 / float64(tied)
+		for k := i; k < j; k++ {
+			payouts = append(payouts, Payout{
+				ParticipantID: sorted[k].ParticipantID,
+				Placement:     sorted[k].Placement,
+				AmountCents:   int64(math.Floor(share)),
+				Status:        "pending",
+			})
+			exact = append(exact, share)
+		}
+		i = j
+	}
+
+	// Hand out the cents lost to flooring
+	target := int64(math.Round(float64(prizePool) * distributedPct / 100))
+	if target > prizePool {
+		target = prizePool
+	}
+	var paid int64
+	for _, p := range payouts {
+		paid += p.AmountCents
+	}
+	for k := 0; paid < target && k < len(payouts); k++ {
+		if exact[k] > 0 {
+			payouts[k].AmountCents++
+			paid++
+		}
+	}
+
+	return payouts
+}
+
+// --- Handlers ---
+
+type FeePaymentRequest struct {
+	Paid bool `json:"paid"`
+}
+
+// MarkFeePaidHandler lets an organizer record (or revoke) a manual entry fee payment.
+func MarkFeePaidHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		participantID := c.Param("participantId")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req FeePaymentRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+
+		updateQuery := `
+			UPDATE registrations
+			SET fee_paid = $1, fee_paid_at = CASE WHEN $1 THEN NOW() ELSE NULL END
+			WHERE tournament_id = $2 AND participant_id = $3
+		`
+		tag, err := db.Exec(c.Request().Context(), updateQuery, req.Paid, tournamentID, participantID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database update failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update payment"})
+		}
+		if tag.RowsAffected() == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Participant not registered"})
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{"participant_id": participantID, "fee_paid": req.Paid})
+	}
+}
+
+// FeePayment is the entry fee state of one registration, as the organizer sees it.
+type FeePayment struct {
+	ParticipantID   string     `json:"participant_id"`
+	ParticipantName string     `json:"participant_name"`
+	FeePaid         bool       `json:"fee_paid"`
+	FeePaidAt       *time.Time `json:"fee_paid_at,omitempty"`
+}
+
+// GetFeePaymentsHandler lists which participants have paid their entry fee. Payment state is
+// not part of the public participant list, so only the organizer and co-organizers see it.
+func GetFeePaymentsHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+
+		paymentsQuery := `
+			SELECT participant_id, participant_name, fee_paid, fee_paid_at
+			FROM registrations
+			WHERE tournament_id = $1
+			ORDER BY participant_name ASC
+		`
+		rows, err := db.Query(c.Request().Context(), paymentsQuery, tournamentID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch payments"})
+		}
+		defer rows.Close()
+
+		payments := []FeePayment{}
+		for rows.Next() {
+			var p FeePayment
+			if err := rows.Scan(&p.ParticipantID, &p.ParticipantName, &p.FeePaid, &p.FeePaidAt); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process payments"})
+			}
+			payments = append(payments, p)
+		}
+
+		return c.JSON(http.StatusOK, payments)
+	}
+}
+
+type CheckInRequest struct {
+	TeamID string `json:"team_id"` // Only for team tournaments
+}
+
+// CheckInHandler confirms a registered participant will show up. Paid tournaments
+// require the entry fee to be marked paid first.
+func CheckInHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		userID := c.Request().Header.Get("X-User-Id")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req CheckInRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+
+		var status, participantType string
+		var entryFee int64
+		query := `SELECT status, participant_type, entry_fee_cents FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&status, &participantType, &entryFee); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if status != "registration_open" && status != "registration_closed" {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Check-in is not open for this tournament"})
+		}
+
+		participantID := userID
+		if participantType == string(ParticipantTeam) {
+			if req.TeamID == "" {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "This is a team tournament. Team ID is required."})
+			}
+			participantID = req.TeamID
+		}
+
+		var feePaid bool
+		regQuery := `SELECT fee_paid FROM registrations WHERE tournament_id = $1 AND participant_id = $2`
+		err := db.QueryRow(c.Request().Context(), regQuery, tournamentID, participantID).Scan(&feePaid)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "You are not registered for this tournament"})
+		}
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check registration"})
+		}
+
+		if entryFee > 0 && !feePaid {
+			return c.JSON(http.StatusPaymentRequired, map[string]string{"error": "Entry fee must be paid before check-in"})
+		}
+
+		updateQuery := `UPDATE registrations SET checked_in = true, checked_in_at = NOW() WHERE tournament_id = $1 AND participant_id = $2`
+		if _, err := db.Exec(c.Request().Context(), updateQuery, tournamentID, participantID); err != nil {
+			slog.ErrorContext(c.Request().Context(), "database update failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check in"})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"message": "Checked in", "participant_id": participantID})
+	}
+}
+
+// --- Final Standings ---
+
+// Upper bound for standings lookups against bracket-service.
+const bracketLookupTimeout = 5 * time.Second
+
+// errNoFinalStandings means the tournament has no bracket or its deciding matches are still open.
+var errNoFinalStandings = errors.New("bracket has no final standings yet")
+
+// BracketClient reads final standings from bracket-service, which decides them from the match results.
+type BracketClient struct {
+	BaseURL string
+}
+
+// FinalStandings returns the placements of a finished bracket. Participants knocked out in the
+// same round share their best placement, e.g. both losing semi-finalists are 3rd.
+func (b *BracketClient) FinalStandings(ctx context.Context, tournamentID string) ([]Standing, error) {
+	ctx, cancel := context.WithTimeout(ctx, bracketLookupTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.BaseURL+"/brackets/"+url.PathEscape(tournamentID)+"/placements", nil)
+	if err != nil {
+		return nil, err
+	}
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(echo.HeaderXRequestID, id)
+	}
+	resp, err := tracedClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errNoFinalStandings
+	default:
+		return nil, fmt.Errorf("placements lookup returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Complete   bool       `json:"complete"`
+		Placements []Standing `json:"placements"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if !body.Complete || len(body.Placements) == 0 {
+		return nil, errNoFinalStandings
+	}
+	return body.Placements, nil
+}
+
+// FinalizePayoutsHandler computes the payouts of a completed tournament from the final placements
+// of its bracket and stores them. It can be re-run until the first payout is marked paid.
+func FinalizePayoutsHandler(db DBClient, brackets *BracketClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		// Permissions are checked before asking bracket-service, and without holding the tournament lock
+		ctx := c.Request().Context()
+		var t Tournament
+		query := `SELECT id, organizer_id, status FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(ctx, query, tournamentID).Scan(&t.ID, &t.OrganizerID, &t.Status); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+		if !canManageTournament(ctx, db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+		if t.Status != "completed" {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Payouts can only be computed once the tournament is completed"})
+		}
+
+		standings, err := brackets.FinalStandings(ctx, tournamentID)
+		if errors.Is(err, errNoFinalStandings) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "The bracket has no final standings yet"})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "final standings lookup failed", "error", err)
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to fetch final standings"})
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to start transaction", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+		defer tx.Rollback(ctx)
+
+		// Locked so the prize terms cannot change while payouts are computed from them. The status is
+		// read again in case the tournament was reopened after the check above.
+		query = `SELECT status, prize_pool_cents, currency, prize_distribution FROM tournaments WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRow(ctx, query, tournamentID).Scan(&t.Status, &t.PrizePoolCents, &t.Currency, &t.PrizeDistribution); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+		if t.Status != "completed" {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Payouts can only be computed once the tournament is completed"})
+		}
+
+		// Every placed participant must actually be registered
+		var registered int
+		ids := make([]string, len(standings))
+		for i, s := range standings {
+			ids[i] = s.ParticipantID
+		}
+		countQuery := `SELECT count(*) FROM registrations WHERE tournament_id = $1 AND participant_id = ANY($2)`
+		if err := tx.QueryRow(ctx, countQuery, tournamentID, ids).Scan(&registered); err != nil {
+			slog.ErrorContext(ctx, "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check participants"})
+		}
+		if registered != len(ids) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Final standings contain participants that are not registered"})
+		}
+
+		var alreadyPaid int
+		paidQuery := `SELECT count(*) FROM payouts WHERE tournament_id = $1 AND status = 'paid'`
+		if err := tx.QueryRow(ctx, paidQuery, tournamentID).Scan(&alreadyPaid); err != nil {
+			slog.ErrorContext(ctx, "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check payouts"})
+		}
+		if alreadyPaid > 0 {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Payouts have already been paid out"})
+		}
+
+		payouts := ComputePayouts(t.PrizePoolCents, t.PrizeDistribution, standings)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM payouts WHERE tournament_id = $1`, tournamentID); err != nil {
+			slog.ErrorContext(ctx, "database delete failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset payouts"})
+		}
+
+		insertQuery := `
+			INSERT INTO payouts (tournament_id, participant_id, placement, amount_cents, currency, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		for i := range payouts {
+			payouts[i].TournamentID = tournamentID
+			payouts[i].Currency = t.Currency
+			p := payouts[i]
+			if _, err := tx.Exec(ctx, insertQuery, p.TournamentID, p.ParticipantID, p.Placement, p.AmountCents, p.Currency, p.Status); err != nil {
+				slog.ErrorContext(ctx, "database insert failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save payouts"})
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			slog.ErrorContext(ctx, "commit failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+		}
+
+		return c.JSON(http.StatusOK, payouts)
+	}
+}
+
+func GetPayoutsHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+
+		query := `
+			SELECT tournament_id, participant_id, placement, amount_cents, currency, status, paid_at
+			FROM payouts
+			WHERE tournament_id = $1
+			ORDER BY placement ASC, participant_id ASC
+		`
+		rows, err := db.Query(c.Request().Context(), query, tournamentID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch payouts"})
+		}
+		defer rows.Close()
+
+		payouts := []Payout{}
+		for rows.Next() {
+			var p Payout
+			if err := rows.Scan(&p.TournamentID, &p.ParticipantID, &p.Placement, &p.AmountCents, &p.Currency, &p.Status, &p.PaidAt); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process payouts"})
+			}
+			payouts = append(payouts, p)
+		}
+
+		return c.JSON(http.StatusOK, payouts)
+	}
+}
+
+// MarkPayoutPaidHandler records that the organizer has paid a prize out manually.
+func MarkPayoutPaidHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		participantID := c.Param("participantId")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+
+		updateQuery := `UPDATE payouts SET status = 'paid', paid_at = NOW() WHERE tournament_id = $1 AND participant_id = $2`
+		tag, err := db.Exec(c.Request().Context(), updateQuery, tournamentID, participantID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database update failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update payout"})
+		}
+		if tag.RowsAffected() == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Payout not found"})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"message": "Payout marked as paid"})
+	}
+}
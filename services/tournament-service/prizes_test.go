@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePrizeFields(t *testing.T) {
+	// Valid: 60/30/10 split
+	var errs ValidationErrors
+	validatePrizeFields(&errs, 500, 10000, "EUR", []PrizeShare{{1, 60}, {2, 30}, {3, 10}}, 8)
+	assert.False(t, errs.HasErrors())
+
+	// Does not add up to 100%
+	errs = nil
+	validatePrizeFields(&errs, 0, 10000, "EUR", []PrizeShare{{1, 60}, {2, 30}}, 8)
+	assert.Equal(t, ValidationErrors{{Field: "prize_distribution", Message: "Prize distribution must sum to 100%"}}, errs)
+
+	// A prize pool without a distribution
+	errs = nil
+	validatePrizeFields(&errs, 0, 10000, "EUR", nil, 8)
+	assert.Equal(t, "prize_distribution", errs[0].Field)
+
+	// Placement beyond the number of participants, bad currency, negative fee
+	errs = nil
+	validatePrizeFields(&errs, -1, 100, "euro", []PrizeShare{{1, 50}, {9, 50}}, 8)
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"entry_fee_cents", "currency", "prize_distribution"}, fields)
+}
+
+func TestComputePayouts_TiesShareCoveredPlaces(t *testing.T) {
+	dist := []PrizeShare{{1, 50}, {2, 25}, {3, 15}, {4, 10}}
+	standings := []Standing{
+		{ParticipantID: "c", Placement: 3},
+		{ParticipantID: "a", Placement: 1},
+		{ParticipantID: "d", Placement: 3},
+		{ParticipantID: "b", Placement: 2},
+	}
+
+	payouts := ComputePayouts(10000, dist, standings)
+
+	amounts := map[string]int64{}
+	for _, p := range payouts {
+		amounts[p.ParticipantID] = p.AmountCents
+		assert.Equal(t, "pending", p.Status)
+	}
+	assert.Equal(t, int64(5000), amounts["a"])
+	assert.Equal(t, int64(2500), amounts["b"])
+	// 3rd and 4th place shares (15% + 10%) are split between the tied semi-finalists
+	assert.Equal(t, int64(1250), amounts["c"])
+	assert.Equal(t, int64(1250), amounts["d"])
+}
+
+func TestComputePayouts_RoundingNeverLosesCents(t *testing.T) {
+	dist := []PrizeShare{{1, 33.34}, {2, 33.33}, {3, 33.33}}
+	standings := []Standing{{"a", 1}, {"b", 2}, {"c", 3}}
+
+	payouts := ComputePayouts(1001, dist, standings)
+
+	var total int64
+	for _, p := range payouts {
+		total += p.AmountCents
+	}
+	assert.Equal(t, int64(1001), total)
+	// Leftover cents go to the best placed participant first
+	assert.Equal(t, "a", payouts[0].ParticipantID)
+	assert.GreaterOrEqual(t, payouts[0].AmountCents, payouts[1].AmountCents)
+}
+
+func TestCheckInHandler_RequiresPaidFee(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-paid"
+	userID := "user-1"
+
+	mockDB.ExpectQuery("SELECT status, participant_type, entry_fee_cents FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"status", "participant_type", "entry_fee_cents"}).
+			AddRow("registration_closed", "individual", int64(500)))
+
+	mockDB.ExpectQuery("SELECT fee_paid FROM registrations").
+		WithArgs(tournamentID, userID).
+		WillReturnRows(pgxmock.NewRows([]string{"fee_paid"}).AddRow(false))
+
+	// Expect NO check-in update
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", userID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = CheckInHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Entry fee must be paid")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestCheckInHandler_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-paid"
+	userID := "user-1"
+
+	mockDB.ExpectQuery("SELECT status, participant_type, entry_fee_cents FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"status", "participant_type", "entry_fee_cents"}).
+			AddRow("registration_closed", "individual", int64(500)))
+
+	mockDB.ExpectQuery("SELECT fee_paid FROM registrations").
+		WithArgs(tournamentID, userID).
+		WillReturnRows(pgxmock.NewRows([]string{"fee_paid"}).AddRow(true))
+
+	mockDB.ExpectExec("UPDATE registrations SET checked_in = true").
+		WithArgs(tournamentID, userID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", userID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = CheckInHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetFeePaymentsHandler_CoOrganizer(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-paid"
+	paidAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs(tournamentID, "co-org").
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("co_organizer"))
+	mockDB.ExpectQuery("SELECT participant_id, participant_name, fee_paid, fee_paid_at FROM registrations").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"participant_id", "participant_name", "fee_paid", "fee_paid_at"}).
+			AddRow("user-1", "Alice", true, &paidAt).
+			AddRow("user-2", "Bob", false, (*time.Time)(nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User-Id", "co-org")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = GetFeePaymentsHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var payments []FeePayment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payments))
+	assert.Len(t, payments, 2)
+	assert.True(t, payments[0].FeePaid)
+	assert.True(t, paidAt.Equal(*payments[0].FeePaidAt))
+	assert.False(t, payments[1].FeePaid)
+	assert.Nil(t, payments[1].FeePaidAt)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetFeePaymentsHandler_ParticipantForbidden(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-paid"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs(tournamentID, "user-1").
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User-Id", "user-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = GetFeePaymentsHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// newBracketServiceMock answers the placements lookup of FinalizePayoutsHandler.
+func newBracketServiceMock(complete bool, placements []Standing) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"complete": complete, "placements": placements})
+	}))
+}
+
+func TestFinalizePayoutsHandler_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-done"
+	organizerID := "user-admin"
+
+	bsMock := newBracketServiceMock(true, []Standing{{"p1", 1}, {"p2", 2}})
+	defer bsMock.Close()
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).AddRow(tournamentID, organizerID, "completed"))
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery("SELECT status, prize_pool_cents, currency, prize_distribution FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"status", "prize_pool_cents", "currency", "prize_distribution"}).
+			AddRow("completed", int64(10000), "EUR", []PrizeShare{{1, 70}, {2, 30}}))
+	mockDB.ExpectQuery("SELECT count\\(\\*\\) FROM registrations").
+		WithArgs(tournamentID, []string{"p1", "p2"}).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+	mockDB.ExpectQuery("SELECT count\\(\\*\\) FROM payouts").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mockDB.ExpectExec("DELETE FROM payouts").
+		WithArgs(tournamentID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mockDB.ExpectExec("INSERT INTO payouts").
+		WithArgs(tournamentID, "p1", 1, int64(7000), "EUR", "pending").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec("INSERT INTO payouts").
+		WithArgs(tournamentID, "p2", 2, int64(3000), "EUR", "pending").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+
+	// Placements posted by the caller are ignored, the bracket decides
+	body := `{"placements": [{"participant_id": "p2", "placement": 1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = FinalizePayoutsHandler(mockDB, &BracketClient{BaseURL: bsMock.URL})(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var payouts []Payout
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payouts))
+	assert.Len(t, payouts, 2)
+	assert.Equal(t, "p1", payouts[0].ParticipantID)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestFinalizePayoutsHandler_NotCompleted(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-running"
+	organizerID := "user-admin"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).AddRow(tournamentID, organizerID, "ongoing"))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = FinalizePayoutsHandler(mockDB, &BracketClient{})(c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestFinalizePayoutsHandler_ReopenedWhileLocking(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-done"
+	organizerID := "user-admin"
+
+	bsMock := newBracketServiceMock(true, []Standing{{"p1", 1}, {"p2", 2}})
+	defer bsMock.Close()
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).AddRow(tournamentID, organizerID, "completed"))
+	mockDB.ExpectBegin()
+	// Moved back to ongoing between the permission check and the lock
+	mockDB.ExpectQuery("SELECT status, prize_pool_cents, currency, prize_distribution FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"status", "prize_pool_cents", "currency", "prize_distribution"}).
+			AddRow("ongoing", int64(10000), "EUR", []PrizeShare{{1, 70}, {2, 30}}))
+	mockDB.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = FinalizePayoutsHandler(mockDB, &BracketClient{BaseURL: bsMock.URL})(c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestFinalizePayoutsHandler_BracketNotFinished(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-done"
+	organizerID := "user-admin"
+
+	// The tournament was marked completed before its final was played
+	bsMock := newBracketServiceMock(false, []Standing{{"p3", 3}, {"p4", 3}})
+	defer bsMock.Close()
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).AddRow(tournamentID, organizerID, "completed"))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = FinalizePayoutsHandler(mockDB, &BracketClient{BaseURL: bsMock.URL})(c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no final standings")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
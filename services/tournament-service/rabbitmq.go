@@ -3,11 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // Constants for RabbitMQ
@@ -18,7 +19,7 @@ const (
 
 // EventPublisher interface allows us to mock RabbitMQ
 type EventPublisher interface {
-	Publish(routingKey string, body string) error
+	Publish(ctx context.Context, routingKey string, body string) error
 }
 
 // Service holds the connection and channel
@@ -68,14 +69,18 @@ func Connect() (*Service, error) {
 		return nil, fmt.Errorf("failed to declare an exchange: %w", err)
 	}
 
-	log.Println("Connected to RabbitMQ and exchange declared")
+	slog.Info("connected to RabbitMQ and exchange declared")
 	return &Service{Conn: conn, Channel: ch}, nil
 }
 
-// Publish sends a message to the configured exchange with a routing key
+// Publish sends a message to the configured exchange with a routing key.
+// The trace of ctx travels along in the message headers; ctx being canceled does not stop the publish.
+func (s *Service) Publish(ctx context.Context, routingKey string, body string) error {
+	headers := amqp.Table{}
+	ctx, span := startPublishSpan(ctx, routingKey, headers)
+	defer span.End()
 
-func (s *Service) Publish(routingKey string, body string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
 	err := s.Channel.PublishWithContext(ctx,
@@ -85,14 +90,17 @@ func (s *Service) Publish(routingKey string, body string) error {
 		false,        // immediate
 		amqp.Publishing{
 			ContentType: "application/json",
+			Headers:     headers,
 			Body:        []byte(body),
 		})
 
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	log.Printf("[x] Sent to %s: %s", routingKey, body)
+	slog.DebugContext(ctx, "event published", "routing_key", routingKey)
 
 	return nil
 }
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// --- Data Models ---
+
+// RuleSet is one version of a tournament's rules. Content is markdown and is
+// rendered by the frontend; old versions are kept so participants can see what changed.
+type RuleSet struct {
+	TournamentID string    `json:"tournament_id"`
+	Version      int       `json:"version"`
+	Content      string    `json:"content"`
+	Changelog    string    `json:"changelog"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Announcement struct {
+	ID           string    `json:"id"`
+	TournamentID string    `json:"tournament_id"`
+	AuthorID     string    `json:"author_id"`
+	Title        string    `json:"title"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Keeps a single rules document/announcement within a sane size.
+const (
+	maxRulesLength        = 50000
+	maxAnnouncementLength = 5000
+)
+
+// --- Rules ---
+
+// GetRulesHandler returns the latest rules, or a specific one with ?version=N.
+func GetRulesHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+
+		query := `
+			SELECT tournament_id, version, content, COALESCE(changelog, ''), created_by, created_at
+			FROM tournament_rules
+			WHERE tournament_id = $1
+			ORDER BY version DESC
+			LIMIT 1
+		`
+		args := []any{tournamentID}
+
+		if v := c.QueryParam("version"); v != "" {
+			version, err := strconv.Atoi(v)
+			if err != nil || version < 1 {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid version"})
+			}
+			query = `
+				SELECT tournament_id, version, content, COALESCE(changelog, ''), created_by, created_at
+				FROM tournament_rules
+				WHERE tournament_id = $1 AND version = $2
+			`
+			args = append(args, version)
+		}
+
+		var r RuleSet
+		err := db.QueryRow(c.Request().Context(), query, args...).Scan(
+			&r.TournamentID, &r.Version, &r.Content, &r.Changelog, &r.CreatedBy, &r.CreatedAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "No rules published"})
+		}
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch rules"})
+		}
+
+		return c.JSON(http.StatusOK, r)
+	}
+}
+
+// GetRuleVersionsHandler lists every version without the content.
+func GetRuleVersionsHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+
+		query := `
+			SELECT tournament_id, version, COALESCE(changelog, ''), created_by, created_at
+			FROM tournament_rules
+			WHERE tournament_id = $1
+			ORDER BY version DESC
+		`
+		rows, err := db.Query(c.Request().Context(), query, tournamentID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch rule versions"})
+		}
+		defer rows.Close()
+
+		versions := []RuleSet{}
+		for rows.Next() {
+			var r RuleSet
+			if err := rows.Scan(&r.TournamentID, &r.Version, &r.Changelog, &r.CreatedBy, &r.CreatedAt); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process rule versions"})
+			}
+			versions = append(versions, r)
+		}
+
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+type PublishRulesRequest struct {
+	Content   string `json:"content"`
+	Changelog string `json:"changelog"`
+}
+
+// PublishRulesHandler stores a new version of the rules. Versions are never edited in place.
+func PublishRulesHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req PublishRulesRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+
+		var errs ValidationErrors
+		if strings.TrimSpace(req.Content) == "" {
+			errs.Add("content", "Content is required")
+		} else if len(req.Content) > maxRulesLength {
+			errs.Add("content", "Content must be at most 50000 characters")
+		}
+		if len(req.Changelog) > 500 {
+			errs.Add("changelog", "Changelog must be at most 500 characters")
+		}
+		if errs.HasErrors() {
+			return validationFailed(c, errs)
+		}
+
+		ctx := c.Request().Context()
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to start transaction", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+		defer tx.Rollback(ctx)
+
+		// Lock the tournament so concurrent publishes get distinct versions
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRow(ctx, query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(ctx, tx, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+
+		r := RuleSet{TournamentID: tournamentID, Content: req.Content, Changelog: req.Changelog, CreatedBy: userID}
+		insertQuery := `
+			INSERT INTO tournament_rules (tournament_id, version, content, changelog, created_by)
+			SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM tournament_rules WHERE tournament_id = $1
+			RETURNING version, created_at
+		`
+		err = tx.QueryRow(ctx, insertQuery, tournamentID, req.Content, req.Changelog, userID).Scan(&r.Version, &r.CreatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "database insert failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save rules"})
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			slog.ErrorContext(ctx, "commit failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+		}
+
+		event := Event{
+			EventType: "TournamentRulesUpdated",
+			Payload: map[string]interface{}{
+				"tournament_id": tournamentID,
+				"version":       r.Version,
+				"changelog":     r.Changelog,
+				"updated_by":    userID,
+			},
+			Timestamp: time.Now(),
+		}
+		eventBytes, _ := json.Marshal(event)
+		_ = rmq.Publish(c.Request().Context(), "events.tournament.rules_updated", string(eventBytes))
+
+		return c.JSON(http.StatusCreated, r)
+	}
+}
+
+// --- Announcements ---
+
+func GetAnnouncementsHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+
+		query := `
+			SELECT id, tournament_id, author_id, title, body, created_at
+			FROM announcements
+			WHERE tournament_id = $1
+			ORDER BY created_at DESC
+		`
+		rows, err := db.Query(c.Request().Context(), query, tournamentID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch announcements"})
+		}
+		defer rows.Close()
+
+		announcements := []Announcement{}
+		for rows.Next() {
+			var a Announcement
+			if err := rows.Scan(&a.ID, &a.TournamentID, &a.AuthorID, &a.Title, &a.Body, &a.CreatedAt); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process announcements"})
+			}
+			announcements = append(announcements, a)
+		}
+
+		return c.JSON(http.StatusOK, announcements)
+	}
+}
+
+type AnnouncementRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// PostAnnouncementHandler stores an announcement and publishes it so participants can be notified.
+func PostAnnouncementHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req AnnouncementRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+
+		var errs ValidationErrors
+		if strings.TrimSpace(req.Title) == "" {
+			errs.Add("title", "Title is required")
+		} else if len(req.Title) > 200 {
+			errs.Add("title", "Title must be at most 200 characters")
+		}
+		if strings.TrimSpace(req.Body) == "" {
+			errs.Add("body", "Body is required")
+		} else if len(req.Body) > maxAnnouncementLength {
+			errs.Add("body", "Body must be at most 5000 characters")
+		}
+		if errs.HasErrors() {
+			return validationFailed(c, errs)
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
+		}
+
+		a := Announcement{
+			ID:           uuid.New().String(),
+			TournamentID: tournamentID,
+			AuthorID:     userID,
+			Title:        req.Title,
+			Body:         req.Body,
+			CreatedAt:    time.Now(),
+		}
+		insertQuery := `
+			INSERT INTO announcements (id, tournament_id, author_id, title, body, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		_, err := db.Exec(c.Request().Context(), insertQuery, a.ID, a.TournamentID, a.AuthorID, a.Title, a.Body, a.CreatedAt)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database insert failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save announcement"})
+		}
+
+		// Event: TournamentAnnouncement (consumers fan this out to registered participants)
+		event := Event{
+			EventType: "TournamentAnnouncement",
+			Payload:   a,
+			Timestamp: time.Now(),
+		}
+		eventBytes, _ := json.Marshal(event)
+		if err := rmq.Publish(c.Request().Context(), "events.tournament.announcement", string(eventBytes)); err != nil {
+			slog.ErrorContext(c.Request().Context(), "failed to publish event", "error", err)
+		}
+
+		return c.JSON(http.StatusCreated, a)
+	}
+}
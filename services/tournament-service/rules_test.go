@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishRulesHandler_NewVersion(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{}
+
+	tournamentID := "tourn-123"
+	organizerID := "organizer"
+	content := "# Rules\n\n1. Be nice"
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments .* FOR UPDATE").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, organizerID))
+	mockDB.ExpectQuery("INSERT INTO tournament_rules").
+		WithArgs(tournamentID, content, "Added rule 1", organizerID).
+		WillReturnRows(pgxmock.NewRows([]string{"version", "created_at"}).AddRow(3, time.Now()))
+	mockDB.ExpectCommit()
+
+	body := `{"content": "# Rules\n\n1. Be nice", "changelog": "Added rule 1"}`
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = PublishRulesHandler(mockDB, mockRMQ)(c)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"version":3`)
+	assert.Equal(t, "events.tournament.rules_updated", mockRMQ.LastKey)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetRulesHandler_SpecificVersion(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	mockDB.ExpectQuery("SELECT .* FROM tournament_rules .* version = \\$2").
+		WithArgs("tourn-123", 1).
+		WillReturnRows(pgxmock.NewRows([]string{"tournament_id", "version", "content", "changelog", "created_by", "created_at"}).
+			AddRow("tourn-123", 1, "Old rules", "", "organizer", time.Now()))
+
+	req := httptest.NewRequest(http.MethodGet, "/?version=1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("tourn-123")
+
+	_ = GetRulesHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Old rules")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetRulesHandler_NoneYet(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	mockDB.ExpectQuery("SELECT .* FROM tournament_rules").
+		WithArgs("tourn-123").
+		WillReturnError(pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("tourn-123")
+
+	_ = GetRulesHandler(mockDB)(c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPostAnnouncementHandler_Referee(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{}
+
+	tournamentID := "tourn-123"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs(tournamentID, "ref").
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("referee"))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"title":"Delay","body":"Round 2 starts at 19:00"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "ref")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = PostAnnouncementHandler(mockDB, mockRMQ)(c)
+
+	// Referees may not post announcements
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, mockRMQ.LastKey)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestPostAnnouncementHandler_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{Err: errors.New("broker down")}
+
+	tournamentID := "tourn-123"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+	mockDB.ExpectExec("INSERT INTO announcements").
+		WithArgs(pgxmock.AnyArg(), tournamentID, "organizer", "Delay", "Round 2 starts at 19:00", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"title":"Delay","body":"Round 2 starts at 19:00"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "organizer")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = PostAnnouncementHandler(mockDB, mockRMQ)(c)
+
+	// The announcement is stored even if the notification event cannot be sent
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "events.tournament.announcement", mockRMQ.LastKey)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
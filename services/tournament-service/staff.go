@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// --- Data Models ---
+
+// StaffRole is a per-tournament role granted by the organizer.
+type StaffRole string
+
+const (
+	// Co-organizers can manage the tournament like the organizer, except for granting roles.
+	StaffCoOrganizer StaffRole = "co_organizer"
+	// Referees can report and correct match results.
+	StaffReferee StaffRole = "referee"
+)
+
+func (r StaffRole) Valid() bool {
+	return r == StaffCoOrganizer || r == StaffReferee
+}
+
+type StaffMember struct {
+	TournamentID string    `json:"tournament_id"`
+	UserID       string    `json:"user_id"`
+	Role         StaffRole `json:"role"`
+	GrantedBy    string    `json:"granted_by"`
+	GrantedAt    time.Time `json:"granted_at"`
+}
+
+// staffRoleFor returns the role of a user on a tournament, or "" if they have none.
+func staffRoleFor(ctx context.Context, db RowQuerier, tournamentID, userID string) (StaffRole, error) {
+	if userID == "" {
+		return "", nil
+	}
+
+	var role string
+	query := `SELECT role FROM tournament_staff WHERE tournament_id = $1 AND user_id = $2`
+	err := db.QueryRow(ctx, query, tournamentID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return StaffRole(role), nil
+}
+
+// --- Handlers ---
+
+func GetStaffHandler(db DBClient) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+
+		query := `
+			SELECT tournament_id, user_id, role, granted_by, granted_at
+			FROM tournament_staff
+			WHERE tournament_id = $1
+			ORDER BY granted_at ASC
+		`
+		rows, err := db.Query(c.Request().Context(), query, tournamentID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch staff"})
+		}
+		defer rows.Close()
+
+		staff := []StaffMember{}
+		for rows.Next() {
+			var m StaffMember
+			var role string
+			if err := rows.Scan(&m.TournamentID, &m.UserID, &role, &m.GrantedBy, &m.GrantedAt); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process staff"})
+			}
+			m.Role = StaffRole(role)
+			staff = append(staff, m)
+		}
+
+		return c.JSON(http.StatusOK, staff)
+	}
+}
+
+type GrantRoleRequest struct {
+	Role StaffRole `json:"role"`
+}
+
+// GrantStaffRoleHandler grants (or changes) a user's role on a tournament.
+// Only the organizer or a SuperAdmin may hand out roles.
+func GrantStaffRoleHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		targetUserID := c.Param("userId")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var req GrantRoleRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+		if !req.Role.Valid() {
+			var errs ValidationErrors
+			errs.Add("role", "Role must be one of: "+joinEnum([]StaffRole{StaffCoOrganizer, StaffReferee}))
+			return validationFailed(c, errs)
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !isOrganizerOrAdmin(userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer can grant roles"})
+		}
+		if targetUserID == t.OrganizerID {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "The organizer already has full access"})
+		}
+
+		upsertQuery := `
+			INSERT INTO tournament_staff (tournament_id, user_id, role, granted_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tournament_id, user_id) DO UPDATE SET role = $3, granted_by = $4, granted_at = NOW()
+		`
+		if _, err := db.Exec(c.Request().Context(), upsertQuery, tournamentID, targetUserID, string(req.Role), userID); err != nil {
+			slog.ErrorContext(c.Request().Context(), "database insert failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to grant role"})
+		}
+
+		event := Event{
+			EventType: "TournamentStaffUpdated",
+			Payload: map[string]string{
+				"tournament_id": tournamentID,
+				"user_id":       targetUserID,
+				"role":          string(req.Role),
+				"updated_by":    userID,
+			},
+			Timestamp: time.Now(),
+		}
+		eventBytes, _ := json.Marshal(event)
+		_ = rmq.Publish(c.Request().Context(), "events.tournament.staff_updated", string(eventBytes))
+
+		return c.JSON(http.StatusOK, map[string]string{"message": "Role granted", "user_id": targetUserID, "role": string(req.Role)})
+	}
+}
+
+func RevokeStaffRoleHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tournamentID := c.Param("id")
+		targetUserID := c.Param("userId")
+		userID := c.Request().Header.Get("X-User-Id")
+		userRoles := c.Request().Header.Get("X-User-Roles")
+
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing authentication"})
+		}
+
+		var t Tournament
+		query := `SELECT id, organizer_id FROM tournaments WHERE id = $1`
+		if err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
+		}
+
+		if !isOrganizerOrAdmin(userID, userRoles, t) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the organizer can revoke roles"})
+		}
+
+		tag, err := db.Exec(c.Request().Context(), `DELETE FROM tournament_staff WHERE tournament_id = $1 AND user_id = $2`, tournamentID, targetUserID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "database delete failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke role"})
+		}
+		if tag.RowsAffected() == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User has no role on this tournament"})
+		}
+
+		event := Event{
+			EventType: "TournamentStaffUpdated",
+			Payload: map[string]string{
+				"tournament_id": tournamentID,
+				"user_id":       targetUserID,
+				"role":          "",
+				"updated_by":    userID,
+			},
+			Timestamp: time.Now(),
+		}
+		eventBytes, _ := json.Marshal(event)
+		_ = rmq.Publish(c.Request().Context(), "events.tournament.staff_updated", string(eventBytes))
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
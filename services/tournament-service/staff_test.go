@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanManageTournament(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tourn := Tournament{ID: "tourn-1", OrganizerID: "organizer"}
+	ctx := t.Context()
+
+	// Organizer and SuperAdmin never hit the database
+	assert.True(t, canManageTournament(ctx, mockDB, "organizer", "", tourn))
+	assert.True(t, canManageTournament(ctx, mockDB, "someone", "Player, SuperAdmin", tourn))
+
+	// Co-organizer is allowed
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs("tourn-1", "co-org").
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("co_organizer"))
+	assert.True(t, canManageTournament(ctx, mockDB, "co-org", "", tourn))
+
+	// Referee is not
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs("tourn-1", "ref").
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("referee"))
+	assert.False(t, canManageTournament(ctx, mockDB, "ref", "", tourn))
+
+	// Nobody else either
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs("tourn-1", "stranger").
+		WillReturnError(pgx.ErrNoRows)
+	assert.False(t, canManageTournament(ctx, mockDB, "stranger", "", tourn))
+
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateTournamentStatusHandler_CoOrganizer(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{}
+
+	tournamentID := "tourn-123"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
+			AddRow(tournamentID, "organizer", "draft"))
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs(tournamentID, "co-org").
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("co_organizer"))
+	mockDB.ExpectExec("UPDATE tournaments SET status").
+		WithArgs("registration_open", tournamentID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	req := httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"status":"registration_open"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "co-org")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	_ = UpdateTournamentStatusHandler(mockDB, mockRMQ)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGrantStaffRoleHandler_Success(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{}
+
+	tournamentID := "tourn-123"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+	mockDB.ExpectExec("INSERT INTO tournament_staff").
+		WithArgs(tournamentID, "ref-1", "referee", "organizer").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"role":"referee"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "organizer")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "userId")
+	c.SetParamValues(tournamentID, "ref-1")
+
+	_ = GrantStaffRoleHandler(mockDB, mockRMQ)(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "events.tournament.staff_updated", mockRMQ.LastKey)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGrantStaffRoleHandler_CoOrganizerCannotGrant(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	tournamentID := "tourn-123"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id"}).AddRow(tournamentID, "organizer"))
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"role":"co_organizer"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "co-org")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "userId")
+	c.SetParamValues(tournamentID, "friend")
+
+	_ = GrantStaffRoleHandler(mockDB, &MockRabbitMQ{})(c)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGrantStaffRoleHandler_InvalidRole(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"role":"owner"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "organizer")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "userId")
+	c.SetParamValues("tourn-123", "friend")
+
+	_ = GrantStaffRoleHandler(mockDB, &MockRabbitMQ{})(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"role"`)
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -20,14 +20,20 @@ type Tournament struct {
 	Name                string    `json:"name"`
 	Description         string    `json:"description"`
 	Game                string    `json:"game"`
-	Format              string    `json:"format"`
-	ParticipantType     string    `json:"participant_type"`
+	Format              string    `json:"format"`           // see TournamentFormat
+	ParticipantType     string    `json:"participant_type"` // see ParticipantType
 	StartDate           time.Time `json:"start_date"`
 	Status              string    `json:"status"`
 	MinParticipants     int       `json:"min_participants"`
 	MaxParticipants     int       `json:"max_participants"`
 	Public              bool      `json:"public"`
 	CurrentParticipants int       `json:"current_participants"`
+
+	// Prize money, all amounts in minor units (cents) of Currency
+	EntryFeeCents     int64        `json:"entry_fee_cents"`
+	PrizePoolCents    int64        `json:"prize_pool_cents"`
+	Currency          string       `json:"currency"`
+	PrizeDistribution []PrizeShare `json:"prize_distribution"`
 }
 
 type Event struct {
@@ -45,7 +51,7 @@ func CreateTournamentHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
 
 		// 1. Bind (Parse) JSON
 		if err := c.Bind(&t); err != nil {
-			log.Printf("Failed to bind tournament data: %v", err)
+			slog.WarnContext(c.Request().Context(), "invalid tournament data", "error", err)
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON input"})
 		}
 
@@ -56,37 +62,44 @@ func CreateTournamentHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
 		}
 		t.OrganizerID = organizerID
 
-		// Validating "Power of 2" for participants
-		if t.MaxParticipants < 2 || t.MaxParticipants > 16 {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max participants must be between 2 and 16"})
-		}
-		// Can fix later to add byes etc.
-		if t.MaxParticipants%2 != 0 {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max participants must be a multiple of 2"})
-		}
-
 		// 3. Set Server-Side Defaults
 		t.ID = uuid.New().String()
 		t.Status = "draft" // Default status
 		t.Public = true    // Default to public
+		if t.MinParticipants == 0 {
+			t.MinParticipants = minParticipantsLimit
+		}
+		if t.Currency == "" {
+			t.Currency = defaultCurrency
+		}
+		if t.PrizeDistribution == nil {
+			t.PrizeDistribution = []PrizeShare{}
+		}
+
+		// Validate the whole payload and report every bad field at once
+		if errs := ValidateCreate(t, time.Now()); errs.HasErrors() {
+			return validationFailed(c, errs)
+		}
 
 		// 4. Insert into PostgreSQL
 		query := `
 			INSERT INTO tournaments 
-			(id, organizer_id, name, description, game, format, participant_type, start_date, status, min_participants, max_participants, public)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			(id, organizer_id, name, description, game, format, participant_type, start_date, status, min_participants, max_participants, public,
+			 entry_fee_cents, prize_pool_cents, currency, prize_distribution)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		`
-		_, err := db.Exec(context.Background(), query,
+		_, err := db.Exec(c.Request().Context(), query,
 			t.ID, t.OrganizerID, t.Name, t.Description, t.Game,
 			t.Format, t.ParticipantType, t.StartDate, t.Status, t.MinParticipants, t.MaxParticipants, t.Public,
+			t.EntryFeeCents, t.PrizePoolCents, t.Currency, t.PrizeDistribution,
 		)
 
 		if err != nil {
-			log.Printf("Database Insert Error: %v", err)
+			slog.ErrorContext(c.Request().Context(), "database insert failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save tournament"})
 		}
 
-		log.Printf("PERSISTED: Tournament '%s' (ID: %s)", t.Name, t.ID)
+		slog.InfoContext(c.Request().Context(), "tournament created", "tournament_id", t.ID)
 
 		// 5. Publish Event to RabbitMQ
 		// Event Name: TournamentCreated
@@ -100,9 +113,9 @@ func CreateTournamentHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
 		eventBytes, _ := json.Marshal(event)
 
 		// Passing the routing key as the first argument
-		err = rmq.Publish("events.tournament.created", string(eventBytes))
+		err = rmq.Publish(c.Request().Context(), "events.tournament.created", string(eventBytes))
 		if err != nil {
-			log.Printf("ERROR: Failed to publish event: %v", err)
+			slog.ErrorContext(c.Request().Context(), "failed to publish event", "error", err)
 			// Decide if this is fatal. For now, we log it but still return success for the DB save.
 		}
 		// 6. Return Success
@@ -124,17 +137,18 @@ func GetAllTournamentsHandler(db DBClient) echo.HandlerFunc {
 				COALESCE(t.description, '') as description,
 				t.game, t.format, t.participant_type, t.start_date, 
 				t.status, t.min_participants, t.max_participants, t.public,
-				COUNT(r.participant_id) as current_participants
+				COUNT(r.participant_id) as current_participants,
+				t.entry_fee_cents, t.prize_pool_cents, t.currency, t.prize_distribution
 			FROM tournaments t
 			LEFT JOIN registrations r ON t.id = r.tournament_id
 			WHERE t.public = true
 			GROUP BY t.id
 		`
 
-		rows, err := db.Query(context.Background(), query)
+		rows, err := db.Query(c.Request().Context(), query)
 
 		if err != nil {
-			log.Printf("Database Query Error: %v", err)
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch tournaments"})
 		}
 		defer rows.Close()
@@ -148,16 +162,15 @@ func GetAllTournamentsHandler(db DBClient) echo.HandlerFunc {
 			err := rows.Scan(
 				&t.ID, &t.OrganizerID, &t.Name, &t.Description, &t.Game,
 				&t.Format, &t.ParticipantType, &t.StartDate, &t.Status, &t.MinParticipants,
-				&t.MaxParticipants, &t.Public, &t.CurrentParticipants)
+				&t.MaxParticipants, &t.Public, &t.CurrentParticipants,
+				&t.EntryFeeCents, &t.PrizePoolCents, &t.Currency, &t.PrizeDistribution)
 
 			if err != nil {
-				log.Printf("Row Scan Error: %v", err)
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
 				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process tournaments"})
 			}
 			tournaments = append(tournaments, t)
 		}
-		debugBytes, _ := json.Marshal(tournaments)
-    	log.Printf("DEBUG: GetAllTournamentsHandler found %d records. Payload: %s", len(tournaments), string(debugBytes))
 		return c.JSON(http.StatusOK, tournaments)
 	}
 }
@@ -178,10 +191,10 @@ func RegisterTournamentHandler(db DBClient) echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		}
 
-		ctx := context.Background()
+		ctx := c.Request().Context()
 		tx, err := db.Begin(ctx)
 		if err != nil {
-			log.Printf("Failed to start transaction: %v", err)
+			slog.ErrorContext(ctx, "failed to start transaction", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		}
 		defer tx.Rollback(ctx)
@@ -197,7 +210,7 @@ func RegisterTournamentHandler(db DBClient) echo.HandlerFunc {
 			if err.Error() == "no rows in result set" {
 				return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
 			}
-			log.Printf("Database Query Error: %v", err)
+			slog.ErrorContext(ctx, "database query failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check tournament details"})
 		}
 
@@ -231,7 +244,7 @@ func RegisterTournamentHandler(db DBClient) echo.HandlerFunc {
 		// 5. Check Capacity
 		var count int
 		countQuery := `SELECT count(*) FROM registrations WHERE tournament_id = $1`
-		err = tx.QueryRow(context.Background(), countQuery, tournamentID).Scan(&count)
+		err = tx.QueryRow(c.Request().Context(), countQuery, tournamentID).Scan(&count)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check registration count"})
 		}
@@ -246,20 +259,20 @@ func RegisterTournamentHandler(db DBClient) echo.HandlerFunc {
 			INSERT INTO registrations (tournament_id, participant_id, participant_name, status)
 			VALUES ($1, $2, $3, 'approved') 
 		`
-		_, err = tx.Exec(context.Background(), insertQuery, tournamentID, participantID, req.Name)
+		_, err = tx.Exec(c.Request().Context(), insertQuery, tournamentID, participantID, req.Name)
 		if err != nil {
 			// Check for Postgres Unique Violation (Error Code 23505)
 			if err.Error() == "ERROR: duplicate key value violates unique constraint \"registrations_pkey\" (SQLSTATE 23505)" {
 				return c.JSON(http.StatusConflict, map[string]string{"error": "You are already registered"})
 			}
 			
-			log.Printf("Database Insert Error: %v", err)
+			slog.ErrorContext(ctx, "database insert failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to register for tournament"})
 		}
 
 		// 6. Commit the transaction
 		if err := tx.Commit(ctx); err != nil {
-			log.Printf("Commit failed: %v", err)
+			slog.ErrorContext(ctx, "commit failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
 		}
 
@@ -279,8 +292,8 @@ type UpdateStatusRequest struct {
 	Status string `json:"status"`
 }
 
-// Now checks for Organizer OR SuperAdmin role
-func canManageTournament(userID string, userRoles string, t Tournament) bool {
+// Checks for Organizer OR SuperAdmin role
+func isOrganizerOrAdmin(userID string, userRoles string, t Tournament) bool {
 	// 1. Check for SuperAdmin role
 	roles := strings.Split(userRoles, ",")
 	for _, role := range roles {
@@ -293,6 +306,21 @@ func canManageTournament(userID string, userRoles string, t Tournament) bool {
 	return userID == t.OrganizerID
 }
 
+// Checks for Organizer, SuperAdmin OR a co-organizer granted on this tournament.
+// The staff lookup only runs when the cheaper checks fail.
+func canManageTournament(ctx context.Context, db RowQuerier, userID string, userRoles string, t Tournament) bool {
+	if isOrganizerOrAdmin(userID, userRoles, t) {
+		return true
+	}
+
+	role, err := staffRoleFor(ctx, db, t.ID, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "staff lookup failed", "error", err)
+		return false
+	}
+	return role == StaffCoOrganizer
+}
+
 
 func UpdateTournamentStatusHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -311,10 +339,6 @@ func UpdateTournamentStatusHandler(db DBClient, rmq EventPublisher) echo.Handler
 		}
 
 		// Validate Status Enum (Safety check)
-		validStatuses := map[string]bool{
-			"draft": true, "registration_open": true, "registration_closed": true,
-			"ongoing": true, "completed": true, "cancelled": true,
-		}
 		if !validStatuses[req.Status] {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid status value"})
 		}
@@ -322,22 +346,22 @@ func UpdateTournamentStatusHandler(db DBClient, rmq EventPublisher) echo.Handler
 		// 2. Fetch Tournament (Need OrganizerID to verify permissions)
 		var t Tournament
 		query := `SELECT id, organizer_id, status FROM tournaments WHERE id = $1`
-		err := db.QueryRow(context.Background(), query, tournamentID).Scan(&t.ID, &t.OrganizerID, &t.Status)
+		err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(&t.ID, &t.OrganizerID, &t.Status)
 
 		if err != nil {
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
 		}
 
 		// 3. Permission Check (Scalable)
-		if !canManageTournament(userID, userRoles, t) { // Pass roles
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) { // Pass roles
 			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to manage this tournament"})
 		}
 
 		// 4. Update Status in DB
 		updateQuery := `UPDATE tournaments SET status = $1 WHERE id = $2`
-		_, err = db.Exec(context.Background(), updateQuery, req.Status, tournamentID)
+		_, err = db.Exec(c.Request().Context(), updateQuery, req.Status, tournamentID)
 		if err != nil {
-			log.Printf("Database Update Error: %v", err)
+			slog.ErrorContext(c.Request().Context(), "database update failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update status"})
 		}
 
@@ -355,7 +379,7 @@ func UpdateTournamentStatusHandler(db DBClient, rmq EventPublisher) echo.Handler
 			Timestamp: time.Now(),
 		}
 		eventBytes, _ := json.Marshal(event)
-		_ = rmq.Publish("events.tournament.status_updated", string(eventBytes))
+		_ = rmq.Publish(c.Request().Context(), "events.tournament.status_updated", string(eventBytes))
 
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "Tournament status updated successfully", 
@@ -376,17 +400,19 @@ func GetTournamentHandler(db DBClient) echo.HandlerFunc {
 				t.id, t.organizer_id, t.name, COALESCE(t.description, ''), t.game, 
 				t.format, t.participant_type, t.start_date, t.status, 
 				t.min_participants, t.max_participants, t.public,
-				COUNT(r.participant_id) as current_participants
+				COUNT(r.participant_id) as current_participants,
+				t.entry_fee_cents, t.prize_pool_cents, t.currency, t.prize_distribution
 			FROM tournaments t
 			LEFT JOIN registrations r ON t.id = r.tournament_id
 			WHERE t.id = $1
 			GROUP BY t.id
 		`
-		err := db.QueryRow(context.Background(), query, tournamentID).Scan(
+		err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(
 			&t.ID, &t.OrganizerID, &t.Name, &t.Description, &t.Game,
 			&t.Format, &t.ParticipantType, &t.StartDate, &t.Status, 
 			&t.MinParticipants, &t.MaxParticipants, &t.Public, 
 			&t.CurrentParticipants,
+			&t.EntryFeeCents, &t.PrizePoolCents, &t.Currency, &t.PrizeDistribution,
 		)
 
 		if err != nil {
@@ -404,16 +430,22 @@ func GetTournamentHandler(db DBClient) echo.HandlerFunc {
 }
 
 // Struct for allowed updates (keeps ID/Organizer immutable)
+// Every field is a pointer so omitted fields (nil) are left untouched (PATCH semantics).
 type UpdateTournamentRequest struct {
-	Name            string     `json:"name"`
-	Description     string     `json:"description"`
-	Game            string     `json:"game"`
-	Format          string     `json:"format"`
-	StartDate       *time.Time `json:"start_date"` // Pointer allows checking for null/missing
-	Status          string     `json:"status"`
-	MinParticipants int        `json:"min_participants"`
-	MaxParticipants int        `json:"max_participants"`
-	Public          bool       `json:"public"`
+	Name            *string           `json:"name"`
+	Description     *string           `json:"description"`
+	Game            *string           `json:"game"`
+	Format          *TournamentFormat `json:"format"`
+	StartDate       *time.Time        `json:"start_date"`
+	Status          *string           `json:"status"`
+	MinParticipants *int              `json:"min_participants"`
+	MaxParticipants *int              `json:"max_participants"`
+	Public          *bool             `json:"public"`
+
+	EntryFeeCents     *int64        `json:"entry_fee_cents"`
+	PrizePoolCents    *int64        `json:"prize_pool_cents"`
+	Currency          *string       `json:"currency"`
+	PrizeDistribution *[]PrizeShare `json:"prize_distribution"`
 }
 
 func UpdateTournamentDetailsHandler(db DBClient, rmq EventPublisher) echo.HandlerFunc {
@@ -432,67 +464,80 @@ func UpdateTournamentDetailsHandler(db DBClient, rmq EventPublisher) echo.Handle
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		}
 
-		// 2. Fetch Existing Data (to check permissions & status)
+		// 2. Fetch Existing Data (to check permissions, status & current limits)
 		var t Tournament
-		query := `SELECT id, organizer_id, status FROM tournaments WHERE id = $1`
-		err := db.QueryRow(context.Background(), query, tournamentID).Scan(&t.ID, &t.OrganizerID, &t.Status)
+		var startDate *time.Time // nullable column
+		var hasPayouts bool
+		query := `
+			SELECT id, organizer_id, status, start_date, min_participants, max_participants,
+				entry_fee_cents, prize_pool_cents, currency, prize_distribution,
+				EXISTS (SELECT 1 FROM payouts WHERE tournament_id = tournaments.id)
+			FROM tournaments WHERE id = $1
+		`
+		err := db.QueryRow(c.Request().Context(), query, tournamentID).Scan(
+			&t.ID, &t.OrganizerID, &t.Status, &startDate, &t.MinParticipants, &t.MaxParticipants,
+			&t.EntryFeeCents, &t.PrizePoolCents, &t.Currency, &t.PrizeDistribution,
+			&hasPayouts,
+		)
 
 		if err != nil {
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "Tournament not found"})
 		}
+		if startDate != nil {
+			t.StartDate = *startDate
+		}
 
 		// 3. Permission Check
-		if !canManageTournament(userID, userRoles, t) {
+		if !canManageTournament(c.Request().Context(), db, userID, userRoles, t) {
 			return c.JSON(http.StatusForbidden, map[string]string{"error": "You do not have permission to edit this tournament"})
 		}
 
-		// 4. Business Logic Validation
-		if req.MaxParticipants != 0 { // Only validate if the field is being updated
-			if req.MaxParticipants < 2 || req.MaxParticipants > 16 {
-				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max participants must be between 2 and 16"})
-			}
-			if req.MaxParticipants%2 != 0 {
-				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max participants must be a multiple of 2"})
-			}
+		// 4. Business Logic Validation (only the fields being updated)
+		if errs := ValidateUpdate(req, t, hasPayouts, time.Now()); errs.HasErrors() {
+			return validationFailed(c, errs)
 		}
 
 		// 5. Safety Checks (Logic Guard)
 		// If tournament is already active/completed, prevent changing Format or Game
-		if (t.Status == "ongoing" || t.Status == "completed") && (req.Format != "" || req.Game != "") {
+		if (t.Status == "ongoing" || t.Status == "completed") && (req.Format != nil || req.Game != nil) {
 			return c.JSON(http.StatusConflict, map[string]string{"error": "Cannot change Game or Format once tournament has started"})
 		}
 
 		// 6. Update Query
-		// Note: This query updates fields only if they are provided (handling partial updates roughly)
-		// For a true PUT, you usually replace all, but here's a robust SQL approach:
+		// NULL parameters (omitted fields) keep the stored value.
 		updateQuery := `
 			UPDATE tournaments SET
-				name = COALESCE(NULLIF($1, ''), name),
-				description = $2,
-				game = COALESCE(NULLIF($3, ''), game),
-				format = COALESCE(NULLIF($4, ''), format),
+				name = COALESCE($1, name),
+				description = COALESCE($2, description),
+				game = COALESCE($3, game),
+				format = COALESCE($4, format),
 				start_date = COALESCE($5, start_date),
-				status = COALESCE(NULLIF($6, ''), status),
-				min_participants = COALESCE(NULLIF($7, 0), min_participants),
-				max_participants = COALESCE(NULLIF($8, 0), max_participants),
-				public = $9
-			WHERE id = $10
+				status = COALESCE($6, status),
+				min_participants = COALESCE($7, min_participants),
+				max_participants = COALESCE($8, max_participants),
+				public = COALESCE($9, public),
+				entry_fee_cents = COALESCE($10, entry_fee_cents),
+				prize_pool_cents = COALESCE($11, prize_pool_cents),
+				currency = COALESCE($12, currency),
+				prize_distribution = COALESCE($13, prize_distribution)
+			WHERE id = $14
 		`
-		
-		_, err = db.Exec(context.Background(), updateQuery,
-			req.Name, req.Description, req.Game, req.Format, 
+
+		_, err = db.Exec(c.Request().Context(), updateQuery,
+			req.Name, req.Description, req.Game, req.Format,
 			req.StartDate, req.Status, req.MinParticipants, req.MaxParticipants, req.Public,
+			req.EntryFeeCents, req.PrizePoolCents, req.Currency, req.PrizeDistribution,
 			tournamentID,
 		)
 
 		if err != nil {
-			log.Printf("Update Error: %v", err)
+			slog.ErrorContext(c.Request().Context(), "database update failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update tournament"})
 		}
 
-		// 6. Publish Event
+		// 7. Publish Event
 		// Use a lightweight payload or fetch the full updated object
-		_ = rmq.Publish("events.tournament.updated", `{"id":"`+tournamentID+`", "action":"details_updated"}`)
+		_ = rmq.Publish(c.Request().Context(), "events.tournament.updated", `{"id":"`+tournamentID+`", "action":"details_updated"}`)
 
 		return c.JSON(http.StatusOK, map[string]string{"message": "Tournament updated successfully"})
 	}
@@ -505,30 +550,32 @@ func GetParticipantsHandler(db DBClient) echo.HandlerFunc {
 		// Query registrations for this tournament
 		// You might want to filter by status='approved' if you implement approval logic later
 		query := `
-			SELECT participant_id, participant_name 
+			SELECT participant_id, participant_name, COALESCE(status, 'approved'), checked_in
 			FROM registrations 
 			WHERE tournament_id = $1
 		`
 		
-		rows, err := db.Query(context.Background(), query, tournamentID)
+		rows, err := db.Query(c.Request().Context(), query, tournamentID)
 		if err != nil {
-			log.Printf("DB Error: %v", err)
+			slog.ErrorContext(c.Request().Context(), "database query failed", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch participants"})
 		}
 		defer rows.Close()
 
 		// Struct matches the JSON expected by Bracket Service
 		type Participant struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
+			ID        string `json:"id"`
+			Name      string `json:"name"`
+			Status    string `json:"status"` // approved, disqualified
+			CheckedIn bool   `json:"checked_in"`
 		}
 		
 		participants := []Participant{}
 
 		for rows.Next() {
 			var p Participant
-			if err := rows.Scan(&p.ID, &p.Name); err != nil {
-				log.Printf("Scan Error: %v", err)
+			if err := rows.Scan(&p.ID, &p.Name, &p.Status, &p.CheckedIn); err != nil {
+				slog.ErrorContext(c.Request().Context(), "row scan failed", "error", err)
 				continue
 			}
 			participants = append(participants, p)
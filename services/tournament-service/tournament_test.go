@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
@@ -21,12 +23,20 @@ type MockRabbitMQ struct {
 	Err      error
 }
 
-func (m *MockRabbitMQ) Publish(routingKey string, body string) error {
+func (m *MockRabbitMQ) Publish(ctx context.Context, routingKey string, body string) error {
 	m.LastKey = routingKey
 	m.LastBody = body
 	return m.Err
 }
 
+func ptr[T any](v T) *T { return &v }
+
+// Columns returned by the lookup in UpdateTournamentDetailsHandler
+var updateLookupColumns = []string{
+	"id", "organizer_id", "status", "start_date", "min_participants", "max_participants",
+	"entry_fee_cents", "prize_pool_cents", "currency", "prize_distribution", "has_payouts",
+}
+
 func TestCreateTournamentHandler(t *testing.T) {
 	// 1. Setup
 	e := echo.New()
@@ -43,6 +53,7 @@ func TestCreateTournamentHandler(t *testing.T) {
 		Game:            "Pong",
 		Format:          "single-elimination",
 		ParticipantType: "individual",
+		StartDate:       time.Now().Add(24 * time.Hour),
 		MinParticipants: 2,
 		MaxParticipants: 4,
 	}
@@ -65,6 +76,7 @@ func TestCreateTournamentHandler(t *testing.T) {
 			reqPayload.MinParticipants,
 			reqPayload.MaxParticipants,
 			true,
+			int64(0), int64(0), "EUR", []PrizeShare{}, // No prize money by default
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
@@ -100,6 +112,7 @@ func TestGetTournamentHandler_Success(t *testing.T) {
 		"id", "organizer_id", "name", "description", "game",
 		"format", "participant_type", "start_date", "status",
 		"min_participants", "max_participants", "public", "current_participants",
+		"entry_fee_cents", "prize_pool_cents", "currency", "prize_distribution",
 	}
 	
 	// Create a mock row
@@ -109,6 +122,7 @@ func TestGetTournamentHandler_Success(t *testing.T) {
 			"tourn-123", "user-123", "My Tourney", "Desc", "Pong",
 			"single-elimination", "individual", time.Now(), "draft",
 			2, 16, true, 5,
+			int64(0), int64(0), "EUR", []PrizeShare{},
 		))
 
 	req := httptest.NewRequest(http.MethodGet, "/tournaments/tourn-123", nil)
@@ -161,12 +175,15 @@ func TestGetAllTournamentsHandler(t *testing.T) {
 		"id", "organizer_id", "name", "description", "game",
 		"format", "participant_type", "start_date", "status",
 		"min_participants", "max_participants", "public", "current_participants",
+		"entry_fee_cents", "prize_pool_cents", "currency", "prize_distribution",
 	}
 
 	mockDB.ExpectQuery("SELECT .* FROM tournaments").
 		WillReturnRows(pgxmock.NewRows(columns).
-			AddRow("t1", "u1", "Tourney A", "Desc", "Pong", "single", "individual", time.Now(), "open", 2, 8, true, 2).
-			AddRow("t2", "u2", "Tourney B", "Desc", "Pong", "single", "individual", time.Now(), "open", 2, 8, true, 0))
+			AddRow("t1", "u1", "Tourney A", "Desc", "Pong", "single", "individual", time.Now(), "open", 2, 8, true, 2,
+				int64(0), int64(0), "EUR", []PrizeShare{}).
+			AddRow("t2", "u2", "Tourney B", "Desc", "Pong", "single", "individual", time.Now(), "open", 2, 8, true, 0,
+				int64(10000), int64(50000), "EUR", []PrizeShare{{Placement: 1, Percentage: 100}}))
 
 	// 2. Execute
 	req := httptest.NewRequest(http.MethodGet, "/tournaments", nil)
@@ -324,17 +341,18 @@ func TestUpdateTournamentDetailsHandler_Success(t *testing.T) {
 
 	// 1. Fetch Existing Data (Permission Check)
 	// Query: SELECT id, organizer_id, status FROM tournaments...
-	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+	mockDB.ExpectQuery("SELECT id, organizer_id, status, start_date, min_participants, max_participants,.* FROM tournaments").
 		WithArgs(tournamentID).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
-			AddRow(tournamentID, organizerID, "draft"))
+		WillReturnRows(pgxmock.NewRows(updateLookupColumns).
+			AddRow(tournamentID, organizerID, "draft", nil, 2, 16, int64(0), int64(0), "EUR", []PrizeShare{}, false))
 
 	// 2. Perform Update
 	// Query: UPDATE tournaments SET ...
 	mockDB.ExpectExec("UPDATE tournaments SET").
 		WithArgs(
-			"New Name", "New Desc", pgxmock.AnyArg(), pgxmock.AnyArg(),
-			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), true,
+			ptr("New Name"), ptr("New Desc"), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), ptr(true),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
 			tournamentID,
 		).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -369,10 +387,10 @@ func TestUpdateTournamentDetailsHandler_Locked(t *testing.T) {
 
 	// 1. Fetch Existing Data
 	// Status is "ongoing", which should LOCK Game/Format changes
-	mockDB.ExpectQuery("SELECT id, organizer_id, status FROM tournaments").
+	mockDB.ExpectQuery("SELECT id, organizer_id, status, start_date, min_participants, max_participants,.* FROM tournaments").
 		WithArgs(tournamentID).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
-			AddRow(tournamentID, organizerID, "ongoing"))
+		WillReturnRows(pgxmock.NewRows(updateLookupColumns).
+			AddRow(tournamentID, organizerID, "ongoing", nil, 2, 16, int64(0), int64(0), "EUR", []PrizeShare{}, false))
 
 	// 2. Request try to change Format
 	reqBody := `{"format": "double-elimination"}`
@@ -401,11 +419,11 @@ func TestGetParticipantsHandler(t *testing.T) {
 	tournamentID := "tourn-123"
 
 	// 1. Mock Query
-	mockDB.ExpectQuery("SELECT participant_id, participant_name FROM registrations").
+	mockDB.ExpectQuery("SELECT participant_id, participant_name, COALESCE\\(status, 'approved'\\), checked_in FROM registrations").
 		WithArgs(tournamentID).
-		WillReturnRows(pgxmock.NewRows([]string{"participant_id", "participant_name"}).
-			AddRow("user-1", "Alice").
-			AddRow("user-2", "Bob"))
+		WillReturnRows(pgxmock.NewRows([]string{"participant_id", "participant_name", "status", "checked_in"}).
+			AddRow("user-1", "Alice", "approved", true).
+			AddRow("user-2", "Bob", "disqualified", false))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
@@ -419,6 +437,7 @@ func TestGetParticipantsHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Contains(t, rec.Body.String(), "Alice")
 	assert.Contains(t, rec.Body.String(), "Bob")
+	assert.NotContains(t, rec.Body.String(), "fee_paid")
 	assert.NoError(t, mockDB.ExpectationsWereMet())
 }
 
@@ -476,7 +495,12 @@ func TestUpdateTournamentStatusHandler_PermissionDenied(t *testing.T) {
 		WillReturnRows(pgxmock.NewRows([]string{"id", "organizer_id", "status"}).
 			AddRow(tournamentID, organizerID, "draft"))
 
-	// 2. Expect NO Update execution
+	// 2. Attacker has no staff role on the tournament
+	mockDB.ExpectQuery("SELECT role FROM tournament_staff").
+		WithArgs(tournamentID, attackerID).
+		WillReturnError(pgx.ErrNoRows)
+
+	// 3. Expect NO Update execution
 
 	req := httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"status":"registration_open"}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -508,6 +532,7 @@ func TestGetTournamentHandler_PrivateForbidden(t *testing.T) {
 		"id", "organizer_id", "name", "description", "game",
 		"format", "participant_type", "start_date", "status",
 		"min_participants", "max_participants", "public", "current_participants",
+		"entry_fee_cents", "prize_pool_cents", "currency", "prize_distribution",
 	}
 	
 	mockDB.ExpectQuery("SELECT .* FROM tournaments t").
@@ -516,6 +541,7 @@ func TestGetTournamentHandler_PrivateForbidden(t *testing.T) {
 			tournamentID, organizerID, "Secret Club", "Desc", "Pong",
 			"single", "individual", time.Now(), "draft",
 			2, 16, false, 0, // <--- Public is FALSE
+			int64(0), int64(0), "EUR", []PrizeShare{},
 		))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -562,4 +588,133 @@ func TestMetricsHandler_StatusOK(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
 	// The body contains Prometheus metrics, so just checking status is enough for this test
-}
\ No newline at end of file
+}
+
+func TestCreateTournamentHandler_FieldErrors(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	// Empty name, unknown enums, start date in the past and min > max
+	reqBody := `{"name": "  ", "game": "Pong", "format": "swiss", "participant_type": "duo",
+		"start_date": "2020-01-01T10:00:00Z", "min_participants": 8, "max_participants": 4}`
+
+	req := httptest.NewRequest(http.MethodPost, "/tournaments", bytes.NewBufferString(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", "user-123")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := CreateTournamentHandler(mockDB, &MockRabbitMQ{})
+	err = handler(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Validation failed", resp.Error)
+
+	fields := map[string]bool{}
+	for _, f := range resp.Fields {
+		fields[f.Field] = true
+	}
+	for _, f := range []string{"name", "format", "participant_type", "start_date", "min_participants"} {
+		assert.True(t, fields[f], "expected field error for %s", f)
+	}
+	// Nothing may be written
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestUpdateTournamentDetailsHandler_OmittedFieldsUntouched(t *testing.T) {
+	e := echo.New()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	mockRMQ := &MockRabbitMQ{}
+
+	tournamentID := "tourn-patch"
+	organizerID := "user-admin"
+
+	mockDB.ExpectQuery("SELECT id, organizer_id, status, start_date, min_participants, max_participants,.* FROM tournaments").
+		WithArgs(tournamentID).
+		WillReturnRows(pgxmock.NewRows(updateLookupColumns).
+			AddRow(tournamentID, organizerID, "draft", nil, 2, 16, int64(0), int64(0), "EUR", []PrizeShare{}, false))
+
+	// Only the name is sent: description and public must be passed as NULL, not as zero values
+	mockDB.ExpectExec("UPDATE tournaments SET").
+		WithArgs(
+			ptr("Renamed"), (*string)(nil), (*string)(nil), (*TournamentFormat)(nil),
+			(*time.Time)(nil), (*string)(nil), (*int)(nil), (*int)(nil), (*bool)(nil),
+			(*int64)(nil), (*int64)(nil), (*string)(nil), (*[]PrizeShare)(nil),
+			tournamentID,
+		).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	req := httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"name": "Renamed"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User-Id", organizerID)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(tournamentID)
+
+	handler := UpdateTournamentDetailsHandler(mockDB, mockRMQ)
+	_ = handler(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestValidateUpdate_UsesStoredLimits(t *testing.T) {
+	current := Tournament{MinParticipants: 2, MaxParticipants: 8}
+	now := time.Now()
+
+	// Raising min above the stored max is rejected even though max is omitted
+	errs := ValidateUpdate(UpdateTournamentRequest{MinParticipants: ptr(10)}, current, false, now)
+	assert.True(t, errs.HasErrors())
+	assert.Equal(t, "min_participants", errs[0].Field)
+
+	// Resending an unchanged start date that has already passed is allowed
+	current.StartDate = now.Add(-time.Hour)
+	errs = ValidateUpdate(UpdateTournamentRequest{StartDate: ptr(current.StartDate)}, current, false, now)
+	assert.False(t, errs.HasErrors())
+
+	// Unknown format
+	errs = ValidateUpdate(UpdateTournamentRequest{Format: ptr(TournamentFormat("swiss"))}, current, false, now)
+	assert.Equal(t, ValidationErrors{{Field: "format", Message: "Format must be one of: single-elimination, double-elimination, round-robin"}}, errs)
+}
+
+func TestValidateUpdate_PrizeTermsLocked(t *testing.T) {
+	current := Tournament{
+		Status: "draft", MinParticipants: 2, MaxParticipants: 8, Currency: "EUR",
+		PrizePoolCents: 10000, PrizeDistribution: []PrizeShare{{1, 50}, {2, 30}, {8, 20}},
+	}
+	now := time.Now()
+	raise := UpdateTournamentRequest{PrizePoolCents: ptr(int64(20000))}
+
+	// Open for changes until the tournament starts or payouts exist
+	assert.False(t, ValidateUpdate(raise, current, false, now).HasErrors())
+	errs := ValidateUpdate(raise, current, true, now)
+	assert.Equal(t, "prize_pool_cents", errs[0].Field)
+
+	current.Status = "ongoing"
+	errs = ValidateUpdate(UpdateTournamentRequest{
+		EntryFeeCents:     ptr(int64(500)),
+		PrizeDistribution: &[]PrizeShare{{1, 100}},
+	}, current, false, now)
+	assert.Len(t, errs, 2)
+
+	// Resending the stored terms is fine
+	errs = ValidateUpdate(UpdateTournamentRequest{PrizeDistribution: ptr(current.PrizeDistribution)}, current, false, now)
+	assert.False(t, errs.HasErrors())
+
+	// Lowering max participants below a paid placement is rejected
+	current.Status = "draft"
+	errs = ValidateUpdate(UpdateTournamentRequest{MaxParticipants: ptr(4)}, current, false, now)
+	assert.Equal(t, ValidationErrors{{Field: "prize_distribution", Message: "Placements must be between 1 and max participants"}}, errs)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracing records spans in memory for the duration of the test.
+func newTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	installTracing(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestTracingMiddleware_ContinuesGatewayTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	e := echo.New()
+	e.Use(TracingMiddleware())
+	e.GET("/tournaments/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/health", HealthCheckHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/tournaments/t1", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /tournaments/:id", spans[0].Name)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent.SpanID().String())
+}
+
+func TestQueryTracer(t *testing.T) {
+	exporter := newTestTracing(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+	qctx := queryTracer{}.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select id from tournaments where id = $1"})
+	queryTracer{}.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	qctx = queryTracer{}.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "UPDATE tournaments SET status = $1"})
+	queryTracer{}.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("deadlock detected")})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "SELECT", spans[0].Name)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Contains(t, spans[0].Attributes, semconv.DBQueryText("select id from tournaments where id = $1"))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code, "no rows is not a failure")
+
+	assert.Equal(t, "UPDATE", spans[1].Name)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+}
+
+func TestStartPublishSpan_InjectsTraceContext(t *testing.T) {
+	exporter := newTestTracing(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+	headers := amqp.Table{}
+	_, span := startPublishSpan(ctx, "events.tournament.created", headers)
+	span.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	publish := spans[0]
+	assert.Equal(t, "publish events.tournament.created", publish.Name)
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), publish.Parent.SpanID())
+
+	// A consumer continues from the publish span
+	consumer := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), amqpHeaders(headers)))
+	assert.Equal(t, publish.SpanContext.TraceID(), consumer.TraceID())
+	assert.Equal(t, publish.SpanContext.SpanID(), consumer.SpanID())
+}
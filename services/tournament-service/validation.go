@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// --- Enums ---
+
+type TournamentFormat string
+
+const (
+	FormatSingleElimination TournamentFormat = "single-elimination"
+	FormatDoubleElimination TournamentFormat = "double-elimination"
+	FormatRoundRobin        TournamentFormat = "round-robin"
+)
+
+var validFormats = []TournamentFormat{FormatSingleElimination, FormatDoubleElimination, FormatRoundRobin}
+
+func (f TournamentFormat) Valid() bool {
+	for _, v := range validFormats {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+type ParticipantType string
+
+const (
+	ParticipantIndividual ParticipantType = "individual"
+	ParticipantTeam       ParticipantType = "team"
+)
+
+var validParticipantTypes = []ParticipantType{ParticipantIndividual, ParticipantTeam}
+
+func (p ParticipantType) Valid() bool {
+	for _, v := range validParticipantTypes {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Lifecycle states, see SCHEMA.md for the expected flow.
+var validStatuses = map[string]bool{
+	"draft": true, "registration_open": true, "registration_closed": true,
+	"ongoing": true, "completed": true, "cancelled": true,
+}
+
+// Participant limits. The upper bound matches what bracket-service can seed.
+const (
+	minParticipantsLimit = 2
+	maxParticipantsLimit = 16
+)
+
+// --- Field Errors ---
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every problem with a request so the client can fix them in one go.
+type ValidationErrors []FieldError
+
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+func (v ValidationErrors) HasErrors() bool {
+	return len(v) > 0
+}
+
+// validationFailed writes the standard 400 response for a failed validation.
+func validationFailed(c echo.Context, errs ValidationErrors) error {
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"error":  "Validation failed",
+		"fields": errs,
+	})
+}
+
+func joinEnum[T ~string](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = string(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validateParticipantLimits checks the min/max pair after defaults or stored values are applied.
+func validateParticipantLimits(errs *ValidationErrors, min, max int) {
+	if max < minParticipantsLimit || max > maxParticipantsLimit {
+		errs.Add("max_participants", "Max participants must be between 2 and 16")
+	} else if max%2 != 0 {
+		// Can fix later to add byes etc.
+		errs.Add("max_participants", "Max participants must be a multiple of 2")
+	}
+	if min < minParticipantsLimit {
+		errs.Add("min_participants", "Min participants must be at least 2")
+	} else if min > max {
+		errs.Add("min_participants", "Min participants cannot be greater than max participants")
+	}
+}
+
+// ValidateCreate checks a new tournament. Defaults must be applied before calling.
+func ValidateCreate(t Tournament, now time.Time) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(t.Name) == "" {
+		errs.Add("name", "Name is required")
+	} else if len(t.Name) > 100 {
+		errs.Add("name", "Name must be at most 100 characters")
+	}
+	if strings.TrimSpace(t.Game) == "" {
+		errs.Add("game", "Game is required")
+	} else if len(t.Game) > 50 {
+		errs.Add("game", "Game must be at most 50 characters")
+	}
+	if !TournamentFormat(t.Format).Valid() {
+		errs.Add("format", "Format must be one of: "+joinEnum(validFormats))
+	}
+	if !ParticipantType(t.ParticipantType).Valid() {
+		errs.Add("participant_type", "Participant type must be one of: "+joinEnum(validParticipantTypes))
+	}
+	if t.StartDate.IsZero() {
+		errs.Add("start_date", "Start date is required")
+	} else if t.StartDate.Before(now) {
+		errs.Add("start_date", "Start date cannot be in the past")
+	}
+	validateParticipantLimits(&errs, t.MinParticipants, t.MaxParticipants)
+	validatePrizeFields(&errs, t.EntryFeeCents, t.PrizePoolCents, t.Currency, t.PrizeDistribution, t.MaxParticipants)
+
+	return errs
+}
+
+// ValidateUpdate checks only the fields present in the request.
+// current holds the stored values, needed for the min/max cross-check. hasPayouts tells whether
+// payouts were already computed, which fixes the prize terms like the start of the tournament does.
+func ValidateUpdate(req UpdateTournamentRequest, current Tournament, hasPayouts bool, now time.Time) ValidationErrors {
+	var errs ValidationErrors
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			errs.Add("name", "Name cannot be empty")
+		} else if len(*req.Name) > 100 {
+			errs.Add("name", "Name must be at most 100 characters")
+		}
+	}
+	if req.Game != nil {
+		if strings.TrimSpace(*req.Game) == "" {
+			errs.Add("game", "Game cannot be empty")
+		} else if len(*req.Game) > 50 {
+			errs.Add("game", "Game must be at most 50 characters")
+		}
+	}
+	if req.Format != nil && !req.Format.Valid() {
+		errs.Add("format", "Format must be one of: "+joinEnum(validFormats))
+	}
+	// Resending the stored date unchanged is fine even once it has passed.
+	if req.StartDate != nil && !req.StartDate.Equal(current.StartDate) && req.StartDate.Before(now) {
+		errs.Add("start_date", "Start date cannot be in the past")
+	}
+	if req.Status != nil && !validStatuses[*req.Status] {
+		errs.Add("status", "Invalid status value")
+	}
+
+	if req.MinParticipants != nil || req.MaxParticipants != nil {
+		min, max := current.MinParticipants, current.MaxParticipants
+		if req.MinParticipants != nil {
+			min = *req.MinParticipants
+		}
+		if req.MaxParticipants != nil {
+			max = *req.MaxParticipants
+		}
+		validateParticipantLimits(&errs, min, max)
+	}
+
+	// Participants registered and payouts were computed under the stored prize terms
+	if current.Status == "ongoing" || current.Status == "completed" || hasPayouts {
+		const locked = " cannot change once the tournament has started or has payouts"
+		if req.EntryFeeCents != nil && *req.EntryFeeCents != current.EntryFeeCents {
+			errs.Add("entry_fee_cents", "Entry fee"+locked)
+		}
+		if req.PrizePoolCents != nil && *req.PrizePoolCents != current.PrizePoolCents {
+			errs.Add("prize_pool_cents", "Prize pool"+locked)
+		}
+		if req.Currency != nil && *req.Currency != current.Currency {
+			errs.Add("currency", "Currency"+locked)
+		}
+		if req.PrizeDistribution != nil && !slices.Equal(*req.PrizeDistribution, current.PrizeDistribution) {
+			errs.Add("prize_distribution", "Prize distribution"+locked)
+		}
+	}
+
+	// Prize fields are checked together against the stored values, and again when
+	// max participants changes since it bounds the placements of the distribution
+	if req.EntryFeeCents != nil || req.PrizePoolCents != nil || req.Currency != nil || req.PrizeDistribution != nil || req.MaxParticipants != nil {
+		fee, pool, currency, dist := current.EntryFeeCents, current.PrizePoolCents, current.Currency, current.PrizeDistribution
+		if req.EntryFeeCents != nil {
+			fee = *req.EntryFeeCents
+		}
+		if req.PrizePoolCents != nil {
+			pool = *req.PrizePoolCents
+		}
+		if req.Currency != nil {
+			currency = *req.Currency
+		}
+		if req.PrizeDistribution != nil {
+			dist = *req.PrizeDistribution
+		}
+		max := current.MaxParticipants
+		if req.MaxParticipants != nil {
+			max = *req.MaxParticipants
+		}
+		validatePrizeFields(&errs, fee, pool, currency, dist, max)
+	}
+
+	return errs
+}
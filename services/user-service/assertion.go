@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// HeaderIdentityAssertion carries the short-lived token api-gateway signs for every authenticated request,
+// and for the user it creates on sign-up.
+const HeaderIdentityAssertion = "X-Identity-Assertion"
+
+// Issuer and audience of identity assertions, and the clock skew we tolerate
+const (
+	assertionIssuer   = "api-gateway"
+	assertionAudience = "t-hub-services"
+	assertionLeeway   = 5 * time.Second
+)
+
+// AssertionClaims is the payload of an identity assertion (a JWT signed with EdDSA).
+type AssertionClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Roles     string `json:"roles"` // Same format as X-User-Roles
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// AssertionVerifier checks identity assertions against the gateway's Ed25519 public keys.
+// The same verifier is in bracket-service/assertion.go, team-service/assertion.go
+// and tournament-service/assertion.go; keep the copies in step with each other and with the signer
+// in api-gateway/assertion.go.
+type AssertionVerifier struct {
+	Keys []ed25519.PublicKey
+	now  func() time.Time // For tests
+}
+
+// NewAssertionVerifierFromEnv reads the gateway's PKIX PEM public key from GATEWAY_ASSERTION_PUBLIC_KEY.
+// Several PEM blocks may be given while the gateway key is rotated. Returns nil when unset.
+func NewAssertionVerifierFromEnv() (*AssertionVerifier, error) {
+	data := []byte(os.Getenv("GATEWAY_ASSERTION_PUBLIC_KEY"))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	v := &AssertionVerifier{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse gateway public key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("gateway public key must be Ed25519")
+		}
+		v.Keys = append(v.Keys, edKey)
+	}
+	if len(v.Keys) == 0 {
+		return nil, errors.New("GATEWAY_ASSERTION_PUBLIC_KEY holds no PEM public key")
+	}
+	return v, nil
+}
+
+// RegistrationVerifierFromEnv is NewAssertionVerifierFromEnv for startup. POST /register takes the new
+// user's ID from the request body, so without the key anyone who can reach the service could create
+// users under any ID. A missing key is an error unless TRUST_IDENTITY_HEADERS=true opts into that for
+// local development.
+func RegistrationVerifierFromEnv() (*AssertionVerifier, error) {
+	v, err := NewAssertionVerifierFromEnv()
+	if err != nil || v != nil {
+		return v, err
+	}
+	if os.Getenv("TRUST_IDENTITY_HEADERS") != "true" {
+		return nil, errors.New("GATEWAY_ASSERTION_PUBLIC_KEY is not set; set TRUST_IDENTITY_HEADERS=true to accept registrations without a gateway assertion (local development only)")
+	}
+	slog.Warn("GATEWAY_ASSERTION_PUBLIC_KEY not set, accepting registrations without a gateway assertion")
+	return nil, nil
+}
+
+// Verify checks the signature, issuer, audience and lifetime of a raw assertion.
+func (v *AssertionVerifier) Verify(raw string) (*AssertionClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed assertion")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed assertion header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "EdDSA" {
+		return nil, errors.New("unexpected assertion algorithm")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed assertion signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	valid := false
+	for _, k := range v.Keys {
+		if ed25519.Verify(k, signed, sig) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errors.New("invalid assertion signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed assertion payload")
+	}
+	var claims AssertionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed assertion payload")
+	}
+
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	t := now()
+	switch {
+	case claims.Issuer != assertionIssuer || claims.Audience != assertionAudience:
+		return nil, errors.New("assertion not issued by the gateway for us")
+	case claims.Subject == "":
+		return nil, errors.New("assertion has no subject")
+	case t.After(time.Unix(claims.ExpiresAt, 0).Add(assertionLeeway)):
+		return nil, errors.New("assertion expired")
+	case t.Add(assertionLeeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, errors.New("assertion issued in the future")
+	}
+	return &claims, nil
+}
@@ -2,45 +2,124 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Realm role that may read and delete any user
+const adminRole = "SuperAdmin"
+
 type Claims struct {
 	Sub               string `json:"sub"`
 	Email             string `json:"email"`
 	PreferredUsername string `json:"preferred_username"`
+	AuthorizedParty   string `json:"azp"`
+	RealmAccess       struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
 	jwt.RegisteredClaims
 }
 
-func ExtractUser(next http.Handler) http.Handler {
+func (c *Claims) IsAdmin() bool {
+	return slices.Contains(c.RealmAccess.Roles, adminRole)
+}
+
+type ctxKey int
+
+const claimsKey ctxKey = iota
+
+// Verifier checks access tokens issued by Keycloak: the signature against the issuer's JWKS,
+// expiry, issuer and, when Audience is set, that the token was issued for us.
+// team-service/auth.go has the same Verifier; keep the two in step.
+type Verifier struct {
+	Issuer   string
+	Audience string // Matched against "aud" or, as Keycloak puts the client there, "azp"
+	Keys     *KeySet
+}
+
+// NewVerifierFromEnv configures the verifier for the KEYCLOAK_URL / KEYCLOAK_REALM realm.
+// JWT_ISSUER and JWKS_URL override the derived issuer and key URL (e.g. to reach Keycloak
+// in-cluster while tokens carry its public URL); JWT_AUDIENCE enables the audience check.
+func NewVerifierFromEnv() (*Verifier, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" && os.Getenv("KEYCLOAK_URL") != "" {
+		realm := os.Getenv("KEYCLOAK_REALM")
+		if realm == "" {
+			realm = "t-hub"
+		}
+		issuer = strings.TrimSuffix(os.Getenv("KEYCLOAK_URL"), "/") + "/realms/" + realm
+	}
+	if issuer == "" {
+		return nil, fmt.Errorf("KEYCLOAK_URL or JWT_ISSUER must be set")
+	}
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = issuer + "/protocol/openid-connect/certs"
+	}
+	return &Verifier{Issuer: issuer, Audience: os.Getenv("JWT_AUDIENCE"), Keys: NewKeySet(jwksURL)}, nil
+}
+
+// Verify parses and validates a raw token.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	claims := &Claims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Sub == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	if v.Audience != "" && !slices.Contains(claims.Audience, v.Audience) && claims.AuthorizedParty != v.Audience {
+		return nil, fmt.Errorf("token not issued for %q", v.Audience)
+	}
+	return claims, nil
+}
+
+// Authenticate rejects requests without a valid bearer token and puts its claims in the context.
+func (v *Verifier) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, _, err := jwt.NewParser().ParseUnverified(tokenStr, &Claims{})
+		claims, err := v.Verify(r.Context(), token)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
+		setLogUser(r.Context(), claims.Sub)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
+	})
+}
 
-		// attach to request context
-		ctx := context.WithValue(r.Context(), "userID", claims.Sub)
-		ctx = context.WithValue(ctx, "email", claims.Email)
-		ctx = context.WithValue(ctx, "username", claims.PreferredUsername)
+func claimsFromCtx(r *http.Request) (*Claims, bool) {
+	c, ok := r.Context().Value(claimsKey).(*Claims)
+	return c, ok
+}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// canAccessUser reports whether the caller may see or delete the private data of user id:
+// only the user themselves and admins may.
+func canAccessUser(r *http.Request, id string) bool {
+	c, ok := claimsFromCtx(r)
+	return ok && (c.Sub == id || c.IsAdmin())
 }
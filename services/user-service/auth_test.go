@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://keycloak.test/realms/t-hub"
+
+// jwksServer is a local stand-in for Keycloak's JWKS endpoint.
+type jwksServer struct {
+	*httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		var keys []map[string]string
+		for kid, k := range s.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+				"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	s.addKey(t, "key-1")
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s.keys[kid] = k
+}
+
+func (s *jwksServer) sign(t *testing.T, kid string, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(s.keys[kid])
+	require.NoError(t, err)
+	return raw
+}
+
+func validClaims(sub string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                sub,
+		"iss":                testIssuer,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"azp":                "t-hub-frontend",
+		"email":              "test@test.com",
+		"preferred_username": "tester",
+		"realm_access":       map[string]interface{}{"roles": []string{"user"}},
+	}
+}
+
+func newTestVerifier(s *jwksServer) *Verifier {
+	return &Verifier{Issuer: testIssuer, Audience: "t-hub-frontend", Keys: NewKeySet(s.URL)}
+}
+
+func authenticate(v *Verifier, token string) (*httptest.ResponseRecorder, *Claims) {
+	var got *Claims
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = claimsFromCtx(r)
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	v.Authenticate(next).ServeHTTP(rec, req)
+	return rec, got
+}
+
+func TestAuthenticate_Success(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+
+	rec, claims := authenticate(v, s.sign(t, "key-1", validClaims("user-123")))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, claims)
+	assert.Equal(t, "user-123", claims.Sub)
+	assert.Equal(t, "test@test.com", claims.Email)
+	assert.False(t, claims.IsAdmin())
+
+	id, ok := userIDFromCtx(asUser(httptest.NewRequest("GET", "/", nil), claims.Sub))
+	assert.True(t, ok)
+	assert.Equal(t, "user-123", id)
+}
+
+func TestAuthenticate_Rejects(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims("user-123"))
+	forged.Header["kid"] = "key-1"
+	forgedRaw, _ := forged.SignedString(other)
+
+	hmacRaw, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims("user-123")).SignedString([]byte("secret"))
+
+	expired := validClaims("user-123")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongIssuer := validClaims("user-123")
+	wrongIssuer["iss"] = "https://evil.test/realms/t-hub"
+	wrongAudience := validClaims("user-123")
+	wrongAudience["azp"] = "other-client"
+	noExpiry := validClaims("user-123")
+	delete(noExpiry, "exp")
+
+	tests := map[string]string{
+		"missing header": "",
+		"garbage":        "not-a-token",
+		"forged":         forgedRaw,
+		"hmac":           hmacRaw,
+		"expired":        s.sign(t, "key-1", expired),
+		"wrong issuer":   s.sign(t, "key-1", wrongIssuer),
+		"wrong audience": s.sign(t, "key-1", wrongAudience),
+		"no expiry":      s.sign(t, "key-1", noExpiry),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec, claims := authenticate(v, token)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Nil(t, claims)
+		})
+	}
+}
+
+func TestAuthenticate_AudienceClaim(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+
+	c := validClaims("user-123")
+	c["azp"] = "other-client"
+	c["aud"] = []string{"account", "t-hub-frontend"}
+
+	rec, _ := authenticate(v, s.sign(t, "key-1", c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthenticate_AdminRole(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+
+	c := validClaims("user-123")
+	c["realm_access"] = map[string]interface{}{"roles": []string{"user", adminRole}}
+
+	rec, claims := authenticate(v, s.sign(t, "key-1", c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, claims)
+	assert.True(t, claims.IsAdmin())
+}
+
+func TestKeySet_CachesAndFollowsRotation(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+	now := time.Now()
+	v.Keys.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		rec, _ := authenticate(v, s.sign(t, "key-1", validClaims("user-123")))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, int32(1), s.fetches.Load(), "keys should be cached")
+
+	// The issuer rotates to a new key: the unknown kid triggers one refetch
+	now = now.Add(time.Minute)
+	s.addKey(t, "key-2")
+	rec, _ := authenticate(v, s.sign(t, "key-2", validClaims("user-123")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(2), s.fetches.Load())
+
+	// Unknown kids right after a fetch do not reach the issuer again
+	s.addKey(t, "key-3")
+	rec, _ = authenticate(v, s.sign(t, "key-3", validClaims("user-123")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, int32(2), s.fetches.Load())
+
+	// Once the cache is stale it is refreshed
+	now = now.Add(jwksCacheTTL + time.Second)
+	rec, _ = authenticate(v, s.sign(t, "key-3", validClaims("user-123")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(3), s.fetches.Load())
+}
+
+func TestKeySet_KeepsKeysWhenIssuerDown(t *testing.T) {
+	s := newJWKSServer(t)
+	v := newTestVerifier(s)
+	now := time.Now()
+	v.Keys.now = func() time.Time { return now }
+
+	token := s.sign(t, "key-1", validClaims("user-123"))
+	rec, _ := authenticate(v, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	s.Close()
+	now = now.Add(jwksCacheTTL + time.Second)
+	rec, _ = authenticate(v, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestKeySet_ServesCachedKeysDuringRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	requested, release := make(chan struct{}), make(chan struct{})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		close(requested)
+		<-release
+		var keys []map[string]string
+		for _, kid := range []string{"key-1", "key-2"} {
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	ks := NewKeySet(srv.URL)
+	ks.keys = map[string]crypto.PublicKey{"key-1": &key.PublicKey}
+	ks.fetchedAt = time.Now().Add(-jwksCacheTTL - time.Minute)
+
+	// A stale cache makes the first caller refresh; the issuer answers slowly
+	refreshed := make(chan error)
+	go func() {
+		_, err := ks.Key(context.Background(), "key-1")
+		refreshed <- err
+	}()
+	<-requested
+
+	// Meanwhile the cached key is served without waiting for the issuer
+	got, err := ks.Key(context.Background(), "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, got)
+
+	// An unknown kid waits for the refresh in flight instead of starting another
+	rotated := make(chan error)
+	go func() {
+		_, err := ks.Key(context.Background(), "key-2")
+		rotated <- err
+	}()
+
+	close(release)
+	assert.NoError(t, <-refreshed)
+	assert.NoError(t, <-rotated)
+	assert.Equal(t, int32(1), fetches.Load())
+}
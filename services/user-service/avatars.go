@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AvatarStore keeps avatar images. Keys are slash separated paths like "avatars/<user>/<id>.png".
+type AvatarStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the object.
+	URL(key string) string
+}
+
+// NewAvatarStoreFromEnv picks the avatar store from AVATAR_STORE ("disk", the default, or "s3").
+// For the disk store it also returns the handler serving the files, to be mounted under AVATAR_BASE_URL.
+func NewAvatarStoreFromEnv() (AvatarStore, http.Handler, error) {
+	switch os.Getenv("AVATAR_STORE") {
+	case "", "disk":
+		dir := os.Getenv("AVATAR_DIR")
+		if dir == "" {
+			dir = "/tmp/avatars"
+		}
+		baseURL := os.Getenv("AVATAR_BASE_URL")
+		if baseURL == "" {
+			baseURL = "/api/users/avatars"
+		}
+		store := &DiskStore{Dir: dir, BaseURL: baseURL}
+		return store, http.FileServer(http.Dir(dir)), nil
+	case "s3":
+		store := &S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		}
+		if store.Endpoint == "" || store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+			return nil, nil, fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY must be set for the s3 avatar store")
+		}
+		return store, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown AVATAR_STORE %q", os.Getenv("AVATAR_STORE"))
+	}
+}
+
+// cleanKey rejects keys that would escape the store.
+func cleanKey(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return clean, nil
+}
+
+// --- Local disk ---
+
+// DiskStore keeps avatars in a directory; user-service serves them itself under BaseURL.
+type DiskStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func (s *DiskStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// Write to a temp file first so readers never see half an image
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *DiskStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *DiskStore) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + key
+}
+
+// --- S3 compatible (AWS S3, MinIO, R2, ...) ---
+
+// S3Store keeps avatars in a bucket using path-style requests signed with AWS Signature Version 4.
+type S3Store struct {
+	Endpoint  string // e.g. https://s3.eu-north-1.amazonaws.com or http://minio:9000
+	Region    string // Defaults to us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string // Base URL clients download from; defaults to Endpoint/Bucket
+	Client    *http.Client
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable") // Keys are never reused
+	return s.do(req, data)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+func (s *S3Store) URL(key string) string {
+	base := s.PublicURL
+	if base == "" {
+		base = strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket
+	}
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
+
+func (s *S3Store) request(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + key
+	return http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+}
+
+func (s *S3Store) do(req *http.Request, body []byte) error {
+	s.sign(req, body, time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Deleting a missing object is a 204 on S3 and some stand-ins answer 404
+	if resp.StatusCode >= 300 && !(req.Method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, msg)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signed = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// serveAvatars serves the disk store's files without directory listings.
+func serveAvatars(files http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	store := &DiskStore{Dir: dir, BaseURL: "/api/users/avatars/"}
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "avatars/u1/a.png", []byte("img"), "image/png"))
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "u1", "a.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "img", string(data))
+	assert.Equal(t, "/api/users/avatars/avatars/u1/a.png", store.URL("avatars/u1/a.png"))
+
+	assert.NoError(t, store.Delete(ctx, "avatars/u1/a.png"))
+	assert.NoError(t, store.Delete(ctx, "avatars/u1/a.png")) // Already gone
+	assert.Error(t, store.Put(ctx, "../escape.png", []byte("x"), "image/png"))
+}
+
+func TestServeAvatars_NoListing(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.png"), []byte("img"), 0o644))
+	h := serveAvatars(http.FileServer(http.Dir(dir)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/a.png", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestS3Store(t *testing.T) {
+	// Local stand-in for an S3 compatible store
+	objects := map[string]string{}
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = r.Header.Get("Content-Type") + ":" + string(body)
+		case http.MethodDelete:
+			if _, ok := objects[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s3.Close()
+
+	store := &S3Store{Endpoint: s3.URL, Bucket: "avatars-bucket", AccessKey: "AKID", SecretKey: "secret"}
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "avatars/u1/a.png", []byte("img"), "image/png"))
+	assert.Equal(t, "image/png:img", objects["/avatars-bucket/avatars/u1/a.png"])
+	assert.Equal(t, s3.URL+"/avatars-bucket/avatars/u1/a.png", store.URL("avatars/u1/a.png"))
+
+	assert.NoError(t, store.Delete(ctx, "avatars/u1/a.png"))
+	assert.Empty(t, objects)
+	assert.NoError(t, store.Delete(ctx, "avatars/u1/a.png"))
+
+	store.SecretKey = ""
+	store.AccessKey = "wrong"
+	assert.Error(t, store.Put(ctx, "avatars/u1/a.png", []byte("img"), "image/png"))
+
+	store.PublicURL = "https://cdn.example.com/"
+	assert.Equal(t, "https://cdn.example.com/avatars/u1/a.png", store.URL("avatars/u1/a.png"))
+}
@@ -32,7 +32,7 @@ func InitDB() *sql.DB {
 		)
 	}
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := openDB(dsn)
 	if err != nil {
 		log.Fatalf("failed to open database: %v", err)
 	}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// Directory limits
+const (
+	minSearchLen       = 2
+	maxSearchLen       = 100
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxLookupIDs       = 100
+	// pg_trgm similarity a name needs to match a misspelt query
+	fuzzyThreshold = 0.3
+)
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (h Handler) scanPublicUsers(rows *sql.Rows) ([]PublicUser, error) {
+	out := []PublicUser{}
+	for rows.Next() {
+		var u PublicUser
+		var avatarKey sql.NullString
+		if err := rows.Scan(&u.ID, &u.Username, &u.DisplayName, &u.Country, &avatarKey); err != nil {
+			return nil, err
+		}
+		u.AvatarURL, u.AvatarThumbnailURL = h.avatarURLs(avatarKey)
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// SearchUsers finds users by username or display name: prefix matches first, then similar names
+// so typos still find someone. A query containing "@" only matches the exact email address.
+// Users who turned off "searchable" are never returned. Query: q, limit (default 20, max 50), offset.
+func (h Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if n := utf8.RuneCountInString(q); n < minSearchLen || n > maxSearchLen {
+		http.Error(w, fmt.Sprintf("q must be %d to %d characters", minSearchLen, maxSearchLen), http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := defaultSearchLimit, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must not be negative", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	var rows *sql.Rows
+	var err error
+	if strings.Contains(q, "@") {
+		// Email is never shown, it only finds the one account it belongs to
+		rows, err = h.DB.QueryContext(r.Context(), `
+			SELECT id, username, display_name, country, avatar_key
+			FROM users
+			WHERE searchable AND lower(email) = lower($1)
+			LIMIT $2 OFFSET $3;`, q, limit, offset)
+	} else {
+		rows, err = h.DB.QueryContext(r.Context(), `
+			SELECT id, username, display_name, country, avatar_key
+			FROM users
+			WHERE searchable
+			  AND (username ILIKE $1 OR display_name ILIKE $1
+			       OR similarity(username, $2) > $3 OR similarity(COALESCE(display_name, ''), $2) > $3)
+			ORDER BY (username ILIKE $1 OR display_name ILIKE $1) DESC,
+			         GREATEST(similarity(username, $2), similarity(COALESCE(display_name, ''), $2)) DESC,
+			         username
+			LIMIT $4 OFFSET $5;`, escapeLike(q)+"%", q, fuzzyThreshold, limit, offset)
+	}
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	users, err := h.scanPublicUsers(rows)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"users":  users,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'):
+			return false
+		}
+	}
+	return true
+}
+
+type lookupRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// LookupUsers resolves up to 100 user IDs to public profiles, for other services rendering
+// brackets or member lists. Unknown IDs are left out. Not affected by "searchable": it only
+// answers for IDs the caller already has.
+func (h Handler) LookupUsers(w http.ResponseWriter, r *http.Request) {
+	var req lookupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxLookupIDs {
+		http.Error(w, fmt.Sprintf("at most %d ids per lookup", maxLookupIDs), http.StatusBadRequest)
+		return
+	}
+
+	// Anything that is not a UUID cannot be a user and would fail the cast
+	ids := make([]string, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if isUUID(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	users := []PublicUser{}
+	if len(ids) > 0 {
+		rows, err := h.DB.QueryContext(r.Context(), `
+			SELECT id, username, display_name, country, avatar_key
+			FROM users
+			WHERE id = ANY($1::uuid[]);`, pq.Array(ids))
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		users, err = h.scanPublicUsers(rows)
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
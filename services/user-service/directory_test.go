@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var publicUserColumns = []string{"id", "username", "display_name", "country", "avatar_key"}
+
+func TestSearchUsers(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+	h.Avatars = newMemoryStore()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE searchable`)).
+		WithArgs(`50\%\_off%`, "50%_off", fuzzyThreshold, 5, 10).
+		WillReturnRows(sqlmock.NewRows(publicUserColumns).
+			AddRow("u1", "50%_offer", "Deal Hunter", "SE", "avatars/u1/a.png").
+			AddRow("u2", "fifty", nil, nil, nil))
+
+	req := httptest.NewRequest("GET", "/users?q=50%25_off&limit=5&offset=10", nil)
+	rec := httptest.NewRecorder()
+
+	h.SearchUsers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var out struct {
+		Users  []PublicUser `json:"users"`
+		Offset int          `json:"offset"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Len(t, out.Users, 2)
+	assert.Equal(t, 10, out.Offset)
+	assert.Equal(t, "https://cdn.test/avatars/u1/a_thumb.png", *out.Users[0].AvatarThumbnailURL)
+	assert.Nil(t, out.Users[1].AvatarURL)
+	assert.NotContains(t, rec.Body.String(), "email")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchUsers_ByEmail(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE searchable AND lower(email) = lower($1)`)).
+		WithArgs("Player@Example.com", defaultSearchLimit, 0).
+		WillReturnRows(sqlmock.NewRows(publicUserColumns).AddRow("u1", "player", nil, nil, nil))
+
+	req := httptest.NewRequest("GET", "/users?q=Player@Example.com", nil)
+	rec := httptest.NewRecorder()
+
+	h.SearchUsers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchUsers_BadRequest(t *testing.T) {
+	_, _, h := setupMockDB(t)
+
+	for _, query := range []string{"", "?q=a", "?q=" + strings.Repeat("x", maxSearchLen+1), "?q=ab&limit=0", "?q=ab&limit=51", "?q=ab&offset=-1"} {
+		rec := httptest.NewRecorder()
+		h.SearchUsers(rec, httptest.NewRequest("GET", "/users"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestLookupUsers(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	id1 := "6f1c2b9e-0d7a-4c1e-9b1a-2f3e4d5c6b7a"
+	id2 := "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id = ANY($1::uuid[])`)).
+		WithArgs("{\"" + id1 + "\",\"" + id2 + "\"}").
+		WillReturnRows(sqlmock.NewRows(publicUserColumns).AddRow(id1, "alice", "Alice", "SE", nil))
+
+	body := `{"ids": ["` + id1 + `", "not-a-uuid", "` + id2 + `"]}`
+	rec := httptest.NewRecorder()
+
+	h.LookupUsers(rec, httptest.NewRequest("POST", "/users/lookup", bytes.NewBufferString(body)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var out struct {
+		Users []PublicUser `json:"users"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Equal(t, []PublicUser{{ID: id1, Username: "alice", DisplayName: strPtr("Alice"), Country: strPtr("SE")}}, out.Users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLookupUsers_Limits(t *testing.T) {
+	_, _, h := setupMockDB(t)
+
+	ids := make([]string, maxLookupIDs+1)
+	for i := range ids {
+		ids[i] = "6f1c2b9e-0d7a-4c1e-9b1a-2f3e4d5c6b7a"
+	}
+	body, _ := json.Marshal(lookupRequest{IDs: ids})
+	rec := httptest.NewRecorder()
+	h.LookupUsers(rec, httptest.NewRequest("POST", "/users/lookup", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Nothing to look up does not touch the database
+	rec = httptest.NewRecorder()
+	h.LookupUsers(rec, httptest.NewRequest("POST", "/users/lookup", bytes.NewBufferString(`{"ids": []}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"users": []}`, rec.Body.String())
+}
+
+func strPtr(s string) *string { return &s }
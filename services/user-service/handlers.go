@@ -9,7 +9,10 @@ import (
 )
 
 type Handler struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Avatars AvatarStore
+	// Registrations verifies the assertion api-gateway signs for a new user; nil accepts any caller
+	Registrations *AssertionVerifier
 }
 
 // health check
@@ -28,10 +31,23 @@ func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the gateway creates users, and only under the ID Keycloak just gave them
+	if h.Registrations != nil {
+		claims, err := h.Registrations.Verify(r.Header.Get(HeaderIdentityAssertion))
+		if err != nil {
+			http.Error(w, "missing or invalid identity assertion", http.StatusUnauthorized)
+			return
+		}
+		if claims.Subject != u.ID {
+			http.Error(w, "identity assertion is for another user", http.StatusForbidden)
+			return
+		}
+	}
+
 	// Use the ID provided by the api-gateway (from Keycloak)
 	query := `INSERT INTO users (id, username, email) VALUES ($1, $2, $3);`
 
-	_, err = h.DB.Exec(query, u.ID, u.Username, u.Email)
+	_, err = h.DB.ExecContext(r.Context(), query, u.ID, u.Username, u.Email)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -42,26 +58,14 @@ func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(u)
 }
 
-// Get User by ID
+// Get User by ID, the user themselves or an admin only (public profiles are served by /users/lookup)
 func (h Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-
-	var u User
-
-	query := `SELECT id, username, email, created_at FROM users WHERE id = $1;`
-	err := h.DB.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
-
-	if err == sql.ErrNoRows {
-		http.Error(w, "not found", http.StatusNotFound)
+	if !canAccessUser(r, id) {
+		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
-	if err != nil {
-		http.Error(w, "db error", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(u)
+	h.writeUser(r.Context(), w, id)
 }
 
 func (h Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
@@ -72,9 +76,13 @@ func (h Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing user id", http.StatusBadRequest)
 		return
 	}
+	if !canAccessUser(r, id) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
 
 	query := `DELETE FROM users WHERE id = $1;`
-	res, err := h.DB.Exec(query, id)
+	res, err := h.DB.ExecContext(r.Context(), query, id)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
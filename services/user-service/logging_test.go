@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Redacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, slog.LevelInfo)
+
+	logger.Info("lookup of jane@example.com failed",
+		"email", "jane@example.com",
+		"header", "Bearer eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJ1MSJ9.c2ln",
+		"error", errors.New("no user jane@example.com"))
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "lookup of [email] failed", record["msg"])
+	assert.Equal(t, "[redacted]", record["email"])
+	assert.Equal(t, "[token]", record["header"])
+	assert.Equal(t, "no user [email]", record["error"])
+	assert.Equal(t, "user-service", record["service"])
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	r := mux.NewRouter()
+	r.Use(requestLogger)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		setLogUser(r.Context(), "user-1") // As Authenticate does
+		slog.ErrorContext(r.Context(), "user load failed")
+		http.Error(w, "db error", http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	req := httptest.NewRequest(http.MethodGet, "/users/u1", nil)
+	req.Header.Set("X-Request-Id", "gw-req-1") // Set by api-gateway
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, "gw-req-1", rec.Header().Get("X-Request-Id"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, "health checks are logged at debug level only")
+	var handler, access map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &handler))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+
+	assert.Equal(t, "gw-req-1", handler["request_id"])
+	assert.Equal(t, "user-1", handler["user_id"])
+
+	assert.Equal(t, "ERROR", access["level"])
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "/users/{id}", access["route"])
+	assert.Equal(t, "/users/u1", access["path"])
+	assert.Equal(t, float64(http.StatusInternalServerError), access["status"])
+	assert.Equal(t, "203.0.113.7", access["remote_ip"])
+	assert.Equal(t, "gw-req-1", access["request_id"])
+	assert.Equal(t, "user-1", access["user_id"])
+	assert.Contains(t, access, "latency_ms")
+}
+
+func TestRequestLogger_ReplacesInvalidIDs(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(requestLogger)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Request-Id", "bad id\n")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Len(t, rec.Header().Get("X-Request-Id"), 32)
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net/http"
 
@@ -9,22 +9,80 @@ import (
 )
 
 func main() {
-	// Trigger CI/CD
-	fmt.Println("debug build")
+	InitLogging()
+
+	shutdownTracing, err := InitTracing(context.Background())
+	if err != nil {
+		log.Fatalf("tracing error: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	db := InitDB()
-	h := Handler{DB: db}
+
+	avatars, avatarFiles, err := NewAvatarStoreFromEnv()
+	if err != nil {
+		log.Fatalf("avatar store error: %v", err)
+	}
+	registrations, err := RegistrationVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("identity assertion config error: %v", err)
+	}
+	h := Handler{DB: db, Avatars: avatars, Registrations: registrations}
+
+	verifier, err := NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("auth config error: %v", err)
+	}
+	auth := verifier.Authenticate
+
+	bus, err := ConnectRabbitMQ()
+	if err != nil {
+		log.Fatalf("rabbitmq error: %v", err)
+	}
+	defer bus.Conn.Close()
+
+	// stats read model
+	if err := bus.Subscribe("user-service.match_completed", "events.match.completed", h.HandleMatchCompleted); err != nil {
+		log.Fatalf("rabbitmq subscribe error: %v", err)
+	}
+	if err := bus.Subscribe("user-service.bracket_completed", "events.bracket.completed", h.HandleBracketCompleted); err != nil {
+		log.Fatalf("rabbitmq subscribe error: %v", err)
+	}
 
 	r := mux.NewRouter()
+	r.Use(tracingMiddleware)
+	r.Use(requestLogger)
 	r.Use(metricsMiddleware)
 
 	// public endpoints
 	r.HandleFunc("/health", h.Health).Methods("GET")
 
-	// user endpoints
+	// user endpoints; /register is called by the gateway during sign-up, before the user has a token,
+	// with an identity assertion for the new user
 	r.HandleFunc("/register", h.CreateUser).Methods("POST")
-	r.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
-	r.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+
+	// own profile, registered before /users/{id} so "me" is not taken for an id
+	me := r.PathPrefix("/users/me").Subrouter()
+	me.Use(auth)
+	me.HandleFunc("", h.GetMe).Methods("GET")
+	me.HandleFunc("", h.UpdateMe).Methods("PATCH")
+	me.HandleFunc("/avatar", h.UploadAvatar).Methods("PUT")
+	me.HandleFunc("/avatar", h.DeleteAvatar).Methods("DELETE")
+
+	// avatars of the disk store (the gateway maps /api/users/avatars here)
+	if avatarFiles != nil {
+		r.PathPrefix("/users/avatars/").Handler(http.StripPrefix("/users/avatars/", serveAvatars(avatarFiles))).Methods("GET")
+	}
+
+	// directory
+	r.Handle("/users", auth(http.HandlerFunc(h.SearchUsers))).Methods("GET")
+	// Public profiles only, the same the gateway serves anonymously, so other services may call it in-cluster
+	r.HandleFunc("/users/lookup", h.LookupUsers).Methods("POST")
+
+	// private profile data, the user themselves or an admin
+	r.Handle("/users/{id}", auth(http.HandlerFunc(h.GetUser))).Methods("GET")
+	r.Handle("/users/{id}", auth(http.HandlerFunc(h.DeleteUser))).Methods("DELETE")
+	r.HandleFunc("/users/{id}/stats", h.GetUserStats).Methods("GET")
 
 	// metrics endpoint
 	r.Handle("/metrics", metricsHandler()).Methods("GET")
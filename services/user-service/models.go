@@ -7,4 +7,54 @@ type User struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Profile, all optional
+	DisplayName        *string           `json:"display_name"`
+	Bio                *string           `json:"bio"`
+	Country            *string           `json:"country"` // ISO 3166-1 alpha-2
+	AvatarURL          *string           `json:"avatar_url"`
+	AvatarThumbnailURL *string           `json:"avatar_thumbnail_url"`
+	GameAccounts       map[string]string `json:"game_accounts"` // game -> in-game ID
+	Searchable         bool              `json:"searchable"`    // Shown in user search
+}
+
+// PublicUser is the part of a profile anyone may see.
+type PublicUser struct {
+	ID                 string  `json:"id"`
+	Username           string  `json:"username"`
+	DisplayName        *string `json:"display_name"`
+	Country            *string `json:"country"`
+	AvatarURL          *string `json:"avatar_url"`
+	AvatarThumbnailURL *string `json:"avatar_thumbnail_url"`
+}
+
+// UserStats is the competitive record of a user, built from bracket-service events.
+type UserStats struct {
+	UserID            string        `json:"user_id"`
+	TournamentsPlayed int           `json:"tournaments_played"`
+	MatchesPlayed     int           `json:"matches_played"`
+	MatchesWon        int           `json:"matches_won"`
+	MatchesLost       int           `json:"matches_lost"`
+	Titles            int           `json:"titles"`
+	Placements        []Placement   `json:"placements"`     // Most recent first
+	RecentResults     []MatchResult `json:"recent_results"` // Most recent first
+}
+
+// Placement is the final position in a finished tournament; tied places span placement..placement_to.
+type Placement struct {
+	TournamentID string    `json:"tournament_id"`
+	Game         string    `json:"game"`
+	Placement    int       `json:"placement"`
+	PlacementTo  int       `json:"placement_to"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+type MatchResult struct {
+	MatchID      string    `json:"match_id"`
+	TournamentID string    `json:"tournament_id"`
+	OpponentID   *string   `json:"opponent_id"`
+	Won          bool      `json:"won"`
+	ScoreFor     *string   `json:"score_for"`
+	ScoreAgainst *string   `json:"score_against"`
+	PlayedAt     time.Time `json:"played_at"`
 }
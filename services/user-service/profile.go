@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Profile limits
+const (
+	maxDisplayNameLen  = 50
+	maxBioLen          = 500
+	maxGameAccounts    = 20
+	maxGameNameLen     = 100
+	maxGameAccountLen  = 100
+	maxAvatarBytes     = 2 << 20 // 2 MiB
+	maxAvatarDimension = 4096
+	minAvatarDimension = 32
+	avatarThumbSize    = 128
+)
+
+// Accepted avatar formats by sniffed content type, with the extension they are stored under.
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+func userIDFromCtx(r *http.Request) (string, bool) {
+	c, ok := claimsFromCtx(r)
+	if !ok || c.Sub == "" {
+		return "", false
+	}
+	return c.Sub, true
+}
+
+// avatarURLs turns a stored avatar key into the image and thumbnail URLs.
+func (h Handler) avatarURLs(key sql.NullString) (full, thumb *string) {
+	if !key.Valid || h.Avatars == nil {
+		return nil, nil
+	}
+	f, t := h.Avatars.URL(key.String), h.Avatars.URL(thumbnailKey(key.String))
+	return &f, &t
+}
+
+// loadUser reads a user with profile and game accounts. Returns sql.ErrNoRows for unknown users.
+func (h Handler) loadUser(ctx context.Context, id string) (User, error) {
+	var u User
+	var avatarKey sql.NullString
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT id, username, email, created_at, display_name, bio, country, avatar_key, searchable
+		FROM users WHERE id = $1;`, id,
+	).Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.DisplayName, &u.Bio, &u.Country, &avatarKey, &u.Searchable)
+	if err != nil {
+		return u, err
+	}
+	u.AvatarURL, u.AvatarThumbnailURL = h.avatarURLs(avatarKey)
+
+	rows, err := h.DB.QueryContext(ctx, `SELECT game, account_id FROM user_game_accounts WHERE user_id = $1 ORDER BY game;`, id)
+	if err != nil {
+		return u, err
+	}
+	defer rows.Close()
+
+	u.GameAccounts = map[string]string{}
+	for rows.Next() {
+		var game, account string
+		if err := rows.Scan(&game, &account); err != nil {
+			return u, err
+		}
+		u.GameAccounts[game] = account
+	}
+	return u, rows.Err()
+}
+
+func (h Handler) writeUser(ctx context.Context, w http.ResponseWriter, id string) {
+	u, err := h.loadUser(ctx, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// GetMe returns the caller's own user with profile.
+func (h Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromCtx(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	h.writeUser(r.Context(), w, userID)
+}
+
+// updateProfileRequest is a partial update: absent fields stay unchanged, empty strings clear them.
+// In game_accounts an empty or null ID unlinks the game.
+type updateProfileRequest struct {
+	DisplayName  *string            `json:"display_name"`
+	Bio          *string            `json:"bio"`
+	Country      *string            `json:"country"`
+	Searchable   *bool              `json:"searchable"`
+	GameAccounts map[string]*string `json:"game_accounts"`
+}
+
+func (req updateProfileRequest) validate() error {
+	if req.DisplayName != nil && utf8.RuneCountInString(strings.TrimSpace(*req.DisplayName)) > maxDisplayNameLen {
+		return fmt.Errorf("display_name must be at most %d characters", maxDisplayNameLen)
+	}
+	if req.Bio != nil && utf8.RuneCountInString(*req.Bio) > maxBioLen {
+		return fmt.Errorf("bio must be at most %d characters", maxBioLen)
+	}
+	if req.Country != nil && *req.Country != "" && !isCountryCode(*req.Country) {
+		return errors.New("country must be an ISO 3166-1 alpha-2 code like SE")
+	}
+	if len(req.GameAccounts) > maxGameAccounts {
+		return fmt.Errorf("at most %d game accounts can be linked", maxGameAccounts)
+	}
+	for game, account := range req.GameAccounts {
+		if strings.TrimSpace(game) == "" || utf8.RuneCountInString(game) > maxGameNameLen {
+			return fmt.Errorf("game names must be 1 to %d characters", maxGameNameLen)
+		}
+		if account != nil && utf8.RuneCountInString(*account) > maxGameAccountLen {
+			return fmt.Errorf("game account IDs must be at most %d characters", maxGameAccountLen)
+		}
+	}
+	return nil
+}
+
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// emptyToNil turns "" into SQL NULL.
+func emptyToNil(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// UpdateMe changes the caller's profile and returns the updated user.
+func (h Handler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromCtx(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req updateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.Country != nil {
+		upper := strings.ToUpper(*req.Country)
+		req.Country = &upper
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the fields present in the request are set
+	var sets []string
+	var args []interface{}
+	set := func(column string, value *string) {
+		if value == nil {
+			return
+		}
+		args = append(args, emptyToNil(strings.TrimSpace(*value)))
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	set("display_name", req.DisplayName)
+	set("bio", req.Bio)
+	set("country", req.Country)
+	if req.Searchable != nil {
+		args = append(args, *req.Searchable)
+		sets = append(sets, fmt.Sprintf("searchable = $%d", len(args)))
+	}
+
+	if len(sets) > 0 {
+		args = append(args, userID)
+		query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d;", strings.Join(sets, ", "), len(args))
+		res, err := tx.ExecContext(r.Context(), query, args...)
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	for game, account := range req.GameAccounts {
+		if account == nil || strings.TrimSpace(*account) == "" {
+			_, err = tx.ExecContext(r.Context(), `DELETE FROM user_game_accounts WHERE user_id = $1 AND game = $2;`, userID, game)
+		} else {
+			_, err = tx.ExecContext(r.Context(), `
+				INSERT INTO user_game_accounts (user_id, game, account_id) VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, game) DO UPDATE SET account_id = EXCLUDED.account_id;`,
+				userID, game, strings.TrimSpace(*account))
+		}
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeUser(r.Context(), w, userID)
+}
+
+// --- Avatars ---
+
+var (
+	errAvatarTooLarge = fmt.Errorf("avatar must be at most %d bytes", maxAvatarBytes)
+	errAvatarType     = errors.New("avatar must be a PNG, JPEG or GIF image")
+	errAvatarSize     = fmt.Errorf("avatar must be between %d and %d pixels wide and high", minAvatarDimension, maxAvatarDimension)
+)
+
+// processAvatar validates an uploaded image and renders its square thumbnail as PNG.
+// It returns the content type the image was sniffed as.
+func processAvatar(data []byte) (contentType string, thumb []byte, err error) {
+	contentType = http.DetectContentType(data)
+	if _, ok := avatarTypes[contentType]; !ok {
+		return "", nil, errAvatarType
+	}
+
+	// Check the dimensions before decoding so a tiny file cannot claim a huge canvas
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, errAvatarType
+	}
+	if cfg.Width < minAvatarDimension || cfg.Height < minAvatarDimension ||
+		cfg.Width > maxAvatarDimension || cfg.Height > maxAvatarDimension {
+		return "", nil, errAvatarSize
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, errAvatarType
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, thumbnail(img, avatarThumbSize)); err != nil {
+		return "", nil, err
+	}
+	return contentType, buf.Bytes(), nil
+}
+
+// thumbnail crops the centre square of img and scales it to size x size by averaging
+// the source pixels that fall into each target pixel.
+func thumbnail(img image.Image, size int) *image.NRGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	// Work on NRGBA so averaging does not depend on the source colour model
+	src := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(src, src.Bounds(), img, image.Pt(x0, y0), draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for ty := 0; ty < size; ty++ {
+		sy0, sy1 := ty*side/size, (ty+1)*side/size
+		if sy1 == sy0 {
+			sy1 = sy0 + 1
+		}
+		for tx := 0; tx < size; tx++ {
+			sx0, sx1 := tx*side/size, (tx+1)*side/size
+			if sx1 == sx0 {
+				sx1 = sx0 + 1
+			}
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := src.NRGBAAt(sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					bl += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(tx, ty, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// thumbnailKey is where the thumbnail of the avatar stored under key lives.
+func thumbnailKey(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "_thumb.png"
+}
+
+func newAvatarKey(userID, ext string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "avatars/" + userID + "/" + hex.EncodeToString(b) + ext
+}
+
+// readAvatar reads the "avatar" field of a multipart upload, or the raw body for image/* uploads.
+func readAvatar(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// Leave room for the multipart envelope around the image
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+64<<10)
+
+	var src io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("avatar")
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, errAvatarTooLarge
+		}
+		if err != nil {
+			return nil, errors.New(`missing "avatar" file`)
+		}
+		defer file.Close()
+		src = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(src, maxAvatarBytes+1))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) || len(data) > maxAvatarBytes {
+		return nil, errAvatarTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UploadAvatar replaces the caller's avatar. Accepts multipart/form-data with an "avatar" file
+// or the image itself as the request body.
+func (h Handler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromCtx(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, err := readAvatar(w, r)
+	if err == errAvatarTooLarge {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType, thumb, err := processAvatar(data)
+	if err == errAvatarType {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	key := newAvatarKey(userID, avatarTypes[contentType])
+	if err := h.Avatars.Put(ctx, key, data, contentType); err != nil {
+		slog.ErrorContext(ctx, "avatar upload failed", "error", err)
+		http.Error(w, "avatar storage error", http.StatusBadGateway)
+		return
+	}
+	if err := h.Avatars.Put(ctx, thumbnailKey(key), thumb, "image/png"); err != nil {
+		slog.ErrorContext(ctx, "avatar thumbnail upload failed", "error", err)
+		_ = h.Avatars.Delete(ctx, key)
+		http.Error(w, "avatar storage error", http.StatusBadGateway)
+		return
+	}
+
+	// Swap the key and remember the old one to clean up
+	var oldKey sql.NullString
+	err = h.DB.QueryRowContext(r.Context(), `
+		UPDATE users u SET avatar_key = $1
+		FROM (SELECT avatar_key FROM users WHERE id = $2 FOR UPDATE) old
+		WHERE u.id = $2
+		RETURNING old.avatar_key;`, key, userID).Scan(&oldKey)
+	if err != nil {
+		_ = h.Avatars.Delete(ctx, key)
+		_ = h.Avatars.Delete(ctx, thumbnailKey(key))
+		if err == sql.ErrNoRows {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.deleteAvatarFiles(r, oldKey)
+
+	h.writeUser(r.Context(), w, userID)
+}
+
+// DeleteAvatar removes the caller's avatar.
+func (h Handler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromCtx(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var oldKey sql.NullString
+	err := h.DB.QueryRowContext(r.Context(), `
+		UPDATE users u SET avatar_key = NULL
+		FROM (SELECT avatar_key FROM users WHERE id = $1 FOR UPDATE) old
+		WHERE u.id = $1
+		RETURNING old.avatar_key;`, userID).Scan(&oldKey)
+	if err == sql.ErrNoRows {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.deleteAvatarFiles(r, oldKey)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteAvatarFiles removes a replaced avatar. Failures only leave an orphaned file behind.
+func (h Handler) deleteAvatarFiles(r *http.Request, key sql.NullString) {
+	if !key.Valid {
+		return
+	}
+	for _, k := range []string{key.String, thumbnailKey(key.String)} {
+		if err := h.Avatars.Delete(r.Context(), k); err != nil {
+			slog.WarnContext(r.Context(), "avatar cleanup failed", "key", k, "error", err)
+		}
+	}
+}
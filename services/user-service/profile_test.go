@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is an in-memory AvatarStore.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore { return &memoryStore{objects: map[string][]byte{}} }
+
+func (m *memoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStore) URL(key string) string { return "https://cdn.test/" + key }
+
+// asUser puts the claims the auth middleware would have verified into the request.
+func asUser(req *http.Request, id string, roles ...string) *http.Request {
+	c := &Claims{Sub: id}
+	c.RealmAccess.Roles = roles
+	return req.WithContext(context.WithValue(req.Context(), claimsKey, c))
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func expectLoadUser(mock sqlmock.Sqlmock, id string, avatarKey interface{}) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, username, email, created_at, display_name`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "created_at", "display_name", "bio", "country", "avatar_key", "searchable"}).
+			AddRow(id, "tester", "t@e.com", time.Now(), "Tester", nil, "SE", avatarKey, true))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_game_accounts`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"game", "account_id"}).AddRow("chess", "magnus"))
+}
+
+func TestGetMe(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+	h.Avatars = newMemoryStore()
+
+	expectLoadUser(mock, "u1", "avatars/u1/abc.jpg")
+
+	rec := httptest.NewRecorder()
+	h.GetMe(rec, asUser(httptest.NewRequest("GET", "/users/me", nil), "u1"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var u User
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &u))
+	assert.Equal(t, "https://cdn.test/avatars/u1/abc.jpg", *u.AvatarURL)
+	assert.Equal(t, "https://cdn.test/avatars/u1/abc_thumb.png", *u.AvatarThumbnailURL)
+	assert.Equal(t, map[string]string{"chess": "magnus"}, u.GameAccounts)
+}
+
+func TestUpdateMe(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET display_name = $1, country = $2 WHERE id = $3;`)).
+		WithArgs("Tester", "SE", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_game_accounts`)).
+		WithArgs("u1", "chess", "magnus").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLoadUser(mock, "u1", nil)
+
+	body := `{"display_name": " Tester ", "country": "se", "game_accounts": {"chess": "magnus"}}`
+	req := asUser(httptest.NewRequest("PATCH", "/users/me", bytes.NewBufferString(body)), "u1")
+	rec := httptest.NewRecorder()
+
+	h.UpdateMe(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMe_ClearsAndUnlinks(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET bio = $1 WHERE id = $2;`)).
+		WithArgs(nil, "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM user_game_accounts`)).
+		WithArgs("u1", "chess").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLoadUser(mock, "u1", nil)
+
+	body := `{"bio": "", "game_accounts": {"chess": null}}`
+	req := asUser(httptest.NewRequest("PATCH", "/users/me", bytes.NewBufferString(body)), "u1")
+	rec := httptest.NewRecorder()
+
+	h.UpdateMe(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMe_Validation(t *testing.T) {
+	_, _, h := setupMockDB(t)
+
+	for _, body := range []string{
+		`{"country": "Sweden"}`,
+		`{"display_name": "` + string(bytes.Repeat([]byte("x"), maxDisplayNameLen+1)) + `"}`,
+		`{"game_accounts": {"": "id"}}`,
+	} {
+		req := asUser(httptest.NewRequest("PATCH", "/users/me", bytes.NewBufferString(body)), "u1")
+		rec := httptest.NewRecorder()
+		h.UpdateMe(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestUpdateMe_Unauthorized(t *testing.T) {
+	_, _, h := setupMockDB(t)
+	rec := httptest.NewRecorder()
+	h.UpdateMe(rec, httptest.NewRequest("PATCH", "/users/me", bytes.NewBufferString(`{}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestProcessAvatar(t *testing.T) {
+	contentType, thumb, err := processAvatar(pngImage(t, 300, 200))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	img, err := png.Decode(bytes.NewReader(thumb))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, avatarThumbSize, avatarThumbSize), img.Bounds())
+	// Centre crop keeps both halves
+	r, _, _, _ := img.At(0, 64).RGBA()
+	_, _, b, _ := img.At(127, 64).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.Equal(t, uint32(0xffff), b)
+
+	_, _, err = processAvatar([]byte("definitely not an image"))
+	assert.Equal(t, errAvatarType, err)
+	_, _, err = processAvatar(pngImage(t, 16, 16))
+	assert.Equal(t, errAvatarSize, err)
+}
+
+func avatarUpload(t *testing.T, data []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("avatar", "me.png")
+	assert.NoError(t, err)
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest("PUT", "/users/me/avatar", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return asUser(req, "u1")
+}
+
+func TestUploadAvatar_ReplacesOldAvatar(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+	store := newMemoryStore()
+	store.objects["avatars/u1/old.jpg"] = []byte("old")
+	store.objects["avatars/u1/old_thumb.png"] = []byte("old")
+	h.Avatars = store
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users u SET avatar_key = $1`)).
+		WithArgs(sqlmock.AnyArg(), "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key"}).AddRow("avatars/u1/old.jpg"))
+	expectLoadUser(mock, "u1", "avatars/u1/new.png")
+
+	rec := httptest.NewRecorder()
+	h.UploadAvatar(rec, avatarUpload(t, pngImage(t, 64, 64)))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, store.objects, 2)
+	assert.NotContains(t, store.objects, "avatars/u1/old.jpg")
+	for key := range store.objects {
+		assert.Regexp(t, `^avatars/u1/[0-9a-f]{16}(_thumb)?\.png$`, key)
+	}
+}
+
+func TestUploadAvatar_Rejected(t *testing.T) {
+	_, _, h := setupMockDB(t)
+	h.Avatars = newMemoryStore()
+
+	rec := httptest.NewRecorder()
+	h.UploadAvatar(rec, avatarUpload(t, []byte("GIF? no, plain text")))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.UploadAvatar(rec, avatarUpload(t, make([]byte, maxAvatarBytes+1)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestDeleteAvatar(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+	store := newMemoryStore()
+	store.objects["avatars/u1/old.jpg"] = []byte("old")
+	store.objects["avatars/u1/old_thumb.png"] = []byte("old")
+	h.Avatars = store
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users u SET avatar_key = NULL`)).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"avatar_key"}).AddRow("avatars/u1/old.jpg"))
+
+	rec := httptest.NewRecorder()
+	h.DeleteAvatar(rec, asUser(httptest.NewRequest("DELETE", "/users/me/avatar", nil), "u1"))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, store.objects)
+}
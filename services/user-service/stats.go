@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How many placements and match results GetUserStats lists.
+const (
+	statsPlacementLimit = 20
+	statsResultLimit    = 10
+)
+
+// The stats read model is fed by bracket-service events, so a stats request never calls another service.
+// Events about participants that are not users (teams) find no users row and are ignored.
+
+type matchCompletedEvent struct {
+	Payload struct {
+		TournamentID string  `json:"tournament_id"`
+		MatchID      string  `json:"match_id"`
+		WinnerID     string  `json:"winner_id"`
+		Player1ID    *string `json:"player1_id"`
+		Player2ID    *string `json:"player2_id"`
+		ScoreA       string  `json:"score_a"`
+		ScoreB       string  `json:"score_b"`
+	} `json:"payload"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HandleMatchCompleted records a played match for each participant that is a user.
+// Redelivered or corrected results overwrite the earlier row.
+func (h Handler) HandleMatchCompleted(ctx context.Context, body []byte) error {
+	var event matchCompletedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	p := event.Payload
+	if p.MatchID == "" || p.TournamentID == "" {
+		return fmt.Errorf("%w: match completed event without match_id or tournament_id", errMalformedEvent)
+	}
+
+	sides := []struct {
+		id, opponent           *string
+		scoreFor, scoreAgainst string
+	}{
+		{p.Player1ID, p.Player2ID, p.ScoreA, p.ScoreB},
+		{p.Player2ID, p.Player1ID, p.ScoreB, p.ScoreA},
+	}
+	for _, s := range sides {
+		if s.id == nil {
+			continue
+		}
+		_, err := h.DB.ExecContext(ctx, `
+			INSERT INTO user_match_results (match_id, user_id, tournament_id, opponent_id, won, score_for, score_against, played_at)
+			SELECT $1::uuid, id, $3::uuid, $4::uuid, $5::boolean, $6::text, $7::text, $8::timestamptz FROM users WHERE id = $2
+			ON CONFLICT (match_id, user_id) DO UPDATE
+			SET opponent_id = EXCLUDED.opponent_id, won = EXCLUDED.won, score_for = EXCLUDED.score_for,
+			    score_against = EXCLUDED.score_against, played_at = EXCLUDED.played_at;`,
+			p.MatchID, *s.id, p.TournamentID, s.opponent, *s.id == p.WinnerID, s.scoreFor, s.scoreAgainst, event.Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type bracketCompletedEvent struct {
+	Payload struct {
+		TournamentID    string `json:"tournament_id"`
+		Game            string `json:"game"`
+		ParticipantType string `json:"participant_type"`
+		Placements      []struct {
+			ParticipantID string `json:"participant_id"`
+			Placement     int    `json:"placement"`
+			PlacementTo   int    `json:"placement_to"`
+		} `json:"placements"`
+	} `json:"payload"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HandleBracketCompleted records the final placement of every user in a finished tournament.
+func (h Handler) HandleBracketCompleted(ctx context.Context, body []byte) error {
+	var event bracketCompletedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	p := event.Payload
+	if p.TournamentID == "" {
+		return fmt.Errorf("%w: bracket completed event without tournament_id", errMalformedEvent)
+	}
+	if p.ParticipantType == "team" {
+		return nil
+	}
+
+	for _, pl := range p.Placements {
+		_, err := h.DB.ExecContext(ctx, `
+			INSERT INTO user_placements (tournament_id, user_id, game, placement, placement_to, completed_at)
+			SELECT $1::uuid, id, $3::text, $4::int, $5::int, $6::timestamptz FROM users WHERE id = $2
+			ON CONFLICT (tournament_id, user_id) DO UPDATE
+			SET game = EXCLUDED.game, placement = EXCLUDED.placement, placement_to = EXCLUDED.placement_to,
+			    completed_at = EXCLUDED.completed_at;`,
+			p.TournamentID, pl.ParticipantID, p.Game, pl.Placement, pl.PlacementTo, event.Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserStats returns a user's tournament and match record.
+func (h Handler) GetUserStats(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var exists bool
+	if err := h.DB.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);`, id).Scan(&exists); err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	stats := UserStats{UserID: id, Placements: []Placement{}, RecentResults: []MatchResult{}}
+
+	// Tournaments still running only show up through their matches
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT
+			(SELECT COUNT(*) FROM (
+				SELECT tournament_id FROM user_match_results WHERE user_id = $1
+				UNION
+				SELECT tournament_id FROM user_placements WHERE user_id = $1) t),
+			(SELECT COUNT(*) FROM user_match_results WHERE user_id = $1),
+			(SELECT COUNT(*) FROM user_match_results WHERE user_id = $1 AND won),
+			(SELECT COUNT(*) FROM user_placements WHERE user_id = $1 AND placement = 1);`, id,
+	).Scan(&stats.TournamentsPlayed, &stats.MatchesPlayed, &stats.MatchesWon, &stats.Titles)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	stats.MatchesLost = stats.MatchesPlayed - stats.MatchesWon
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT tournament_id, game, placement, placement_to, completed_at
+		FROM user_placements
+		WHERE user_id = $1
+		ORDER BY completed_at DESC
+		LIMIT $2;`, id, statsPlacementLimit)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Placement
+		if err := rows.Scan(&p.TournamentID, &p.Game, &p.Placement, &p.PlacementTo, &p.CompletedAt); err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		stats.Placements = append(stats.Placements, p)
+	}
+
+	results, err := h.DB.QueryContext(r.Context(), `
+		SELECT match_id, tournament_id, opponent_id, won, score_for, score_against, played_at
+		FROM user_match_results
+		WHERE user_id = $1
+		ORDER BY played_at DESC
+		LIMIT $2;`, id, statsResultLimit)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	defer results.Close()
+	for results.Next() {
+		var m MatchResult
+		if err := results.Scan(&m.MatchID, &m.TournamentID, &m.OpponentID, &m.Won, &m.ScoreFor, &m.ScoreAgainst, &m.PlayedAt); err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		stats.RecentResults = append(stats.RecentResults, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
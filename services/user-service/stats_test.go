@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleMatchCompleted_RecordsBothSides(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	at := time.Date(2025, 12, 20, 19, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_match_results`)).
+		WithArgs("m1", "u1", "t1", "u2", false, "1", "3", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_match_results`)).
+		WithArgs("m1", "u2", "t1", "u1", true, "3", "1", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	body := `{"event_type": "MatchCompleted", "payload": {"tournament_id": "t1", "match_id": "m1", "winner_id": "u2",
+		"player1_id": "u1", "player2_id": "u2", "score_a": "1", "score_b": "3"}, "timestamp": "2025-12-20T19:00:00Z"}`
+	assert.NoError(t, h.HandleMatchCompleted(context.Background(), []byte(body)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleMatchCompleted_MissingIDs(t *testing.T) {
+	_, _, h := setupMockDB(t)
+	assert.Error(t, h.HandleMatchCompleted(context.Background(), []byte(`{"payload": {"winner_id": "u1"}}`)))
+}
+
+func TestHandleBracketCompleted(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_placements`)).
+		WithArgs("t1", "u2", "chess", 1, 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_placements`)).
+		WithArgs("t1", "u1", "chess", 2, 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	body := `{"event_type": "BracketCompleted", "payload": {"tournament_id": "t1", "game": "chess", "participant_type": "individual",
+		"placements": [{"participant_id": "u2", "placement": 1, "placement_to": 1}, {"participant_id": "u1", "placement": 2, "placement_to": 2}]}}`
+	assert.NoError(t, h.HandleBracketCompleted(context.Background(), []byte(body)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleBracketCompleted_IgnoresTeamTournaments(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	body := `{"payload": {"tournament_id": "t1", "participant_type": "team", "placements": [{"participant_id": "team1", "placement": 1, "placement_to": 1}]}}`
+	assert.NoError(t, h.HandleBracketCompleted(context.Background(), []byte(body)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserStats_Success(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM users`)).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT\s+\(SELECT COUNT`).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"tournaments", "played", "won", "titles"}).AddRow(3, 7, 5, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_placements`)).
+		WithArgs("u1", statsPlacementLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"tournament_id", "game", "placement", "placement_to", "completed_at"}).
+			AddRow("t2", "chess", 1, 1, now).
+			AddRow("t1", "chess", 3, 4, now.Add(-time.Hour)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_match_results`)).
+		WithArgs("u1", statsResultLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"match_id", "tournament_id", "opponent_id", "won", "score_for", "score_against", "played_at"}).
+			AddRow("m9", "t2", "u2", true, "2", "0", now).
+			AddRow("m8", "t3", nil, false, nil, nil, now.Add(-time.Minute)))
+
+	req := httptest.NewRequest("GET", "/users/u1/stats", nil)
+	rec := httptest.NewRecorder()
+	r := mux.NewRouter()
+	r.HandleFunc("/users/{id}/stats", h.GetUserStats)
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stats UserStats
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, 3, stats.TournamentsPlayed)
+	assert.Equal(t, 2, stats.MatchesLost)
+	assert.Equal(t, 1, stats.Titles)
+	assert.Len(t, stats.Placements, 2)
+	assert.Equal(t, "u2", *stats.RecentResults[0].OpponentID)
+	assert.Nil(t, stats.RecentResults[1].ScoreFor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserStats_NotFound(t *testing.T) {
+	db, mock, h := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM users`)).
+		WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	req := httptest.NewRequest("GET", "/users/nope/stats", nil)
+	rec := httptest.NewRecorder()
+	r := mux.NewRouter()
+	r.HandleFunc("/users/{id}/stats", h.GetUserStats)
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
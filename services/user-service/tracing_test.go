@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+// newTestTracing records spans in memory for the duration of the test.
+func newTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	installTracing(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestTracingMiddleware_ContinuesGatewayTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	r := mux.NewRouter()
+	r.Use(tracingMiddleware)
+	r.HandleFunc("/health", Handler{}.Health)
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/u1", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/{id}", spans[0].Name)
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent.SpanID().String())
+}
+
+func TestHandleDelivery_ContinuesPublisherTrace(t *testing.T) {
+	exporter := newTestTracing(t)
+
+	var handled trace.SpanContext
+	msg := amqp.Delivery{Headers: amqp.Table{"traceparent": testTraceparent}, RoutingKey: "events.match.completed"}
+	handleDelivery(msg, func(ctx context.Context, body []byte) error {
+		handled = trace.SpanContextFromContext(ctx)
+		return nil
+	})
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "process events.match.completed", spans[0].Name)
+	assert.Equal(t, trace.SpanKindConsumer, spans[0].SpanKind)
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent.SpanID().String())
+	assert.Equal(t, spans[0].SpanContext.SpanID(), handled.SpanID())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", handled.TraceID().String())
+}
+
+// recordingAcknowledger remembers how a delivery was settled.
+type recordingAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestHandleDelivery_RequeuesFailuresButDropsMalformedEvents(t *testing.T) {
+	requeueDelay = 0
+	t.Cleanup(func() { requeueDelay = time.Second })
+
+	h := Handler{}
+	malformed := &recordingAcknowledger{}
+	handleDelivery(amqp.Delivery{Acknowledger: malformed, Body: []byte(`{"payload":`)}, h.HandleMatchCompleted)
+	assert.True(t, malformed.nacked)
+	assert.False(t, malformed.requeued, "a malformed event fails the same way on every delivery")
+
+	failed := &recordingAcknowledger{}
+	handleDelivery(amqp.Delivery{Acknowledger: failed}, func(ctx context.Context, body []byte) error {
+		return errors.New("connection refused")
+	})
+	assert.True(t, failed.nacked)
+	assert.True(t, failed.requeued)
+}